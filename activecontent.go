@@ -0,0 +1,322 @@
+package pdftohtml
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- active content
+// ----------------------------------------------------------------------------
+
+// ErrActiveContent is returned when the conversion is refused, because the PDF
+// file contains active content.
+var ErrActiveContent = errors.New("pdftohtml: active content in PDF file")
+
+// ActiveContentName is the name of the active content report in the outdir.
+const ActiveContentName = "active-content.json"
+
+// ActiveContentPolicy determines what is done with the active content found in
+// the PDF file.
+type ActiveContentPolicy int
+
+const (
+	// ActiveContentReport writes the findings into the outdir.
+	ActiveContentReport ActiveContentPolicy = iota + 1
+	// ActiveContentRefuse fails the conversion before it starts.
+	ActiveContentRefuse
+)
+
+// ActiveContent is the single finding of the active content scan.
+type ActiveContent struct {
+	Kind   string `json:"kind"`             // e.g. "javascript", "launch", "uri", "submit-form"
+	Where  string `json:"where"`            // e.g. "document", "page 3", "field address.city"
+	Detail string `json:"detail,omitempty"` // script, URI or the launched file, shortened
+}
+
+func (a ActiveContent) String() string {
+	if a.Detail == "" {
+		return a.Kind + " in " + a.Where
+	}
+
+	return fmt.Sprintf("%s in %s: %s", a.Kind, a.Where, a.Detail)
+}
+
+// activeActions maps action types to kinds of the findings. Actions that only
+// navigate within the document are not active content.
+var activeActions = map[pdfName]string{
+	"JavaScript": "javascript",
+	"Launch":     "launch",
+	"URI":        "uri",
+	"SubmitForm": "submit-form",
+	"ImportData": "import-data",
+	"GoToR":      "remote-goto",
+	"GoToE":      "embedded-goto",
+	"Rendition":  "rendition",
+}
+
+// activeDetailMax is the length of the detail kept in the findings.
+const activeDetailMax = 200
+
+// ScanActiveContent scans the PDF file for JavaScript, actions launching files
+// or opening URIs, form submissions, embedded files, XFA forms and rich media,
+// before it is converted.
+//
+// The file is read by the package itself, which does not read encrypted files,
+// see `ErrUnsupportedPDF`.
+func ScanActiveContent(inpath string) ([]ActiveContent, error) {
+	r, err := openPDFFile(inpath)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &activeScanner{r: r, seen: make(map[pdfRef]bool)}
+	catalog := r.catalog()
+
+	s.action(catalog["OpenAction"], "document")
+	s.actions(catalog["AA"], "document")
+
+	names := r.dict(catalog["Names"])
+	r.nameTree(names["JavaScript"], func(name string, val any) {
+		s.action(val, "document")
+	})
+	r.nameTree(names["EmbeddedFiles"], func(name string, _ any) {
+		s.add("embedded-file", "document", name)
+	})
+
+	form := r.dict(catalog["AcroForm"])
+	if form["XFA"] != nil {
+		s.add("xfa", "document", "")
+	}
+	for _, field := range r.array(form["Fields"]) {
+		s.field(field, "", 0)
+	}
+
+	for i, page := range r.pages() {
+		where := fmt.Sprintf("page %d", i+1)
+		s.actions(page.dict["AA"], where)
+
+		for _, obj := range r.array(page.dict["Annots"]) {
+			annot := r.dict(obj)
+			switch r.resolve(annot["Subtype"]) {
+			case pdfName("RichMedia"), pdfName("3D"):
+				s.add("rich-media", where, "")
+			case pdfName("FileAttachment"):
+				s.add("embedded-file", where, r.text(r.dict(annot["FS"])["F"]))
+			}
+			s.action(annot["A"], where)
+			s.actions(annot["AA"], where)
+		}
+	}
+
+	return s.found, nil
+}
+
+// activeScanner collects the findings of the active content scan.
+type activeScanner struct {
+	r     *pdfReader
+	seen  map[pdfRef]bool
+	found []ActiveContent
+}
+
+// add records the finding, unless already recorded.
+func (s *activeScanner) add(kind, where, detail string) {
+	detail = strings.Join(strings.Fields(detail), " ")
+	if runes := []rune(detail); len(runes) > activeDetailMax {
+		detail = string(runes[:activeDetailMax]) + "…"
+	}
+
+	finding := ActiveContent{Kind: kind, Where: where, Detail: detail}
+	for _, f := range s.found {
+		if f == finding {
+			return
+		}
+	}
+	s.found = append(s.found, finding)
+}
+
+// visit reports whether the object was not visited yet, so shared and looping
+// objects are scanned once.
+func (s *activeScanner) visit(obj any) bool {
+	ref, ok := obj.(pdfRef)
+	if !ok {
+		return true
+	}
+	if s.seen[ref] {
+		return false
+	}
+	s.seen[ref] = true
+
+	return true
+}
+
+// actions scans the additional actions dictionary, triggered by events.
+func (s *activeScanner) actions(obj any, where string) {
+	for _, action := range s.r.dict(obj) {
+		s.action(action, where)
+	}
+}
+
+// action scans the action and the actions following it.
+func (s *activeScanner) action(obj any, where string) {
+	for depth := 0; obj != nil && depth < pdfMaxDepth; depth++ {
+		if !s.visit(obj) {
+			return
+		}
+
+		action := s.r.dict(obj)
+		if action == nil {
+			// destinations are not actions
+			return
+		}
+
+		typ, _ := s.r.resolve(action["S"]).(pdfName)
+		if kind, ok := activeActions[typ]; ok {
+			s.add(kind, where, s.actionDetail(typ, action))
+		}
+		if typ == "Rendition" && action["JS"] != nil {
+			s.add("javascript", where, s.script(action["JS"]))
+		}
+
+		// the next action is a single one, or the list of them
+		next := action["Next"]
+		if list := s.r.array(next); list != nil {
+			for _, a := range list {
+				s.action(a, where)
+			}
+			return
+		}
+		obj = next
+	}
+}
+
+// actionDetail returns the script, URI or file of the action.
+func (s *activeScanner) actionDetail(typ pdfName, action pdfDict) string {
+	switch typ {
+	case "JavaScript":
+		return s.script(action["JS"])
+	case "URI":
+		return s.r.text(action["URI"])
+	case "Launch":
+		if win := s.r.dict(action["Win"]); win != nil {
+			return strings.TrimSpace(s.r.text(win["F"]) + " " + s.r.text(win["P"]))
+		}
+		fallthrough
+	case "SubmitForm", "ImportData", "GoToR", "GoToE":
+		return s.file(action["F"])
+	}
+
+	return ""
+}
+
+// script returns the JavaScript, given as the string or the stream.
+func (s *activeScanner) script(obj any) string {
+	if stream, ok := s.r.resolve(obj).(*pdfStream); ok {
+		data, err := s.r.decode(stream)
+		if err != nil {
+			return ""
+		}
+		return s.r.text(pdfString(data))
+	}
+
+	return s.r.text(obj)
+}
+
+// file returns the name or URL of the file specification.
+func (s *activeScanner) file(obj any) string {
+	if spec := s.r.dict(obj); spec != nil {
+		for _, key := range []pdfName{"UF", "F"} {
+			if name := s.r.text(spec[key]); name != "" {
+				return name
+			}
+		}
+		return ""
+	}
+
+	return s.r.text(obj)
+}
+
+// field scans the actions of the form field and its kids, such as formatting
+// and calculation scripts.
+func (s *activeScanner) field(obj any, name string, depth int) {
+	node := s.r.dict(obj)
+	if node == nil || depth > pdfMaxDepth {
+		return
+	}
+
+	if t := s.r.text(node["T"]); t != "" {
+		if name != "" {
+			name += "."
+		}
+		name += t
+	}
+
+	s.actions(node["AA"], "field "+name)
+	for _, kid := range s.r.array(node["Kids"]) {
+		s.field(kid, name, depth+1)
+	}
+}
+
+// checkActiveContent refuses the conversion of the PDF file with active
+// content. Files that can't be scanned are refused as well.
+func checkActiveContent(_ context.Context, inpath, _ string) error {
+	found, err := ScanActiveContent(inpath)
+	if err != nil {
+		return fmt.Errorf("pdftohtml: can't scan for active content: %w", err)
+	}
+	if len(found) == 0 {
+		return nil
+	}
+
+	list := make([]string, len(found))
+	for i, f := range found {
+		list[i] = f.String()
+	}
+
+	return fmt.Errorf("%w: %s", ErrActiveContent, strings.Join(list, "; "))
+}
+
+// reportActiveContent writes the active content of the PDF file into the outdir.
+func reportActiveContent(_ context.Context, inpath, outdir string) error {
+	found, err := ScanActiveContent(inpath)
+	if errors.Is(err, ErrUnsupportedPDF) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if found == nil {
+		found = []ActiveContent{}
+	}
+
+	data, err := json.MarshalIndent(found, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(outdir, ActiveContentName), data, 0o644)
+}
+
+// ----------------------------------------------------------------------------
+// -- active content options
+// ----------------------------------------------------------------------------
+
+// Scan the PDF file for active content, see `ScanActiveContent`. With
+// `ActiveContentReport` the findings are written into `active-content.json` of
+// the outdir. With `ActiveContentRefuse` the conversion fails before it starts
+// with `ErrActiveContent`, also for files that can't be scanned, e.g. encrypted.
+func WithActiveContentScan(policy ActiveContentPolicy) option {
+	return func(c *Command) {
+		switch policy {
+		case ActiveContentReport:
+			c.stages = append(c.stages, reportActiveContent)
+		case ActiveContentRefuse:
+			c.checks = append(c.checks, checkActiveContent)
+		}
+	}
+}
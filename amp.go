@@ -0,0 +1,213 @@
+package pdftohtml
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- amp export
+// ----------------------------------------------------------------------------
+
+// ErrAMPStyleTooLarge is returned when the CSS of a page exceeds the AMP limit.
+var ErrAMPStyleTooLarge = errors.New("pdftohtml: AMP style too large")
+
+// ampStyleLimit is the maximum size of the custom CSS of an AMP page.
+const ampStyleLimit = 75000
+
+// ampBoilerplate is the required AMP boilerplate code.
+const ampBoilerplate = `<style amp-boilerplate>body{-webkit-animation:-amp-start 8s steps(1,end) 0s 1 normal both;-moz-animation:-amp-start 8s steps(1,end) 0s 1 normal both;-ms-animation:-amp-start 8s steps(1,end) 0s 1 normal both;animation:-amp-start 8s steps(1,end) 0s 1 normal both}@-webkit-keyframes -amp-start{from{visibility:hidden}to{visibility:visible}}@-moz-keyframes -amp-start{from{visibility:hidden}to{visibility:visible}}@-ms-keyframes -amp-start{from{visibility:hidden}to{visibility:visible}}@-o-keyframes -amp-start{from{visibility:hidden}to{visibility:visible}}@keyframes -amp-start{from{visibility:hidden}to{visibility:visible}}</style><noscript><style amp-boilerplate>body{-webkit-animation:none;-moz-animation:none;-ms-animation:none;animation:none}</style></noscript>`
+
+// ampDisallowed are elements removed from AMP pages, with their content.
+var ampDisallowed = map[string]bool{
+	"script": true, "noscript": true, "iframe": true, "frame": true, "frameset": true,
+	"object": true, "embed": true, "applet": true, "base": true, "form": true,
+	"input": true, "textarea": true, "select": true, "button": true,
+}
+
+// ExportAMP writes AMP versions of the HTML files in the outdir into the dst
+// directory, together with copies of the other files they reference.
+//
+// The baseURL is the public location the outdir is served from, AMP pages point
+// to their canonical pages in it. Backgrounds become `amp-img` elements, all CSS
+// is inlined into the single custom style, and elements AMP disallows, including
+// form fields, are removed.
+func ExportAMP(outdir, dst, baseURL string) error {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(outdir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || name == ManifestName {
+			continue
+		}
+
+		if filepath.Ext(name) != ".html" {
+			if err := copyFile(filepath.Join(outdir, name), filepath.Join(dst, name)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(outdir, name))
+		if err != nil {
+			return err
+		}
+
+		amp, err := toAMP(data, outdir, dst, name, baseURL+"/"+name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dst, name), amp, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// toAMP transforms the HTML document into the AMP document. Stylesheets of the
+// outdir are inlined, and embedded images are written into the dst directory,
+// AMP does not allow data URIs.
+func toAMP(data []byte, outdir, dst, name, canonical string) ([]byte, error) {
+	doc := parseHTML(normalizeMarkup(data))
+	root, head, body := doc.find("html"), doc.find("head"), doc.find("body")
+	if root == nil || head == nil || body == nil {
+		return nil, fmt.Errorf("pdftohtml: malformed document")
+	}
+	root.setAttr("amp", "")
+	doc.removeDoctype()
+
+	var css strings.Builder
+	var images int
+	var err error
+
+	doc.walk(func(n *htmlNode) bool {
+		if n.kind != elementNode || err != nil {
+			return err == nil
+		}
+
+		// event handlers are not allowed
+		attrs := n.attrs[:0]
+		for _, a := range n.attrs {
+			if !strings.HasPrefix(a.Key, "on") {
+				attrs = append(attrs, a)
+			}
+		}
+		n.attrs = attrs
+
+		kept := n.children[:0]
+		for _, child := range n.children {
+			switch {
+			case child.kind == elementNode && child.tag == "style":
+				for _, text := range child.children {
+					css.WriteString(text.text)
+				}
+			case child.kind == elementNode && child.tag == "meta" && n == head:
+				// charset and viewport are added below
+				if key, _ := child.attr("name"); key != "viewport" {
+					if _, ok := child.attr("charset"); !ok {
+						kept = append(kept, child)
+					}
+				}
+			case child.kind == elementNode && child.tag == "link":
+				rel, _ := child.attr("rel")
+				if rel != "stylesheet" && rel != "canonical" {
+					kept = append(kept, child)
+				}
+
+				// only stylesheets within the outdir are inlined
+				href, _ := child.attr("href")
+				local := !strings.Contains(href, ":") && filepath.IsLocal(filepath.FromSlash(path.Clean(href)))
+				if rel == "stylesheet" && local {
+					if data, rerr := os.ReadFile(filepath.Join(outdir, filepath.FromSlash(path.Clean(href)))); rerr == nil {
+						css.Write(data)
+					}
+				}
+			case child.kind == elementNode && ampDisallowed[child.tag]:
+			default:
+				kept = append(kept, child)
+			}
+		}
+		n.children = kept
+
+		if n.tag == "img" {
+			images++
+			err = ampImage(n, dst, fmt.Sprintf("%s-%d", strings.TrimSuffix(name, ".html"), images))
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if css.Len() > ampStyleLimit {
+		return nil, fmt.Errorf("%w: %d bytes", ErrAMPStyleTooLarge, css.Len())
+	}
+
+	markup := fmt.Sprintf("<meta charset=\"utf-8\">\n"+
+		"<script async src=\"https://cdn.ampproject.org/v0.js\"></script>\n"+
+		"<link rel=\"canonical\" href=\"%s\">\n"+
+		"<meta name=\"viewport\" content=\"width=device-width\">\n"+
+		"<style amp-custom>%s</style>\n%s\n", xmlEscaper.Replace(canonical), css.String(), ampBoilerplate)
+
+	prolog := parseHTML([]byte(markup))
+	for _, child := range prolog.children {
+		child.parent = head
+	}
+	head.children = append(prolog.children, head.children...)
+
+	return append([]byte("<!DOCTYPE html>\n"), renderHTML(doc, false)...), nil
+}
+
+// ampImage turns the image element into the fixed size `amp-img` element.
+func ampImage(n *htmlNode, dst, base string) error {
+	n.tag = "amp-img"
+	n.setAttr("layout", "fixed")
+
+	src, _ := n.attr("src")
+	if payload, ok := strings.CutPrefix(src, "data:"); ok {
+		mime, encoded, ok := strings.Cut(payload, ";base64,")
+		if !ok {
+			return fmt.Errorf("pdftohtml: unsupported image data URI")
+		}
+
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return err
+		}
+
+		ext := ".png"
+		if mime == "image/jpeg" {
+			ext = ".jpg"
+		}
+		if err := os.WriteFile(filepath.Join(dst, base+ext), data, 0o644); err != nil {
+			return err
+		}
+		n.setAttr("src", base+ext)
+	}
+
+	// fixed layout requires the size
+	for _, key := range []string{"width", "height"} {
+		if _, ok := n.attr(key); !ok {
+			return fmt.Errorf("pdftohtml: image %q without %s", src, key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package pdftohtml
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// ----------------------------------------------------------------------------
+// -- annotation overlays
+// ----------------------------------------------------------------------------
+
+// Annotation is a note attached to the area of a page. The bounding box is in
+// pixels of the generated HTML, the same space `Command.Words` reports.
+type Annotation struct {
+	Page  int
+	X, Y  float64
+	W, H  float64
+	Color string // CSS color of the overlay, yellow if empty
+	Note  string
+}
+
+// reCSSColor matches CSS colors safe to put into the style attribute.
+var reCSSColor = regexp.MustCompile(`^(#[0-9a-fA-F]{3,8}|[a-zA-Z]+|(rgb|rgba|hsl|hsla)\([0-9.,%\s]+\))$`)
+
+// annotationStyle makes the overlays translucent, the note shows as a tooltip.
+const annotationStyle = "a.pdftohtml-annotation{position:absolute;display:block;opacity:0.35;z-index:10;}" +
+	"a.pdftohtml-annotation:hover{opacity:0.6;}" +
+	"ol.pdftohtml-notes{font-family:sans-serif;font-size:12px;}"
+
+// Annotate renders the annotations into the page files in the outdir. Every
+// annotation becomes a positioned overlay with the note as its tooltip, linking
+// to the numbered note in the list at the end of the page, which links back.
+//
+// Annotations of pages missing in the outdir are ignored. The files are
+// rewritten in place, so the hashes of the manifest no longer match.
+func Annotate(outdir string, annotations []Annotation) error {
+	byPage := make(map[int][]Annotation)
+	for _, a := range annotations {
+		byPage[a.Page] = append(byPage[a.Page], a)
+	}
+
+	return rewriteHTML(outdir, func(name string, data []byte) ([]byte, error) {
+		n := pageNumber(name)
+		if len(byPage[n]) == 0 {
+			return data, nil
+		}
+
+		doc, xhtml := parseDocument(data)
+
+		body := doc.find("body")
+		if body == nil {
+			return data, nil
+		}
+
+		annotatePage(doc, body, n, byPage[n])
+
+		return renderDocument(doc, xhtml), nil
+	})
+}
+
+// annotatePage appends the overlays and the list of notes to the body.
+func annotatePage(doc, body *htmlNode, page int, annotations []Annotation) {
+	// notes are numbered in the reading order of the page
+	annotations = append([]Annotation(nil), annotations...)
+	sort.SliceStable(annotations, func(i, j int) bool {
+		if annotations[i].Y != annotations[j].Y {
+			return annotations[i].Y < annotations[j].Y
+		}
+		return annotations[i].X < annotations[j].X
+	})
+
+	if head := doc.find("head"); head != nil {
+		style := &htmlNode{kind: elementNode, tag: "style"}
+		style.appendChild(&htmlNode{kind: textNode, text: annotationStyle, raw: true})
+		head.appendChild(style)
+	}
+
+	list := &htmlNode{kind: elementNode, tag: "ol", attrs: []htmlAttr{{Key: "class", Val: "pdftohtml-notes"}}}
+
+	// the list goes below the page, which is positioned absolutely
+	if h, ok := backgroundHeight(doc); ok {
+		list.setAttr("style", fmt.Sprintf("position:absolute; left:0px; top:%spx;", formatPixels(h)))
+	}
+
+	for i, a := range annotations {
+		id := fmt.Sprintf("pdftohtml-annotation-%d-%d", page, i+1)
+		note := fmt.Sprintf("pdftohtml-note-%d-%d", page, i+1)
+
+		color := a.Color
+		if !reCSSColor.MatchString(color) {
+			color = "yellow"
+		}
+
+		overlay := &htmlNode{kind: elementNode, tag: "a", attrs: []htmlAttr{
+			{Key: "id", Val: id},
+			{Key: "class", Val: "pdftohtml-annotation"},
+			{Key: "href", Val: "#" + note},
+			{Key: "title", Val: a.Note},
+			{Key: "style", Val: fmt.Sprintf("left:%spx; top:%spx; width:%spx; height:%spx; background-color:%s;",
+				formatPixels(a.X), formatPixels(a.Y), formatPixels(a.W), formatPixels(a.H), color)},
+		}}
+		body.appendChild(overlay)
+		body.appendChild(&htmlNode{kind: textNode, text: "\n"})
+
+		back := &htmlNode{kind: elementNode, tag: "a", attrs: []htmlAttr{{Key: "href", Val: "#" + id}}}
+		back.appendChild(&htmlNode{kind: textNode, text: "↩"})
+
+		item := &htmlNode{kind: elementNode, tag: "li", attrs: []htmlAttr{{Key: "id", Val: note}}}
+		item.appendChild(&htmlNode{kind: textNode, text: a.Note + " "})
+		item.appendChild(back)
+		list.appendChild(item)
+	}
+
+	body.appendChild(list)
+	body.appendChild(&htmlNode{kind: textNode, text: "\n"})
+}
+
+// backgroundHeight returns the height of the background image of the page.
+func backgroundHeight(doc *htmlNode) (float64, bool) {
+	var height float64
+	var found bool
+	doc.walk(func(n *htmlNode) bool {
+		if id, _ := n.attr("id"); found || n.kind != elementNode || n.tag != "img" || id != "background" {
+			return !found
+		}
+
+		val, _ := n.attr("height")
+		if h, err := strconv.ParseFloat(val, 64); err == nil {
+			height, found = h, true
+		}
+
+		return false
+	})
+
+	return height, found
+}
+
+// formatPixels formats the coordinate the way the generated CSS does.
+func formatPixels(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// ----------------------------------------------------------------------------
+// -- annotation options
+// ----------------------------------------------------------------------------
+
+// Render the annotations as overlays in the generated pages. See `Annotate`.
+func WithAnnotations(annotations ...Annotation) option {
+	return func(c *Command) {
+		c.stages = append(c.stages, func(_ context.Context, _, outdir string) error {
+			return Annotate(outdir, annotations)
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package pdftohtml
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- embedded attachments
+// ----------------------------------------------------------------------------
+
+// AttachmentsDir is the directory in the outdir embedded files are extracted to.
+const AttachmentsDir = "attachments"
+
+// AttachmentPolicy tells what to do with files embedded in the PDF file.
+type AttachmentPolicy int
+
+const (
+	// AttachmentsExtract extracts embedded files into the outdir.
+	AttachmentsExtract AttachmentPolicy = iota + 1
+
+	// AttachmentsLink extracts embedded files and links them from the index.
+	AttachmentsLink
+)
+
+// Attachment is a file embedded in the PDF file.
+type Attachment struct {
+	Index int    // 1-based index of `pdfdetach`
+	Name  string // name as stored in the PDF file
+}
+
+// reAttachment matches a line of the `pdfdetach -list` output.
+var reAttachment = regexp.MustCompile(`^\s*(\d+):\s(.*)$`)
+
+// reUnsafeName matches characters replaced in names of extracted files.
+var reUnsafeName = regexp.MustCompile(`[^\w.\- ]+`)
+
+// Attachments lists files embedded in the PDF file, using Xpdf `pdfdetach`.
+func (c *Command) Attachments(ctx context.Context, inpath string) ([]Attachment, error) {
+	out, err := c.detach(ctx, runOptions(ctx, c), "-list", inpath)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachments []Attachment
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		match := reAttachment.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+
+		n, _ := strconv.Atoi(match[1])
+		attachments = append(attachments, Attachment{Index: n, Name: match[2]})
+	}
+
+	return attachments, nil
+}
+
+// detach runs `pdfdetach` with the arguments followed by the PDF file.
+func (c *Command) detach(ctx context.Context, opts OptionSet, args ...string) ([]byte, error) {
+	path, err := c.toolPath("pdfdetach")
+	if err != nil {
+		return nil, err
+	}
+
+	// the PDF file goes last
+	inpath := args[len(args)-1]
+	args = args[:len(args)-1]
+
+	if opts.OwnerPassword != "" {
+		args = append(args, "-opw", opts.OwnerPassword)
+	}
+	if opts.UserPassword != "" {
+		args = append(args, "-upw", opts.UserPassword)
+	}
+
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, path, append(args, "--", inpath)...)
+	cmd.Env = c.environ()
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("pdftohtml: pdfdetach: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return out, nil
+}
+
+// extractAttachments extracts the embedded files into the attachments directory
+// of the outdir, and returns their names in it.
+func (c *Command) extractAttachments(ctx context.Context, inpath, outdir string) ([]string, error) {
+	attachments, err := c.Attachments(ctx, inpath)
+	if err != nil || len(attachments) == 0 {
+		return nil, err
+	}
+
+	dir := filepath.Join(outdir, AttachmentsDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool)
+
+	var names []string
+	for _, a := range attachments {
+		name := attachmentName(a, used)
+
+		out := filepath.Join(dir, name)
+		if _, err := c.detach(ctx, runOptions(ctx, c), "-save", strconv.Itoa(a.Index), "-o", out, inpath); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// attachmentName returns the safe, unique file name of the attachment.
+func attachmentName(a Attachment, used map[string]bool) string {
+	// names may have paths, of any platform
+	name := a.Name
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.Trim(reUnsafeName.ReplaceAllString(name, "_"), ". ")
+	if name == "" {
+		name = fmt.Sprintf("attachment%d", a.Index)
+	}
+
+	unique := name
+	for i := 2; used[strings.ToLower(unique)]; i++ {
+		ext := filepath.Ext(name)
+		unique = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), i, ext)
+	}
+	used[strings.ToLower(unique)] = true
+
+	return unique
+}
+
+// linkAttachments adds the download section with the attachments to the index.
+func linkAttachments(outdir string, names []string) error {
+	path := filepath.Join(outdir, "index.html")
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("<h2>Attachments</h2>\n<ul class=\"pdftohtml-attachments\">\n")
+	for _, name := range names {
+		href := AttachmentsDir + "/" + url.PathEscape(name)
+		fmt.Fprintf(&b, "<li><a href=\"%s\" download>%s</a></li>\n", html.EscapeString(href), html.EscapeString(name))
+	}
+	b.WriteString("</ul>\n")
+
+	return os.WriteFile(path, insertBodyEnd(data, []byte(b.String())), 0o644)
+}
+
+// reBodyEnd matches the closing tag of the body element.
+var reBodyEnd = regexp.MustCompile(`(?i)</body\s*>`)
+
+// insertBodyEnd inserts the markup at the end of the body element, or at the
+// end of the document if there is no closing tag.
+func insertBodyEnd(data, markup []byte) []byte {
+	loc := reBodyEnd.FindIndex(data)
+	if loc == nil {
+		return append(data, markup...)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(data[:loc[0]])
+	buf.Write(markup)
+	buf.Write(data[loc[0]:])
+
+	return buf.Bytes()
+}
+
+// ----------------------------------------------------------------------------
+// -- embedded attachments options
+// ----------------------------------------------------------------------------
+
+// Extract files embedded in the PDF file into the `attachments` directory of the
+// outdir, using Xpdf `pdfdetach`, and list them in the manifest. Depending on
+// the policy, they are also linked from the index.
+func WithAttachments(policy AttachmentPolicy) option {
+	return func(c *Command) {
+		c.stages = append(c.stages, func(ctx context.Context, inpath, outdir string) error {
+			names, err := c.extractAttachments(ctx, inpath, outdir)
+			if err != nil || len(names) == 0 || policy != AttachmentsLink {
+				return err
+			}
+
+			return linkAttachments(outdir, names)
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package pdftohtml
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- audit log
+// ----------------------------------------------------------------------------
+
+// Audit statuses of the conversion.
+const (
+	AuditSucceeded = "succeeded"
+	AuditFailed    = "failed"
+	AuditCanceled  = "canceled"
+)
+
+// AuditRecord describes a single conversion for the audit log.
+type AuditRecord struct {
+	Time       time.Time         `json:"time"`                  // start of the conversion
+	Duration   time.Duration     `json:"duration"`              // duration of the conversion
+	Actor      string            `json:"actor,omitempty"`       // who requested the conversion
+	Fields     map[string]string `json:"fields,omitempty"`      // additional context of the request
+	Input      string            `json:"input"`                 // path of the input
+	InputHash  string            `json:"input-hash,omitempty"`  // SHA-256 of the input
+	Args       []string          `json:"args,omitempty"`        // arguments, passwords redacted
+	Status     string            `json:"status"`                // one of the audit statuses
+	Error      string            `json:"error,omitempty"`       // reason of the failure
+	OutputHash string            `json:"output-hash,omitempty"` // SHA-256 of the output file list
+}
+
+// AuditSink stores audit records, e.g. in a file or a database.
+type AuditSink interface {
+	Record(ctx context.Context, record AuditRecord) error
+}
+
+type auditKey struct{}
+
+type auditContext struct {
+	actor  string
+	fields map[string]string
+}
+
+// WithAuditActor returns the context recording the actor in audit records of
+// conversions run with it.
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+	a := auditFrom(ctx)
+	a.actor = actor
+
+	return context.WithValue(ctx, auditKey{}, a)
+}
+
+// WithAuditField returns the context recording the field in audit records of
+// conversions run with it, e.g. request ID or tenant.
+func WithAuditField(ctx context.Context, key, value string) context.Context {
+	a := auditFrom(ctx)
+	a.fields = maps.Clone(a.fields)
+	if a.fields == nil {
+		a.fields = make(map[string]string)
+	}
+	a.fields[key] = value
+
+	return context.WithValue(ctx, auditKey{}, a)
+}
+
+func auditFrom(ctx context.Context) auditContext {
+	a, _ := ctx.Value(auditKey{}).(auditContext)
+
+	return a
+}
+
+// Auditor records every conversion of the runner into the audit sink.
+type Auditor struct {
+	runner Runner
+	sink   AuditSink
+}
+
+// NewAuditor creates new auditor of the runner. Arguments are recorded when the
+// runner is `Command`.
+func NewAuditor(runner Runner, sink AuditSink) *Auditor {
+	return &Auditor{runner: runner, sink: sink}
+}
+
+// Run executes the runner and records the conversion. Failing to record the
+// conversion fails the run, even when the conversion succeeded.
+func (a *Auditor) Run(ctx context.Context, inpath, outdir string) error {
+	actx := auditFrom(ctx)
+	record := AuditRecord{Time: time.Now().UTC(), Actor: actx.actor, Fields: actx.fields, Input: inpath}
+
+	// hash before the conversion, so the record describes what was converted
+	if hash, err := hashFile(inpath); err == nil {
+		record.InputHash = hash
+	}
+
+	if cmd, ok := a.runner.(*Command); ok {
+		record.Args = redactArgs(cmd.Args())
+	}
+
+	err := a.runner.Run(ctx, inpath, outdir)
+	record.Duration = time.Since(record.Time)
+
+	var errs []error
+	switch {
+	case err == nil:
+		record.Status = AuditSucceeded
+		if record.OutputHash, err = outputHash(outdir); err != nil {
+			errs = append(errs, err)
+		}
+	case ctx.Err() != nil:
+		record.Status, record.Error = AuditCanceled, err.Error()
+		errs = append(errs, err)
+	default:
+		record.Status, record.Error = AuditFailed, err.Error()
+		errs = append(errs, err)
+	}
+
+	if err := a.sink.Record(context.WithoutCancel(ctx), record); err != nil {
+		errs = append(errs, fmt.Errorf("pdftohtml: audit: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// redactArgs returns the arguments with passwords replaced.
+func redactArgs(args []string) []string {
+	args = append([]string(nil), args...)
+	for i := 0; i < len(args)-1; i++ {
+		if args[i] == "-opw" || args[i] == "-upw" {
+			args[i+1] = "REDACTED"
+			i++
+		}
+	}
+
+	return args
+}
+
+// outputHash returns the hash of names and content hashes of all files in the
+// outdir, so the same output always has the same hash.
+func outputHash(outdir string) (string, error) {
+	m, err := newManifest("", outdir)
+	if err != nil {
+		return "", err
+	}
+
+	sort.Slice(m.Files, func(i, j int) bool {
+		return m.Files[i].Name < m.Files[j].Name
+	})
+
+	h := sha256.New()
+	for _, f := range m.Files {
+		fmt.Fprintf(h, "%s\x00%s\n", f.Name, f.Hash)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ----------------------------------------------------------------------------
+// -- audit file sink
+// ----------------------------------------------------------------------------
+
+// FileAuditSink appends audit records to the file as JSON lines.
+type FileAuditSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFileAuditSink opens the file for appending audit records, creating it if
+// needed.
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileAuditSink{f: f}, nil
+}
+
+// Record appends the record to the file and syncs it to the disk.
+func (s *FileAuditSink) Record(_ context.Context, record AuditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	return s.f.Sync()
+}
+
+// Close closes the file.
+func (s *FileAuditSink) Close() error {
+	return s.f.Close()
+}
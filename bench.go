@@ -0,0 +1,221 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- benchmark
+// ----------------------------------------------------------------------------
+
+// BenchmarkProfile is the named combination of options compared by `Benchmark`.
+type BenchmarkProfile struct {
+	Name    string
+	Options []option
+}
+
+// BenchmarkResult describes conversions of the corpus with a single profile.
+type BenchmarkResult struct {
+	Profile   string        `json:"profile"`
+	Documents int           `json:"documents"`          // converted documents, failures excluded
+	Failures  []string      `json:"failures,omitempty"` // documents that failed, with the errors
+	Duration  time.Duration `json:"duration"`           // total, in nanoseconds in JSON
+	Size      int64         `json:"size"`               // total size of the outputs, in bytes
+	// Fidelity is the mean similarity of the text of the generated pages to the
+	// text extracted by `pdftotext`, from 0 to 1. It is missing without the
+	// `pdftotext` executable.
+	Fidelity *float64 `json:"fidelity,omitempty"`
+}
+
+// MeanDuration returns the mean duration of the conversion of a document.
+func (r BenchmarkResult) MeanDuration() time.Duration {
+	if r.Documents == 0 {
+		return 0
+	}
+
+	return r.Duration / time.Duration(r.Documents)
+}
+
+// Benchmark converts every PDF file in the corpus directory, and its
+// subdirectories, with each of the profiles, and compares their durations,
+// output sizes and text fidelity. Without profiles, the presets are compared.
+//
+// The text of the pages is compared with the text extracted by Xpdf `pdftotext`,
+// found next to the `pdftohtml` executable of the profile or in the PATH, word
+// by word regardless of the order. The reference is the text of all pages, so
+// profiles selecting pages score lower.
+func Benchmark(ctx context.Context, corpus string, profiles ...BenchmarkProfile) ([]BenchmarkResult, error) {
+	if len(profiles) == 0 {
+		for _, preset := range []Preset{PresetFast, PresetHighFidelity, PresetArchival, PresetTextOnly} {
+			profiles = append(profiles, BenchmarkProfile{Name: string(preset), Options: []option{WithPreset(preset)}})
+		}
+	}
+
+	var docs []string
+	err := filepath.WalkDir(corpus, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".pdf") {
+			docs = append(docs, path)
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tmpdir, err := os.MkdirTemp("", "pdftohtml-benchmark-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// reference text does not depend on the profile
+	references := make(map[string][]string)
+
+	results := make([]BenchmarkResult, 0, len(profiles))
+	for i, profile := range profiles {
+		c, err := NewCommand(profile.Options...)
+		if err != nil {
+			return nil, fmt.Errorf("pdftohtml: benchmark profile %q: %w", profile.Name, err)
+		}
+
+		result := BenchmarkResult{Profile: profile.Name}
+
+		var fidelity float64
+		var compared int
+		for j, doc := range docs {
+			outdir := filepath.Join(tmpdir, fmt.Sprintf("%d-%d", i, j))
+
+			start := time.Now()
+			if err := c.Run(ctx, doc, outdir); err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				result.Failures = append(result.Failures, fmt.Sprintf("%s: %v", doc, err))
+				continue
+			}
+			result.Duration += time.Since(start)
+			result.Documents++
+
+			words, err := outputWords(outdir, &result.Size)
+			if err != nil {
+				return nil, err
+			}
+
+			reference, ok := references[doc]
+			if !ok {
+				reference, ok = c.referenceWords(ctx, doc)
+				if ok {
+					references[doc] = reference
+				}
+			}
+			if ok {
+				fidelity += textSimilarity(words, reference)
+				compared++
+			}
+
+			if err := os.RemoveAll(outdir); err != nil {
+				return nil, err
+			}
+		}
+
+		if compared > 0 {
+			mean := fidelity / float64(compared)
+			result.Fidelity = &mean
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// outputWords returns the words of the pages in the outdir, adding the size of
+// the output to the total.
+func outputWords(outdir string, size *int64) ([]string, error) {
+	var words []string
+	err := filepath.WalkDir(outdir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		*size += info.Size()
+
+		if pageNumber(d.Name()) == 0 || filepath.Dir(path) != outdir {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		words = append(words, strings.Fields(strings.ToLower(html.UnescapeString(string(htmlText(data)))))...)
+
+		return nil
+	})
+
+	return words, err
+}
+
+// referenceWords returns the words of the PDF file extracted by `pdftotext`, or
+// false if it is not available or fails.
+func (c *Command) referenceWords(ctx context.Context, inpath string) ([]string, bool) {
+	path, err := c.toolPath("pdftotext")
+	if err != nil {
+		return nil, false
+	}
+
+	var args []string
+	if c.opts.OwnerPassword != "" {
+		args = append(args, "-opw", c.opts.OwnerPassword)
+	}
+	if c.opts.UserPassword != "" {
+		args = append(args, "-upw", c.opts.UserPassword)
+	}
+
+	cmd := exec.CommandContext(ctx, path, append(args, "-enc", "UTF-8", "--", inpath, "-")...)
+	cmd.Env = c.environ()
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, false
+	}
+
+	return strings.Fields(strings.ToLower(string(bytes.ToValidUTF8(out, nil)))), true
+}
+
+// textSimilarity returns the Dice coefficient of the multisets of the words,
+// 1 for identical texts and 0 for texts without common words.
+func textSimilarity(a, b []string) float64 {
+	if len(a)+len(b) == 0 {
+		return 1
+	}
+
+	counts := make(map[string]int, len(b))
+	for _, w := range b {
+		counts[w]++
+	}
+
+	common := 0
+	for _, w := range a {
+		if counts[w] > 0 {
+			counts[w]--
+			common++
+		}
+	}
+
+	return 2 * float64(common) / float64(len(a)+len(b))
+}
@@ -0,0 +1,141 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- background image format
+// ----------------------------------------------------------------------------
+
+// reBackgroundRef matches references to the background image files of the page
+// in the `src` and `srcset` attributes.
+var reBackgroundRef = regexp.MustCompile(`\bpage\d+(?:@[0-9.]+x|-[a-z][a-z0-9]*)?\.png\b`)
+
+// transcodeJPEG re-encodes the PNG image as JPEG of the quality. Transparent
+// areas, which JPEG cannot store, become white.
+func transcodeJPEG(data []byte, quality int) ([]byte, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// jpegBackgrounds creates the stage converting the background images of the
+// pages, stored in the outdir or embedded, to JPEG, and updating the references
+// to the stored ones.
+func jpegBackgrounds(quality int) stage {
+	return func(_ context.Context, _, outdir string) error {
+		entries, err := os.ReadDir(outdir)
+		if err != nil {
+			return err
+		}
+
+		renamed := make(map[string]bool)
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() || !reBackgroundFile.MatchString(entry.Name()) {
+				continue
+			}
+
+			path := filepath.Join(outdir, entry.Name())
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			if data, err = transcodeJPEG(data, quality); err != nil {
+				return err
+			}
+
+			if err := os.WriteFile(strings.TrimSuffix(path, ".png")+".jpg", data, 0o644); err != nil {
+				return err
+			}
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			renamed[entry.Name()] = true
+		}
+
+		return rewriteHTML(outdir, func(name string, data []byte) ([]byte, error) {
+			if pageNumber(name) == 0 {
+				return data, nil
+			}
+
+			var err error
+			data = reBackground.ReplaceAllFunc(data, func(tag []byte) []byte {
+				tag = reBackgroundRef.ReplaceAllFunc(tag, func(ref []byte) []byte {
+					if !renamed[string(ref)] {
+						return ref
+					}
+
+					return append(bytes.TrimSuffix(ref, []byte(".png")), ".jpg"...)
+				})
+
+				return reEmbeddedPNG.ReplaceAllFunc(tag, func(attr []byte) []byte {
+					match := reEmbeddedPNG.FindSubmatch(attr)
+
+					img, derr := base64.StdEncoding.DecodeString(string(match[2]))
+					if derr == nil {
+						img, derr = transcodeJPEG(img, quality)
+					}
+					if derr != nil {
+						err = derr
+						return attr
+					}
+
+					prefix := strings.Replace(string(match[1]), "image/png", "image/jpeg", 1)
+
+					return []byte(prefix + base64.StdEncoding.EncodeToString(img) + `"`)
+				})
+			})
+
+			return data, err
+		})
+	}
+}
+
+// ----------------------------------------------------------------------------
+// -- background image format options
+// ----------------------------------------------------------------------------
+
+// Convert the background images to JPEG of the quality, from 1 to 100, after
+// the conversion. JPEG is much smaller than PNG for scanned documents and
+// photographs, but blurs sharp lines and text drawn in the background.
+//
+// Xpdf `pdftohtml` renders PNG backgrounds only, so the images are transcoded
+// by the package, also the embedded ones and the extra renditions. Stages
+// processing the PNG backgrounds, e.g. `WithGrayscaleBackgrounds`, must be given
+// before this option.
+func WithJPEGBackgrounds(quality int) option {
+	return func(c *Command) {
+		if !c.checkRange(RangeError{Option: "JPEG quality", Value: float64(quality), Min: 1, Max: 100}) {
+			return
+		}
+
+		c.stages = append(c.stages, jpegBackgrounds(quality))
+	}
+}
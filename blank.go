@@ -0,0 +1,211 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- blank pages
+// ----------------------------------------------------------------------------
+
+// BlankPagePolicy tells what to do with detected blank pages.
+type BlankPagePolicy int
+
+const (
+	// BlankPagesMark only marks blank pages in the manifest.
+	BlankPagesMark BlankPagePolicy = iota + 1
+
+	// BlankPagesCollapse keeps blank pages, but removes them from the index.
+	BlankPagesCollapse
+
+	// BlankPagesSkip removes blank pages from the output and from the index.
+	BlankPagesSkip
+)
+
+// reBackground matches the background image of the page.
+var reBackground = regexp.MustCompile(`(?i)<img\b[^>]*\bid="background"[^>]*>`)
+
+// attrValue returns the unescaped value of the tag attribute.
+func attrValue(tag []byte, name string) (string, bool) {
+	re := regexp.MustCompile(`(?i)\s` + regexp.QuoteMeta(name) + `\s*=\s*"([^"]*)"`)
+
+	match := re.FindSubmatch(tag)
+	if match == nil {
+		return "", false
+	}
+
+	return html.UnescapeString(string(match[1])), true
+}
+
+// isBlankPage reports whether the page has no text and its background image is
+// nearly uniform.
+func isBlankPage(outdir string, data []byte) (bool, error) {
+	text := html.UnescapeString(string(htmlText(data)))
+	if strings.TrimSpace(strings.ReplaceAll(text, " ", " ")) != "" {
+		return false, nil
+	}
+
+	tag := reBackground.Find(data)
+	if tag == nil {
+		return true, nil
+	}
+	src, _ := attrValue(tag, "src")
+
+	img, err := loadImage(outdir, src)
+	if err != nil {
+		return false, err
+	}
+
+	return isUniform(img), nil
+}
+
+// loadImage decodes the image referenced by the page, either embedded as data
+// URI or stored in the outdir.
+func loadImage(outdir, src string) (image.Image, error) {
+	var data []byte
+
+	if payload, ok := strings.CutPrefix(src, "data:"); ok {
+		_, encoded, ok := strings.Cut(payload, ";base64,")
+		if !ok {
+			return nil, fmt.Errorf("pdftohtml: unsupported image data URI")
+		}
+
+		var err error
+		if data, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if data, err = os.ReadFile(filepath.Join(outdir, filepath.FromSlash(src))); err != nil {
+			return nil, err
+		}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+
+	return img, err
+}
+
+// isUniform reports whether nearly all sampled pixels have the same luminance,
+// leaving room for noise of scanned pages.
+func isUniform(img image.Image) bool {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return true
+	}
+	step := max(1, min(bounds.Dx(), bounds.Dy())/200)
+
+	var samples []uint8
+	var sum int
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			lum := color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y
+			samples = append(samples, lum)
+			sum += int(lum)
+		}
+	}
+	mean := sum / len(samples)
+
+	outliers := 0
+	for _, lum := range samples {
+		if d := int(lum) - mean; d > 32 || d < -32 {
+			outliers++
+		}
+	}
+
+	return outliers*500 < len(samples)
+}
+
+// handleBlankPages detects blank pages in the outdir and applies the policy to
+// them. It returns numbers of the blank pages.
+func (c *Command) handleBlankPages(outdir string) ([]int, error) {
+	if c.blank == 0 {
+		return nil, nil
+	}
+
+	names, err := listHTML(outdir)
+	if err != nil {
+		return nil, err
+	}
+
+	var blank []int
+	for _, name := range names {
+		n := pageNumber(name)
+		if n == 0 {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(outdir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		ok, err := isBlankPage(outdir, data)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			blank = append(blank, n)
+		}
+	}
+
+	if c.blank == BlankPagesMark {
+		return blank, nil
+	}
+
+	for _, n := range blank {
+		if err := removeIndexLink(outdir, fmt.Sprintf("page%d.html", n)); err != nil {
+			return nil, err
+		}
+
+		if c.blank == BlankPagesSkip {
+			for _, ext := range []string{".html", ".png"} {
+				err := os.Remove(filepath.Join(outdir, fmt.Sprintf("page%d%s", n, ext)))
+				if err != nil && !os.IsNotExist(err) {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return blank, nil
+}
+
+// removeIndexLink removes the link to the page from the index.
+func removeIndexLink(outdir, name string) error {
+	path := filepath.Join(outdir, "index.html")
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	re := regexp.MustCompile(`(?i)<a\s[^>]*href="` + regexp.QuoteMeta(name) + `"[^>]*>.*?</a>(?:<br\s*/?>)?\n?`)
+
+	return os.WriteFile(path, re.ReplaceAll(data, nil), 0o644)
+}
+
+// Detect blank pages: pages without text with nearly uniform background image.
+//
+// Depending on the policy blank pages are only marked in the manifest, removed
+// from the index, or removed from the output altogether. Blank pages are always
+// marked in the manifest, which is written whenever the policy is `BlankPagesMark`.
+func WithBlankPages(policy BlankPagePolicy) option {
+	return func(c *Command) {
+		c.blank = policy
+	}
+}
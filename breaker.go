@@ -0,0 +1,152 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- circuit breaker
+// ----------------------------------------------------------------------------
+
+// ErrCircuitOpen is returned by the breaker when conversions are refused after
+// repeated infrastructure failures.
+var ErrCircuitOpen = errors.New("pdftohtml: circuit open")
+
+// Breaker stops running conversions when they fail repeatedly because of the
+// host, not the input: missing executable, exec format errors, or processes
+// killed by the system (e.g. by the OOM killer).
+//
+// After a cooldown the breaker lets a single probe conversion through, and
+// closes again if it does not fail with an infrastructure error.
+type Breaker struct {
+	runner    Runner
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewBreaker creates new circuit breaker around the runner.
+func NewBreaker(runner Runner, opts ...breakerOption) *Breaker {
+	b := &Breaker{runner: runner, threshold: 5, cooldown: 30 * time.Second, now: time.Now}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// Run executes the conversion, unless the circuit is open.
+func (b *Breaker) Run(ctx context.Context, inpath, outdir string) error {
+	probe, err := b.acquire()
+	if err != nil {
+		return err
+	}
+
+	// the probe is given back even if the runner panics
+	var finished, failed bool
+	defer func() {
+		b.release(probe, finished, failed)
+	}()
+
+	err = b.runner.Run(ctx, inpath, outdir)
+	finished, failed = ctx.Err() == nil, isInfraError(err)
+
+	return err
+}
+
+// acquire lets the conversion through, unless the circuit is open, and reports
+// whether it is the probe.
+func (b *Breaker) acquire() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return false, nil
+	}
+
+	// circuit is open, let single probe through after the cooldown
+	if b.probing || b.now().Sub(b.openedAt) < b.cooldown {
+		return false, ErrCircuitOpen
+	}
+	b.probing = true
+
+	return true, nil
+}
+
+// release records the outcome of the conversion. Only the probe ends probing,
+// and conversions that panicked or were canceled tell nothing about the host.
+func (b *Breaker) release(probe, finished, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+	if !finished {
+		return
+	}
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = b.now()
+	}
+}
+
+// isInfraError reports whether the error was caused by the host rather than by
+// the converted document.
+func isInfraError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// executable missing, not executable or of wrong format
+	var pathErr *fs.PathError
+	if errors.Is(err, exec.ErrNotFound) || errors.As(err, &pathErr) && pathErr.Op == "fork/exec" {
+		return true
+	}
+
+	// process killed by the system
+	sig, ok := exitSignal(err)
+
+	return ok && sig == os.Kill
+}
+
+// ----------------------------------------------------------------------------
+// -- circuit breaker options
+// ----------------------------------------------------------------------------
+
+type breakerOption func(*Breaker)
+
+// Set the number of consecutive infrastructure failures opening the circuit.
+//
+// The default is 5.
+func WithBreakerThreshold(n int) breakerOption {
+	return func(b *Breaker) {
+		b.threshold = n
+	}
+}
+
+// Set the time the circuit stays open before probing recovery.
+//
+// The default is 30 seconds.
+func WithBreakerCooldown(d time.Duration) breakerOption {
+	return func(b *Breaker) {
+		b.cooldown = d
+	}
+}
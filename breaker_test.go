@@ -0,0 +1,156 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+)
+
+// runnerFunc is the runner calling the function.
+type runnerFunc func(ctx context.Context) error
+
+func (f runnerFunc) Run(ctx context.Context, _, _ string) error {
+	return f(ctx)
+}
+
+// blockingRunner returns the runner signaling its start and returning the error
+// sent to it.
+func blockingRunner() (Runner, chan struct{}, chan error) {
+	started, finish := make(chan struct{}), make(chan error)
+
+	return runnerFunc(func(context.Context) error {
+		close(started)
+		return <-finish
+	}), started, finish
+}
+
+// fakeClock is the clock advanced by the test only.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.t = c.t.Add(d)
+}
+
+func TestBreakerCooldown(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	b := NewBreaker(runnerFunc(func(context.Context) error {
+		return &exec.Error{Name: "pdftohtml", Err: exec.ErrNotFound}
+	}), WithBreakerThreshold(2), WithBreakerCooldown(time.Minute))
+	b.now = clock.now
+
+	for i := 0; i < 2; i++ {
+		if err := b.Run(context.Background(), "", ""); !errors.Is(err, exec.ErrNotFound) {
+			t.Fatalf("run %d: got %v, want infrastructure error", i, err)
+		}
+	}
+
+	clock.advance(time.Minute - time.Nanosecond)
+	if err := b.Run(context.Background(), "", ""); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("before the cooldown: got %v, want ErrCircuitOpen", err)
+	}
+
+	// failed probe opens the circuit for another cooldown
+	clock.advance(time.Nanosecond)
+	if err := b.Run(context.Background(), "", ""); !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("after the cooldown: got %v, want the probe", err)
+	}
+	if err := b.Run(context.Background(), "", ""); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("after the failed probe: got %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestBreakerProbe(t *testing.T) {
+	infra := &exec.Error{Name: "pdftohtml", Err: exec.ErrNotFound}
+
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	runners := make(chan Runner, 1)
+	b := NewBreaker(runnerFunc(func(ctx context.Context) error {
+		return (<-runners).Run(ctx, "", "")
+	}), WithBreakerThreshold(1), WithBreakerCooldown(time.Minute))
+	b.now = clock.now
+
+	run := func(r Runner) chan error {
+		done := make(chan error, 1)
+		runners <- r
+		go func() { done <- b.Run(context.Background(), "", "") }()
+		return done
+	}
+
+	// conversion started before the circuit opened finishes during the probe
+	early, earlyStarted, earlyFinish := blockingRunner()
+	earlyDone := run(early)
+	<-earlyStarted
+
+	if err := <-run(runnerFunc(func(context.Context) error { return infra })); !errors.Is(err, exec.ErrNotFound) {
+		t.Fatalf("got %v, want infrastructure error", err)
+	}
+	clock.advance(time.Minute)
+
+	probe, probeStarted, probeFinish := blockingRunner()
+	probeDone := run(probe)
+	<-probeStarted
+
+	// the other conversion must not end the probe
+	earlyFinish <- infra
+	<-earlyDone
+	clock.advance(time.Minute)
+	if err := <-run(runnerFunc(func(context.Context) error { return nil })); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("during the probe: got %v, want ErrCircuitOpen", err)
+	}
+	select {
+	case <-runners: // not taken by the refused conversion
+	default:
+	}
+
+	probeFinish <- nil
+	if err := <-probeDone; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-run(runnerFunc(func(context.Context) error { return nil })); err != nil {
+		t.Errorf("after the probe: got %v, want closed circuit", err)
+	}
+}
+
+func TestBreakerProbePanics(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	fail := true
+	b := NewBreaker(runnerFunc(func(context.Context) error {
+		if fail {
+			return &exec.Error{Name: "pdftohtml", Err: exec.ErrNotFound}
+		}
+		panic("runner failed")
+	}), WithBreakerThreshold(1), WithBreakerCooldown(time.Minute))
+	b.now = clock.now
+
+	_ = b.Run(context.Background(), "", "")
+	clock.advance(time.Minute)
+
+	fail = false
+	func() {
+		defer func() { _ = recover() }()
+		_ = b.Run(context.Background(), "", "")
+	}()
+
+	// the panicked probe is given back, so the next conversion probes again
+	fail = true
+	if err := b.Run(context.Background(), "", ""); errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("got %v, want another probe", err)
+	}
+}
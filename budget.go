@@ -0,0 +1,121 @@
+package pdftohtml
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// ----------------------------------------------------------------------------
+// -- output size budget
+// ----------------------------------------------------------------------------
+
+// Resolutions of the probe conversions, and the bounds of the picked one.
+var budgetDPIs = []float64{36, 72, 144}
+
+const (
+	budgetMinDPI = 36
+	budgetMaxDPI = 600
+)
+
+// fitResolution picks the background resolution of the run, so the output of
+// the selected pages fits the size budget. The sample page is converted at the
+// probe resolutions, and its size is fitted as a fixed part, e.g. markup and
+// fonts, and a part growing with the pixels of the background.
+func (c *Command) fitResolution(ctx context.Context, opts OptionSet, inpath string) (OptionSet, []string, error) {
+	if c.budget == 0 {
+		return opts, nil, nil
+	}
+
+	count, err := c.pageCount(ctx, opts, inpath)
+	if err != nil {
+		return opts, nil, err
+	}
+
+	var selected []uint64
+	for n := max(opts.PageFrom, 1); n <= count && (opts.PageTo == 0 || n <= opts.PageTo); n++ {
+		if c.pages.contains(n) {
+			selected = append(selected, n)
+		}
+	}
+	if len(selected) == 0 {
+		return opts, nil, nil
+	}
+	sample := selected[len(selected)/2]
+
+	tmpdir, err := os.MkdirTemp("", "pdftohtml-budget-*")
+	if err != nil {
+		return opts, nil, err
+	}
+	defer os.RemoveAll(tmpdir)
+
+	probe := *c
+	probe.onPage = nil
+
+	// least squares of the size against the square of the resolution
+	var sx, sy, sxx, sxy float64
+	for _, dpi := range budgetDPIs {
+		o := opts
+		o.PageFrom, o.PageTo, o.Resolution = sample, sample, uint64(dpi)
+
+		rundir := filepath.Join(tmpdir, strconv.Itoa(int(dpi)))
+		if err := probe.exec(ctx, o, inpath, rundir); err != nil {
+			return opts, nil, err
+		}
+
+		size, err := dirSize(rundir)
+		if err != nil {
+			return opts, nil, err
+		}
+
+		x, y := dpi*dpi, float64(size)
+		sx, sy, sxx, sxy = sx+x, sy+y, sxx+x*x, sxy+x*y
+	}
+	k := float64(len(budgetDPIs))
+	perPixel := (k*sxy - sx*sy) / (k*sxx - sx*sx)
+	fixed := (sy - perPixel*sx) / k
+
+	perPage := float64(c.budget) / float64(len(selected))
+
+	dpi := float64(budgetMaxDPI)
+	if perPixel > 0 {
+		dpi = math.Sqrt(max(perPage-fixed, 0) / perPixel)
+	}
+
+	var warnings []string
+	if dpi < budgetMinDPI {
+		warnings = append(warnings, fmt.Sprintf("output of %d pages exceeds the size budget of %d bytes even at %d DPI",
+			len(selected), c.budget, budgetMinDPI))
+	}
+	opts.Resolution = uint64(min(max(dpi, budgetMinDPI), budgetMaxDPI))
+
+	return opts, warnings, nil
+}
+
+// ----------------------------------------------------------------------------
+// -- output size budget options
+// ----------------------------------------------------------------------------
+
+// Picks the resolution of the backgrounds, so the output of the conversion lands
+// near the size budget in bytes, e.g. for delivery to mobile devices. This
+// overrides `WithResolution`.
+//
+// The middle page of the selection is converted at a few resolutions first, and
+// the resolution is interpolated from their sizes, between 36 and 600 DPI. The
+// budget covers the output of `pdftohtml`, not files added by stages. If the
+// budget cannot be met, the lowest resolution is used with the warning in the
+// manifest.
+//
+// The budget must be greater than 0.
+func WithTargetOutputSize(bytes int64) option {
+	return func(c *Command) {
+		if !c.checkRange(RangeError{Option: "target output size", Value: float64(bytes), Min: 0, Max: math.Inf(+1), Exclusive: true}) {
+			return
+		}
+
+		c.budget = bytes
+	}
+}
@@ -0,0 +1,251 @@
+package pdftohtml
+
+import (
+	"container/list"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- disk cache
+// ----------------------------------------------------------------------------
+
+// ErrInvalidCacheKey is returned when the key cannot be used as a name of the
+// cache entry directory.
+var ErrInvalidCacheKey = errors.New("pdftohtml: invalid cache key")
+
+// Cache keeps conversion outputs in the root directory, evicting the least
+// recently used ones once the configured limits are exceeded.
+//
+// Entries found in the root are picked up on creation, ordered by the time they
+// were last used.
+type Cache struct {
+	root       string
+	maxBytes   int64
+	maxEntries int
+
+	mu      sync.Mutex
+	lru     *list.List // front is the most recently used
+	entries map[string]*list.Element
+	stats   CacheStats
+}
+
+// CacheStats describes the usage of the cache.
+type CacheStats struct {
+	Entries      int   // number of entries
+	Bytes        int64 // total size of entries
+	Hits         int64 // lookups of existing entries
+	Misses       int64 // lookups of missing entries
+	Evictions    int64 // evicted entries
+	EvictedBytes int64 // total size of evicted entries
+}
+
+type cacheEntry struct {
+	key  string
+	size int64
+	used time.Time
+}
+
+// NewCache creates new cache in the root directory.
+func NewCache(root string, opts ...cacheOption) (*Cache, error) {
+	c := &Cache{root: root, lru: list.New(), entries: make(map[string]*list.Element)}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+
+	dirs, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []*cacheEntry
+	for _, d := range dirs {
+		// skip workspaces of interrupted conversions
+		if !d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			continue
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := dirSize(filepath.Join(root, d.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		found = append(found, &cacheEntry{key: d.Name(), size: size, used: info.ModTime()})
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].used.After(found[j].used)
+	})
+	for _, e := range found {
+		c.entries[e.key] = c.lru.PushBack(e)
+		c.stats.Entries++
+		c.stats.Bytes += e.size
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c, c.evict()
+}
+
+// Root returns the root directory of the cache.
+func (c *Cache) Root() string {
+	return c.root
+}
+
+// Path returns the path of the entry directory, which exists only if the entry
+// is cached.
+func (c *Cache) Path(key string) string {
+	return filepath.Join(c.root, key)
+}
+
+// Get returns the path of the cached entry and marks it as recently used.
+func (c *Cache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return "", false
+	}
+	c.stats.Hits++
+
+	e := elem.Value.(*cacheEntry)
+	e.used = time.Now()
+	c.lru.MoveToFront(elem)
+
+	// usage survives restarts as the modification time of the entry
+	path := c.Path(key)
+	_ = os.Chtimes(path, e.used, e.used)
+
+	return path, true
+}
+
+// Put moves the directory into the cache under the key, replacing the existing
+// entry, and evicts least recently used entries over the limits. The directory
+// has to be on the same filesystem as the cache root.
+//
+// The newly put entry is never evicted, even when it exceeds the limits alone.
+func (c *Cache) Put(key, dir string) (string, error) {
+	if key == "" || strings.HasPrefix(key, ".") || strings.ContainsAny(key, `/\`) {
+		return "", ErrInvalidCacheKey
+	}
+
+	size, err := dirSize(dir)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path := c.Path(key)
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+		if err := os.RemoveAll(path); err != nil {
+			return "", err
+		}
+	}
+
+	if err := os.Rename(dir, path); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, size: size, used: now})
+	c.stats.Entries++
+	c.stats.Bytes += size
+
+	return path, c.evict()
+}
+
+// Remove removes the entry from the cache.
+func (c *Cache) Remove(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.remove(elem)
+
+	return os.RemoveAll(c.Path(key))
+}
+
+// Stats returns the current usage of the cache.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stats
+}
+
+// remove forgets the entry, without touching the disk.
+func (c *Cache) remove(elem *list.Element) {
+	e := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, e.key)
+	c.stats.Entries--
+	c.stats.Bytes -= e.size
+}
+
+// evict removes least recently used entries until the cache is within the
+// limits, keeping the most recently used one.
+func (c *Cache) evict() error {
+	for c.lru.Len() > 1 {
+		over := c.maxBytes > 0 && c.stats.Bytes > c.maxBytes
+		over = over || c.maxEntries > 0 && c.stats.Entries > c.maxEntries
+		if !over {
+			return nil
+		}
+
+		elem := c.lru.Back()
+		e := elem.Value.(*cacheEntry)
+		c.remove(elem)
+		c.stats.Evictions++
+		c.stats.EvictedBytes += e.size
+
+		if err := os.RemoveAll(c.Path(e.key)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+// -- disk cache options
+// ----------------------------------------------------------------------------
+
+type cacheOption func(*Cache)
+
+// Limit the total size of cached entries, in bytes.
+func WithCacheMaxBytes(bytes int64) cacheOption {
+	return func(c *Cache) {
+		c.maxBytes = bytes
+	}
+}
+
+// Limit the number of cached entries.
+func WithCacheMaxEntries(n int) cacheOption {
+	return func(c *Cache) {
+		c.maxEntries = n
+	}
+}
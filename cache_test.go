@@ -0,0 +1,169 @@
+package pdftohtml
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// putEntry puts the new directory with the file of the size into the cache.
+func putEntry(t *testing.T, c *Cache, key string, size int) {
+	t.Helper()
+
+	dir, err := os.MkdirTemp(filepath.Dir(c.Root()), "entry-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "page1.html"), make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Put(key, dir); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// cachedKeys returns which of the keys are in the cache, on disk as well.
+func cachedKeys(t *testing.T, c *Cache, keys ...string) []string {
+	t.Helper()
+
+	var cached []string
+	for _, key := range keys {
+		c.mu.Lock()
+		_, ok := c.entries[key]
+		c.mu.Unlock()
+
+		_, err := os.Stat(c.Path(key))
+		if ok != (err == nil) {
+			t.Fatalf("entry %q cached %v, on disk %v", key, ok, err == nil)
+		}
+		if ok {
+			cached = append(cached, key)
+		}
+	}
+
+	return cached
+}
+
+func TestCache(t *testing.T) {
+	c, err := NewCache(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("missing entry found")
+	}
+
+	putEntry(t, c, "a", 10)
+	path, ok := c.Get("a")
+	if !ok || path != c.Path("a") {
+		t.Errorf("got %q %v, want %q", path, ok, c.Path("a"))
+	}
+
+	// replaced entry counts once
+	putEntry(t, c, "a", 20)
+	if got, want := c.Stats(), (CacheStats{Entries: 1, Bytes: 20, Hits: 1, Misses: 1}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if err := c.Remove("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(c.Path("a")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("removed entry kept on disk: %v", err)
+	}
+	if got := c.Stats(); got.Entries != 0 || got.Bytes != 0 {
+		t.Errorf("got %+v, want empty cache", got)
+	}
+
+	for _, key := range []string{"", ".tmp", "../a", `a\b`, "a/b"} {
+		if _, err := c.Put(key, t.TempDir()); !errors.Is(err, ErrInvalidCacheKey) {
+			t.Errorf("Put(%q) = %v, want ErrInvalidCacheKey", key, err)
+		}
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  cacheOption
+	}{
+		{"entries", WithCacheMaxEntries(2)},
+		{"bytes", WithCacheMaxBytes(25)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCache(filepath.Join(t.TempDir(), "cache"), tt.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			putEntry(t, c, "a", 10)
+			putEntry(t, c, "b", 10)
+			c.Get("a") // b is the least recently used now
+			putEntry(t, c, "c", 10)
+
+			if got := cachedKeys(t, c, "a", "b", "c"); len(got) != 2 || got[0] != "a" || got[1] != "c" {
+				t.Errorf("got %q, want a and c", got)
+			}
+			if got := c.Stats(); got.Evictions != 1 || got.EvictedBytes != 10 {
+				t.Errorf("got %+v, want b evicted", got)
+			}
+		})
+	}
+}
+
+func TestCacheEvictionKeepsNewest(t *testing.T) {
+	c, err := NewCache(filepath.Join(t.TempDir(), "cache"), WithCacheMaxBytes(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putEntry(t, c, "a", 10)
+	putEntry(t, c, "b", 10)
+
+	if got := cachedKeys(t, c, "a", "b"); len(got) != 1 || got[0] != "b" {
+		t.Errorf("got %q, want b over the limit alone", got)
+	}
+}
+
+func TestCacheReopen(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "cache")
+
+	c, err := NewCache(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	putEntry(t, c, "a", 10)
+	putEntry(t, c, "b", 10)
+	putEntry(t, c, "c", 10)
+
+	// usage survives as the modification time: a is the most recently used
+	now := time.Now()
+	for i, key := range []string{"b", "c", "a"} {
+		used := now.Add(time.Duration(i-3) * time.Minute)
+		if err := os.Chtimes(c.Path(key), used, used); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// workspaces of interrupted conversions are not entries
+	if err := os.Mkdir(filepath.Join(root, ".tmp-1"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = NewCache(root, WithCacheMaxEntries(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := cachedKeys(t, c, "a", "b", "c"); len(got) != 2 || got[0] != "a" || got[1] != "c" {
+		t.Errorf("got %q, want a and c", got)
+	}
+	if got := c.Stats(); got.Entries != 2 || got.Bytes != 20 {
+		t.Errorf("got %+v, want 2 entries of 20 bytes", got)
+	}
+}
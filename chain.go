@@ -0,0 +1,161 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- fallback chain
+// ----------------------------------------------------------------------------
+
+// ErrEmptyOutput is returned when conversion succeeded, but produced no pages.
+var ErrEmptyOutput = errors.New("pdftohtml: empty output")
+
+// Chain tries runners in order, moving on to the next one when the previous one
+// fails or produces no pages.
+type Chain struct {
+	runners []Runner
+}
+
+// NewChain creates new chain of runners, e.g. Xpdf `pdftohtml` command followed
+// by `TextRunner` as the degraded fallback.
+func NewChain(runners ...Runner) *Chain {
+	return &Chain{runners: runners}
+}
+
+// Run executes the runners in order until one of them succeeds.
+func (c *Chain) Run(ctx context.Context, inpath, outdir string) error {
+	_, err := c.Try(ctx, inpath, outdir)
+
+	return err
+}
+
+// Try executes the runners in order until one of them succeeds, and returns the
+// runner that succeeded. If all of them fail, the errors of all runners are
+// joined.
+func (c *Chain) Try(ctx context.Context, inpath, outdir string) (Runner, error) {
+	// output of failed attempts is removed only if the outdir did not exist
+	_, err := os.Stat(outdir)
+	removable := errors.Is(err, os.ErrNotExist)
+
+	var errs []error
+	for i, runner := range c.runners {
+		if i > 0 && removable {
+			if err := os.RemoveAll(outdir); err != nil {
+				return nil, err
+			}
+		}
+
+		err := runner.Run(ctx, inpath, outdir)
+		if err == nil {
+			err = assertPages(outdir)
+		}
+		if err == nil {
+			return runner, nil
+		}
+		errs = append(errs, fmt.Errorf("runner %d: %w", i, err))
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// assertPages returns `ErrEmptyOutput` if there are no page files in the outdir.
+func assertPages(outdir string) error {
+	names, err := listHTML(outdir)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if pageNumber(name) > 0 {
+			return nil
+		}
+	}
+
+	return ErrEmptyOutput
+}
+
+// ----------------------------------------------------------------------------
+// -- `pdftotext` fallback
+// ----------------------------------------------------------------------------
+
+// TextRunner converts PDF files into plain HTML pages with the text extracted
+// by Xpdf `pdftotext`, without any layout or images. `pdftotext` runs with the
+// default environment of `WithEnv`.
+type TextRunner struct {
+	path string
+}
+
+// NewTextRunner creates new `pdftotext` runner. If the path is empty, the
+// executable is looked up in the PATH.
+func NewTextRunner(path string) (*TextRunner, error) {
+	if path == "" {
+		path = "pdftotext"
+	}
+
+	path, err := exec.LookPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TextRunner{path: path}, nil
+}
+
+// Run extracts the text of the PDF file and writes it as `index.html` and one
+// `page<N>.html` file per page into the outdir.
+func (r *TextRunner) Run(ctx context.Context, inpath, outdir string) error {
+	var stdout bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, r.path, "-layout", "-enc", "UTF-8", "--", inpath, "-")
+	cmd.Env = minimalEnviron(os.TempDir(), inheritedEnv, nil)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(outdir, 0o755); err != nil {
+		return err
+	}
+
+	// pages are separated by form feeds, the last one ends with it
+	pages := strings.Split(strings.TrimSuffix(stdout.String(), "\f"), "\f")
+
+	var index strings.Builder
+	for i, text := range pages {
+		name := fmt.Sprintf("page%d.html", i+1)
+		title := fmt.Sprintf("Page %d", i+1)
+
+		body := "<pre>" + html.EscapeString(text) + "</pre>\n"
+		if err := writeTextPage(filepath.Join(outdir, name), title, body); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&index, "<a href=\"%s\">%s</a><br>\n", name, title)
+	}
+
+	return writeTextPage(filepath.Join(outdir, "index.html"), filepath.Base(inpath), index.String())
+}
+
+// writeTextPage writes minimal HTML document with the body.
+func writeTextPage(path, title, body string) error {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"UTF-8\">\n")
+	b.WriteString("<title>" + html.EscapeString(title) + "</title>\n")
+	b.WriteString("</head>\n<body>\n")
+	b.WriteString(body)
+	b.WriteString("</body>\n</html>\n")
+
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
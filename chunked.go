@@ -0,0 +1,130 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ----------------------------------------------------------------------------
+// -- chunked conversion
+// ----------------------------------------------------------------------------
+
+// ErrNoPagesSelected is returned when none of the selected pages is in the PDF
+// file.
+var ErrNoPagesSelected = errors.New("pdftohtml: no pages selected")
+
+// maxCountedSize is the size of the largest PDF file the package counts pages of
+// itself, holding it in memory. Pages of larger files are counted by `pdfinfo`.
+const maxCountedSize = 64 << 20
+
+// ConvertChunked converts the PDF file like `Run`, running the executable for at
+// most the number of pages at a time. Output of every chunk is merged into the
+// outdir before the next one starts, so memory of the executable and the
+// temporary disk space are bounded by the chunk, e.g. for scans of thousands of
+// pages.
+//
+// Pages keep their numbers, and the index lists all of them. Post-processing
+// stages run once all chunks are merged. Selections of pages beyond the last one
+// fail with `ErrNoPagesSelected`.
+func (c *Command) ConvertChunked(ctx context.Context, inpath, outdir string, pagesPerChunk int) error {
+	if pagesPerChunk < 1 {
+		return fmt.Errorf("pdftohtml: invalid number of pages per chunk %d", pagesPerChunk)
+	}
+
+	chunked := *c
+	chunked.chunk = pagesPerChunk
+
+	return chunked.Run(ctx, inpath, outdir)
+}
+
+// runRanges splits the pages to convert into the ranges converted by single runs
+// of the executable: chunks of at most the chunk size, of pages with the same
+// zoom fitting the target width.
+func (c *Command) runRanges(ctx context.Context, opts OptionSet, inpath string) ([]pageRange, error) {
+	var zooms []float64
+	var count uint64
+	var err error
+	if c.width > 0 {
+		zooms, err = c.pageZooms(ctx, opts, inpath)
+		count = uint64(len(zooms))
+	} else {
+		count, err = c.pageCount(ctx, opts, inpath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := c.pages
+	if len(ranges) == 0 {
+		ranges = pageSelection{{from: max(opts.PageFrom, 1), to: opts.PageTo}}
+	}
+
+	var runs []pageRange
+	for _, r := range ranges {
+		last := r.to
+		if last == 0 || last > count {
+			last = count
+		}
+
+		size := last - r.from + 1
+		if c.chunk > 0 {
+			size = uint64(c.chunk)
+		}
+
+		for from := r.from; from <= last; from += size {
+			chunk := pageRange{from: from, to: min(from+size-1, last)}
+			if zooms == nil {
+				runs = append(runs, chunk)
+				continue
+			}
+
+			// pages of other sizes need other zoom, so another run
+			run := pageRange{from: chunk.from, to: chunk.from, zoom: zooms[chunk.from-1]}
+			for n := chunk.from + 1; n <= chunk.to; n++ {
+				if zooms[n-1] != run.zoom {
+					runs = append(runs, run)
+					run = pageRange{from: n, zoom: zooms[n-1]}
+				}
+				run.to = n
+			}
+			runs = append(runs, run)
+		}
+	}
+
+	// the document is not converted as a whole in place of the selection
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("%w: the PDF file has %d pages", ErrNoPagesSelected, count)
+	}
+
+	return runs, nil
+}
+
+// pageCount returns the number of pages of the PDF file, read by the package
+// itself, or by `pdfinfo` for large files and the files it does not read.
+func (c *Command) pageCount(ctx context.Context, opts OptionSet, inpath string) (uint64, error) {
+	stat, err := os.Stat(inpath)
+	if err != nil {
+		return 0, err
+	}
+
+	if stat.Size() <= maxCountedSize {
+		if r, err := openPDFFile(inpath); err == nil {
+			if pages := r.pages(); len(pages) > 0 {
+				return uint64(len(pages)), nil
+			}
+		}
+	}
+
+	// passwords that opened the file are in the options of the run
+	info := *c
+	info.opts = opts
+
+	i, err := info.Info(ctx, inpath)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(i.Pages), nil
+}
@@ -0,0 +1,45 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestRunRanges(t *testing.T) {
+	inpath := filepath.Join(t.TempDir(), "in.pdf")
+	if err := os.WriteFile(inpath, threePages(1, "", "", ""), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		pages pageSelection
+		opts  OptionSet
+		want  []pageRange
+		err   error
+	}{
+		{name: "all", want: []pageRange{{from: 1, to: 2}, {from: 3, to: 3}}},
+		{name: "selection", pages: pageSelection{{from: 2, to: 0}}, want: []pageRange{{from: 2, to: 3}}},
+		{name: "range", opts: OptionSet{PageFrom: 3}, want: []pageRange{{from: 3, to: 3}}},
+		{name: "beyond last page", pages: pageSelection{{from: 5, to: 6}, {from: 9, to: 0}}, err: ErrNoPagesSelected},
+		{name: "range beyond last page", opts: OptionSet{PageFrom: 4, PageTo: 8}, err: ErrNoPagesSelected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Command{chunk: 2, pages: tt.pages}
+
+			got, err := c.runRanges(context.Background(), tt.opts, inpath)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
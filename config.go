@@ -0,0 +1,472 @@
+package pdftohtml
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- config
+// ----------------------------------------------------------------------------
+
+// Executors available in the config.
+const (
+	ExecutorPdftohtml = "pdftohtml" // Xpdf `pdftohtml` command
+	ExecutorPdftotext = "pdftotext" // plain HTML pages from Xpdf `pdftotext`
+	ExecutorFallback  = "fallback"  // `pdftohtml`, falling back to `pdftotext`
+)
+
+// Post-processing stages available in the config.
+const (
+	StageLigatures   = "ligatures"
+	StageDirection   = "direction"
+	StageFrontMatter = "front-matter"
+	StageNormalize   = "normalize"
+)
+
+// Config holds conversion settings in a form that can be loaded from a file,
+// so conversions can be tuned without recompiling.
+//
+// Passwords are read from config files, but never written to JSON, see
+// `OptionSet`.
+type Config struct {
+	Executor string `json:"executor,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Version  string `json:"version-constraint,omitempty"`
+	Preset   Preset `json:"preset,omitempty"`
+
+	OptionSet
+
+	Stages        []string `json:"stages,omitempty"`
+	SocialPreview string   `json:"social-preview,omitempty"`
+	Sitemap       string   `json:"sitemap,omitempty"`
+
+	TempDir   string `json:"temp-dir,omitempty"`
+	DiskLimit int64  `json:"disk-limit,omitempty"`
+}
+
+// configKey describes a single setting of the config.
+type configKey struct {
+	name  string
+	usage string
+	field func(*Config) any
+}
+
+var configKeys = []configKey{
+	{"executor", "executor: pdftohtml, pdftotext or fallback", func(c *Config) any { return &c.Executor }},
+	{"path", "location of the executable", func(c *Config) any { return &c.Path }},
+	{"version-constraint", "version constraint of the registered executable, e.g. >=4.04", func(c *Config) any { return &c.Version }},
+	{"preset", "preset: fast, high-fidelity, archival or text-only", func(c *Config) any { return &c.Preset }},
+
+	{"xpdfrc", "Xpdf config file used in place of ~/.xpdfrc", func(c *Config) any { return &c.ConfigFile }},
+	{"overwrite", "overwrite the existing output directory", func(c *Config) any { return &c.Overwrite }},
+	{"first-page", "first page to convert", func(c *Config) any { return &c.PageFrom }},
+	{"last-page", "last page to convert", func(c *Config) any { return &c.PageTo }},
+	{"zoom", "initial zoom level", func(c *Config) any { return &c.Zoom }},
+	{"resolution", "resolution, in DPI, of background images", func(c *Config) any { return &c.Resolution }},
+	{"vstretch", "vertical stretch factor", func(c *Config) any { return &c.VerticalStretch }},
+	{"embed-background", "embed background images into HTML files", func(c *Config) any { return &c.EmbedBackground }},
+	{"no-fonts", "disable extraction of embedded fonts", func(c *Config) any { return &c.NoFonts }},
+	{"embed-fonts", "embed extracted fonts into HTML files", func(c *Config) any { return &c.EmbedFonts }},
+	{"skip-invisible", "don't draw invisible text", func(c *Config) any { return &c.NoInvisibleText }},
+	{"all-invisible", "treat all text as invisible", func(c *Config) any { return &c.AllInvisibleText }},
+	{"form-fields", "convert AcroForm fields to HTML input elements", func(c *Config) any { return &c.FormFields }},
+	{"meta", "include PDF document metadata as meta elements", func(c *Config) any { return &c.MetaTags }},
+	{"table", "use table mode for text extraction", func(c *Config) any { return &c.Table }},
+	{"owner-password", "owner password for the PDF file", func(c *Config) any { return &c.OwnerPassword }},
+	{"user-password", "user password for the PDF file", func(c *Config) any { return &c.UserPassword }},
+
+	{"stages", "post-processing stages: ligatures, direction, front-matter, normalize", func(c *Config) any { return &c.Stages }},
+	{"social-preview", "base URL for social preview meta tags", func(c *Config) any { return &c.SocialPreview }},
+	{"sitemap", "base URL for sitemap.xml", func(c *Config) any { return &c.Sitemap }},
+
+	{"temp-dir", "parent directory for pipeline workspaces", func(c *Config) any { return &c.TempDir }},
+	{"disk-limit", "disk limit, in bytes, of a single pipeline workspace", func(c *Config) any { return &c.DiskLimit }},
+}
+
+// set parses the value of the named setting.
+func (c *Config) set(name string, value []string) error {
+	for _, key := range configKeys {
+		if key.name != name {
+			continue
+		}
+
+		if list, ok := key.field(c).(*[]string); ok {
+			*list = value
+			return nil
+		}
+
+		if len(value) != 1 {
+			return fmt.Errorf("pdftohtml: config %q expects single value", name)
+		}
+
+		return setConfigValue(key.field(c), value[0])
+	}
+
+	return fmt.Errorf("pdftohtml: unknown config %q", name)
+}
+
+// setConfigValue parses the raw value into the field.
+func setConfigValue(field any, raw string) error {
+	var err error
+
+	switch f := field.(type) {
+	case *string:
+		*f = raw
+	case *Preset:
+		*f = Preset(raw)
+	case *bool:
+		*f, err = strconv.ParseBool(raw)
+	case *uint64:
+		*f, err = strconv.ParseUint(raw, 10, 64)
+	case *int64:
+		*f, err = strconv.ParseInt(raw, 10, 64)
+	case *float64:
+		*f, err = strconv.ParseFloat(raw, 64)
+	}
+
+	return err
+}
+
+// Options returns the command options described by the config.
+func (c *Config) Options() []option {
+	var opts []option
+
+	str := func(v string, fn func(string) option) {
+		if v != "" {
+			opts = append(opts, fn(v))
+		}
+	}
+
+	// executable and preset go first, so other options can override the preset
+	str(c.Path, WithCustomPath)
+	str(c.Version, WithVersionConstraint)
+	if c.Preset != "" {
+		opts = append(opts, WithPreset(c.Preset))
+	}
+
+	opts = append(opts, c.OptionSet.options()...)
+
+	for _, name := range c.Stages {
+		opts = append(opts, withStage(name))
+	}
+	str(c.SocialPreview, WithSocialPreview)
+	if c.Sitemap != "" {
+		opts = append(opts, WithSitemap(c.Sitemap))
+	}
+
+	return opts
+}
+
+// withStage applies the post-processing stage by its config name.
+func withStage(name string) option {
+	switch name {
+	case StageLigatures:
+		return WithLigatureExpansion()
+	case StageDirection:
+		return WithDirectionDetection()
+	case StageFrontMatter:
+		return WithFrontMatter()
+	case StageNormalize:
+		return WithMarkupNormalization()
+	}
+
+	return func(c *Command) {
+		c.errs = append(c.errs, fmt.Errorf("pdftohtml: unknown stage %q", name))
+	}
+}
+
+// Command creates new `pdftohtml` command described by the config.
+func (c *Config) Command() (*Command, error) {
+	return NewCommand(c.Options()...)
+}
+
+// Runner creates new runner using the executor described by the config.
+func (c *Config) Runner() (Runner, error) {
+	switch c.Executor {
+	case "", ExecutorPdftohtml:
+		return c.Command()
+	case ExecutorPdftotext:
+		return NewTextRunner("")
+	case ExecutorFallback:
+		cmd, err := c.Command()
+		if err != nil {
+			return nil, err
+		}
+
+		text, err := NewTextRunner("")
+		if err != nil {
+			return nil, err
+		}
+
+		return NewChain(cmd, text), nil
+	}
+
+	return nil, fmt.Errorf("pdftohtml: unknown executor %q", c.Executor)
+}
+
+// Pipeline creates new pipeline described by the config.
+func (c *Config) Pipeline() (*Pipeline, error) {
+	runner, err := c.Runner()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPipeline(runner, WithPipelineTempDir(c.TempDir), WithPipelineDiskLimit(c.DiskLimit)), nil
+}
+
+// ----------------------------------------------------------------------------
+// -- config files
+// ----------------------------------------------------------------------------
+
+// LoadConfig reads the config from the YAML (.yaml, .yml) or TOML (.toml) file
+// and validates it by creating the runner it describes.
+//
+// Only flat files are supported: one setting per line, with scalar values or
+// lists of strings, e.g.
+//
+//	preset = "fast"
+//	zoom = 1.5
+//	stages = ["ligatures", "direction"]
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string][]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		values, err = parseYAML(data)
+	case ".toml":
+		values, err = parseTOML(data)
+	default:
+		err = fmt.Errorf("pdftohtml: unsupported config format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	for name, value := range values {
+		if err := cfg.set(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := cfg.Runner(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// parseTOML parses flat TOML document with `key = value` lines.
+func parseTOML(data []byte) (map[string][]string, error) {
+	values := make(map[string][]string)
+
+	err := eachLine(data, false, func(n int, line string) error {
+		name, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("pdftohtml: config line %d: expected key = value", n)
+		}
+
+		value, err := parseConfigValue(raw)
+		if err != nil {
+			return fmt.Errorf("pdftohtml: config line %d: %w", n, err)
+		}
+		values[strings.TrimSpace(name)] = value
+
+		return nil
+	})
+
+	return values, err
+}
+
+// parseYAML parses flat YAML document with `key: value` lines; lists can also
+// be given as `- item` lines following the key.
+func parseYAML(data []byte) (map[string][]string, error) {
+	values := make(map[string][]string)
+
+	var list string
+	err := eachLine(data, true, func(n int, line string) error {
+		if item, ok := strings.CutPrefix(line, "- "); ok && list != "" {
+			value, err := parseConfigValue(item)
+			if err != nil {
+				return fmt.Errorf("pdftohtml: config line %d: %w", n, err)
+			}
+			values[list] = append(values[list], value...)
+
+			return nil
+		}
+
+		name, raw, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("pdftohtml: config line %d: expected key: value", n)
+		}
+		name = strings.TrimSpace(name)
+
+		// key without value starts a block list
+		if strings.TrimSpace(raw) == "" {
+			list = name
+			values[name] = []string{}
+			return nil
+		}
+		list = ""
+
+		value, err := parseConfigValue(raw)
+		if err != nil {
+			return fmt.Errorf("pdftohtml: config line %d: %w", n, err)
+		}
+		values[name] = value
+
+		return nil
+	})
+
+	return values, err
+}
+
+// eachLine calls fn for every non-empty line of the data, without comments. In
+// YAML comments start with `#` preceded by white space, e.g. `url: /a#b` has
+// none.
+func eachLine(data []byte, yaml bool, fn func(n int, line string) error) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(stripComment(scanner.Text(), yaml))
+		if line == "" || line == "---" {
+			continue
+		}
+
+		if err := fn(n, line); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// stripComment removes the `#` comment outside of quoted strings, optionally
+// only the one preceded by white space.
+func stripComment(line string, spaced bool) string {
+	end := len(line)
+	eachUnquoted(line, func(i int) bool {
+		if line[i] == '#' && (!spaced || i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			end = i
+			return false
+		}
+		return true
+	})
+
+	return line[:end]
+}
+
+// splitList splits the items of the list at commas outside of quoted strings.
+func splitList(s string) []string {
+	var items []string
+
+	start := 0
+	eachUnquoted(s, func(i int) bool {
+		if s[i] == ',' {
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+		return true
+	})
+
+	return append(items, s[start:])
+}
+
+// eachUnquoted calls fn with the index of every byte of s outside of quoted
+// strings, until fn returns false.
+func eachUnquoted(s string, fn func(i int) bool) {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++ // escaped character, e.g. the quote
+		case quote == '\'' && c == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++ // doubled quote
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && opensString(s[:i]):
+			quote = c
+		default:
+			if !fn(i) {
+				return
+			}
+		}
+	}
+}
+
+// opensString reports whether the quote following the text opens the quoted
+// string, i.e. starts the key, the value or the list item. Quotes inside plain
+// values, e.g. the apostrophe of `it's`, do not.
+func opensString(before string) bool {
+	before = strings.TrimRight(before, " \t")
+
+	return before == "" || strings.IndexByte(":=[,-", before[len(before)-1]) >= 0
+}
+
+// parseConfigValue parses scalar or `[a, b]` list value, unquoting strings.
+func parseConfigValue(raw string) ([]string, error) {
+	raw = strings.TrimSpace(raw)
+
+	inner, ok := strings.CutPrefix(raw, "[")
+	if !ok {
+		value, err := unquote(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{value}, nil
+	}
+
+	inner, ok = strings.CutSuffix(inner, "]")
+	if !ok {
+		return nil, fmt.Errorf("unterminated list %s", raw)
+	}
+
+	values := []string{}
+	for _, item := range splitList(inner) {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+
+		value, err := unquote(item)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// unquote removes double or single quotes around the string. Escapes of double
+// quoted strings are interpreted, and so are doubled quotes of single quoted
+// ones, which YAML uses for the quote itself.
+func unquote(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		value, err := strconv.Unquote(s)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", s)
+		}
+		return value, nil
+	case strings.HasPrefix(s, `'`):
+		if len(s) < 2 || !strings.HasSuffix(s, `'`) {
+			return "", fmt.Errorf("unterminated string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+
+	return s, nil
+}
@@ -0,0 +1,110 @@
+package pdftohtml
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name, in string
+		want     map[string][]string
+	}{
+		{"plain", `zoom = 1.5`, map[string][]string{"zoom": {"1.5"}}},
+		{"comment", "# settings\nzoom = 1.5 # initial", map[string][]string{"zoom": {"1.5"}}},
+		{"comment after string", `preset = "fast" # or "archival"`, map[string][]string{"preset": {"fast"}}},
+		{"hash in string", `sitemap = "https://example.com/#docs"`, map[string][]string{"sitemap": {"https://example.com/#docs"}}},
+		{"escaped quote", `path = "a\"#b" # c`, map[string][]string{"path": {`a"#b`}}},
+		{"literal string", `path = 'C:\bin#1\pdftohtml.exe'`, map[string][]string{"path": {`C:\bin#1\pdftohtml.exe`}}},
+		{"list", `stages = ["ligatures", 'direction'] # two`, map[string][]string{"stages": {"ligatures", "direction"}}},
+		{"comma in list string", `stages = ["a,b", "c"]`, map[string][]string{"stages": {"a,b", "c"}}},
+		{"empty list", `stages = []`, map[string][]string{"stages": {}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTOML([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name, in string
+		want     map[string][]string
+	}{
+		{"plain", "---\nzoom: 1.5", map[string][]string{"zoom": {"1.5"}}},
+		{"comment", "# settings\nzoom: 1.5 # initial", map[string][]string{"zoom": {"1.5"}}},
+		{"comment after string", `preset: "fast" # or "archival"`, map[string][]string{"preset": {"fast"}}},
+		{"hash in plain value", `sitemap: https://example.com/#docs`, map[string][]string{"sitemap": {"https://example.com/#docs"}}},
+		{"hash in string", `sitemap: 'https://example.com/ #docs' # c`, map[string][]string{"sitemap": {"https://example.com/ #docs"}}},
+		{"apostrophe in plain value", `social-preview: it's # c`, map[string][]string{"social-preview": {"it's"}}},
+		{"doubled quote", `social-preview: 'it''s #1' # c`, map[string][]string{"social-preview": {"it's #1"}}},
+		{"escaped quote", `path: "a\"#b" # c`, map[string][]string{"path": {`a"#b`}}},
+		{"flow list", `stages: ["ligatures", 'a, b']`, map[string][]string{"stages": {"ligatures", "a, b"}}},
+		{"block list", "stages:\n  - ligatures # first\n  - 'direction'\nzoom: 2", map[string][]string{"stages": {"ligatures", "direction"}, "zoom": {"2"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConfigMalformed(t *testing.T) {
+	tests := []string{
+		`preset = "fast`,
+		`preset = 'fast`,
+		`preset = "fast" archival`,
+		`stages = ["a", "b"`,
+		`zoom`,
+	}
+
+	for _, in := range tests {
+		t.Run(in, func(t *testing.T) {
+			if _, err := parseTOML([]byte(in)); err == nil {
+				t.Error("parsed malformed config")
+			}
+		})
+	}
+}
+
+func TestLoadConfigUnknownKey(t *testing.T) {
+	tests := []struct {
+		name, in string
+	}{
+		{"config.toml", "zoom = 1.5\nzooom = 2"},
+		{"config.yaml", "zoom: 1.5\nzooom: 2"},
+		{"config.yml", "zooom:\n  - 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+			if err := os.WriteFile(path, []byte(tt.in), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := LoadConfig(path)
+			if err == nil || !strings.Contains(err.Error(), `unknown config "zooom"`) {
+				t.Errorf("got %v, want unknown config error", err)
+			}
+		})
+	}
+}
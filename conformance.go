@@ -0,0 +1,158 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- conformance
+// ----------------------------------------------------------------------------
+
+// Conformance is the conformance to the PDF standards the PDF file claims in its
+// XMP metadata. The claim is not validated.
+type Conformance struct {
+	PDFA    string `json:"pdfa,omitempty"`    // part and level of PDF/A, e.g. "1b", "2u" or "4"
+	PDFARev string `json:"pdfaRev,omitempty"` // year of the PDF/A revision, e.g. "2020"
+	PDFUA   string `json:"pdfua,omitempty"`   // part of PDF/UA, e.g. "1"
+}
+
+// Claimed reports whether the PDF file claims any conformance.
+func (c Conformance) Claimed() bool {
+	return c.PDFA != "" || c.PDFUA != ""
+}
+
+func (c Conformance) String() string {
+	var claims []string
+	if c.PDFA != "" {
+		claims = append(claims, "PDF/A-"+c.PDFA)
+	}
+	if c.PDFUA != "" {
+		claims = append(claims, "PDF/UA-"+c.PDFUA)
+	}
+
+	return strings.Join(claims, ", ")
+}
+
+// Namespaces of the XMP identification schemas.
+const (
+	xmpPDFAID  = "http://www.aiim.org/pdfa/ns/id/"
+	xmpPDFUAID = "http://www.aiim.org/pdfua/ns/id/"
+)
+
+// DetectConformance reads the PDF/A and PDF/UA conformance claimed in the XMP
+// metadata of the PDF file.
+//
+// The file is read by the package itself, which does not read encrypted files,
+// see `ErrUnsupportedPDF`.
+func DetectConformance(inpath string) (Conformance, error) {
+	r, err := openPDFFile(inpath)
+	if err != nil {
+		return Conformance{}, err
+	}
+
+	stream, ok := r.resolve(r.catalog()["Metadata"]).(*pdfStream)
+	if !ok {
+		return Conformance{}, nil
+	}
+
+	data, err := r.decode(stream)
+	if err != nil {
+		return Conformance{}, err
+	}
+
+	return parseConformance(data), nil
+}
+
+// parseConformance reads the identification properties of the XMP packet. The
+// properties are either attributes of the description, or its elements.
+func parseConformance(data []byte) Conformance {
+	var part, level, rev, ua string
+	set := func(name xml.Name, value string) {
+		value = strings.TrimSpace(value)
+		switch {
+		case name.Space == xmpPDFAID && name.Local == "part":
+			part = value
+		case name.Space == xmpPDFAID && name.Local == "conformance":
+			level = strings.ToLower(value)
+		case name.Space == xmpPDFAID && name.Local == "rev":
+			rev = value
+		case name.Space == xmpPDFUAID && name.Local == "part":
+			ua = value
+		}
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+
+	var prop *xml.Name
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			// damaged packets keep the properties read so far
+			break
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			for _, attr := range t.Attr {
+				set(attr.Name, attr.Value)
+			}
+			if t.Name.Space == xmpPDFAID || t.Name.Space == xmpPDFUAID {
+				name := t.Name
+				prop = &name
+				text.Reset()
+			}
+		case xml.CharData:
+			if prop != nil {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if prop != nil && t.Name == *prop {
+				set(*prop, text.String())
+				prop = nil
+			}
+		}
+	}
+
+	var c Conformance
+	if part != "" {
+		c.PDFA, c.PDFARev = part+level, rev
+	}
+	c.PDFUA = ua
+
+	return c
+}
+
+// ----------------------------------------------------------------------------
+// -- conformance options
+// ----------------------------------------------------------------------------
+
+// Detect the PDF/A and PDF/UA conformance claimed by the PDF file before the
+// conversion, and record it in the manifest, see `Conformance`.
+//
+// The metadata is read by the package itself, conformance of encrypted files is
+// not detected.
+func WithConformance() option {
+	return func(c *Command) {
+		c.checks = append(c.checks, func(ctx context.Context, inpath, _ string) error {
+			conformance, err := DetectConformance(inpath)
+			if errors.Is(err, ErrUnsupportedPDF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			if record := recordFrom(ctx); record != nil && conformance.Claimed() {
+				record.conformance = &conformance
+			}
+
+			return nil
+		})
+	}
+}
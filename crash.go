@@ -0,0 +1,107 @@
+package pdftohtml
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- crashes
+// ----------------------------------------------------------------------------
+
+// CrashError is returned when the executable died from a signal caused by the
+// document, e.g. a segmentation fault. Retrying such conversion is pointless.
+type CrashError struct {
+	Signal     os.Signal // signal that killed the executable
+	InputHash  string    // SHA-256 of the input
+	Quarantine string    // path of the preserved input, empty if not preserved
+	Stderr     string    // error output of the executable
+	Err        error
+}
+
+func (e *CrashError) Error() string {
+	return fmt.Sprintf("pdftohtml: crashed with signal %v on input %s", e.Signal, e.InputHash)
+}
+
+func (e *CrashError) Unwrap() error {
+	return e.Err
+}
+
+// crashSignal returns the signal the executable crashed with, if it did.
+func crashSignal(err error) (os.Signal, bool) {
+	sig, ok := exitSignal(err)
+	if !ok {
+		return nil, false
+	}
+
+	for _, crash := range crashSignals {
+		if sig == crash {
+			return sig, true
+		}
+	}
+
+	return nil, false
+}
+
+// crashReport is written next to the quarantined input.
+type crashReport struct {
+	Time   time.Time `json:"time"`
+	Signal string    `json:"signal"`
+	Args   []string  `json:"args"`
+	Stderr string    `json:"stderr,omitempty"`
+}
+
+// crash returns the `CrashError` of the input, preserving the input and the
+// crash report in the quarantine directory if configured.
+func (c *Command) crash(inpath string, sig os.Signal, cause error, stderr []byte) error {
+	hash, err := hashFile(inpath)
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+
+	crash := &CrashError{Signal: sig, InputHash: hash, Stderr: string(stderr), Err: cause}
+	if c.quarantine == "" {
+		return crash
+	}
+
+	if err := os.MkdirAll(c.quarantine, 0o700); err != nil {
+		return errors.Join(crash, err)
+	}
+
+	path := filepath.Join(c.quarantine, hash+".pdf")
+	if err := copyFile(inpath, path); err != nil {
+		return errors.Join(crash, err)
+	}
+
+	report, err := json.MarshalIndent(crashReport{
+		Time:   time.Now().UTC(),
+		Signal: sig.String(),
+		Args:   redactArgs(c.Args()),
+		Stderr: string(stderr),
+	}, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(c.quarantine, hash+".json"), report, 0o600)
+	}
+	if err != nil {
+		return errors.Join(crash, err)
+	}
+	crash.Quarantine = path
+
+	return crash
+}
+
+// ----------------------------------------------------------------------------
+// -- crash options
+// ----------------------------------------------------------------------------
+
+// Preserve inputs crashing the executable in the quarantine directory, named by
+// their SHA-256, together with a JSON report of the crash.
+func WithCrashQuarantine(dir string) option {
+	return func(c *Command) {
+		c.quarantine = dir
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !unix && !windows
+
+package pdftohtml
+
+import "os"
+
+// crashSignals are signals the executable dies from when it crashes, none on
+// the platform without signals.
+var crashSignals []os.Signal
+
+// exitSignal never reports the signal, as the platform has none.
+func exitSignal(err error) (os.Signal, bool) {
+	return nil, false
+}
@@ -0,0 +1,31 @@
+//go:build unix || windows
+
+package pdftohtml
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// crashSignals are signals the executable dies from when it crashes.
+var crashSignals = []os.Signal{syscall.SIGSEGV, syscall.SIGABRT, syscall.SIGBUS, syscall.SIGILL, syscall.SIGFPE}
+
+// exitSignal returns the signal that killed the process, if it was killed.
+func exitSignal(err error) (os.Signal, bool) {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return nil, false
+	}
+
+	status, ok := exitErr.Sys().(interface {
+		Signaled() bool
+		Signal() syscall.Signal
+	})
+	if !ok || !status.Signaled() {
+		return nil, false
+	}
+
+	return status.Signal(), true
+}
@@ -0,0 +1,378 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- page boxes
+// ----------------------------------------------------------------------------
+
+// PageBox is the boundary of the PDF page.
+type PageBox string
+
+const (
+	// PageBoxCrop is the visible region of the page, converted by `pdftohtml`.
+	PageBoxCrop PageBox = "CropBox"
+	// PageBoxBleed is the region the page is clipped to in production, including
+	// the bleed.
+	PageBoxBleed PageBox = "BleedBox"
+	// PageBoxTrim is the intended size of the finished page, without printer's
+	// marks and bleed.
+	PageBoxTrim PageBox = "TrimBox"
+	// PageBoxArt is the meaningful content of the page.
+	PageBoxArt PageBox = "ArtBox"
+)
+
+// cropBox is the region of the page kept, in pixels of the generated HTML from
+// the top-left corner of the page.
+type cropBox struct {
+	X, Y, W, H float64
+}
+
+// pageBoxes returns the regions of the pages given by the page box, relative to
+// the crop box converted by `pdftohtml`. Pages without the box are not cropped.
+func (c *Command) pageBoxes(inpath string, box PageBox) (map[int]cropBox, error) {
+	r, err := openPDFFile(inpath)
+	if err != nil {
+		return nil, err
+	}
+
+	sx, sy := c.pixelScale()
+	boxes := make(map[int]cropBox)
+
+	for i, page := range r.pages() {
+		rect, ok := r.rect(page.dict[pdfName(box)])
+		if !ok {
+			continue
+		}
+
+		// the box is clipped to the crop box
+		crop := page.cropBox
+		x0, y0 := max(rect[0], crop[0]), max(rect[1], crop[1])
+		x1, y1 := min(rect[2], crop[2]), min(rect[3], crop[3])
+		if x1 <= x0 || y1 <= y0 || [4]float64{x0, y0, x1, y1} == crop {
+			continue
+		}
+
+		boxes[i+1] = cropBox{
+			X: (x0 - crop[0]) * sx,
+			Y: (crop[3] - y1) * sy,
+			W: (x1 - x0) * sx,
+			H: (y1 - y0) * sy,
+		}
+	}
+
+	return boxes, nil
+}
+
+// cropImage crops the PNG image to the region of the page, scaled from the page
+// width in pixels of the HTML to the width of the image.
+func cropImage(data []byte, box cropBox, width float64) ([]byte, int, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	})
+	if !ok {
+		return nil, 0, fmt.Errorf("pdftohtml: can't crop %T image", img)
+	}
+
+	bounds := img.Bounds()
+	k := float64(bounds.Dx()) / width
+	rect := image.Rect(
+		int(math.Round(box.X*k)), int(math.Round(box.Y*k)),
+		int(math.Round((box.X+box.W)*k)), int(math.Round((box.Y+box.H)*k)),
+	).Add(bounds.Min).Intersect(bounds)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, sub.SubImage(rect)); err != nil {
+		return nil, 0, err
+	}
+
+	return buf.Bytes(), rect.Dx(), nil
+}
+
+// reStyleOffset matches the left and top properties of the inline style.
+var reStyleOffset = regexp.MustCompile(`(?i)(^|[;\s])(left|top)(\s*:\s*)(-?[0-9.]+)px`)
+
+// cropPage crops the background of the page and its renditions to the region,
+// and moves the positioned elements with it. Elements outside of the region,
+// e.g. printer's marks, are removed.
+func cropPage(outdir string, n int, box cropBox) error {
+	path := filepath.Join(outdir, fmt.Sprintf("page%d.html", n))
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	doc, xhtml := parseDocument(data)
+
+	background := findBackground(doc)
+	if background != nil {
+		attr, _ := background.attr("width")
+		width, err := strconv.ParseFloat(attr, 64)
+		if err != nil || width <= 0 {
+			return fmt.Errorf("pdftohtml: page %d: background without width", n)
+		}
+		attr, _ = background.attr("height")
+		height, _ := strconv.ParseFloat(attr, 64)
+
+		// the region can't exceed the page
+		box.W = min(box.W, width-box.X)
+		if height > 0 {
+			box.H = min(box.H, height-box.Y)
+		}
+
+		err = transformBackground(outdir, n, background, width, box.W, box.H, func(data []byte) ([]byte, int, error) {
+			return cropImage(data, box, width)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var outside []*htmlNode
+	doc.walk(func(node *htmlNode) bool {
+		style, ok := node.attr("style")
+		if node.kind != elementNode || node == background || !ok {
+			return true
+		}
+
+		in := true
+		style = reStyleOffset.ReplaceAllStringFunc(style, func(prop string) string {
+			match := reStyleOffset.FindStringSubmatch(prop)
+			v, _ := strconv.ParseFloat(match[4], 64)
+
+			if strings.EqualFold(match[2], "left") {
+				v -= box.X
+				in = in && v >= 0 && v < box.W
+			} else {
+				v -= box.Y
+				in = in && v >= 0 && v < box.H
+			}
+
+			return match[1] + match[2] + match[3] + formatPixels(math.Round(v*100)/100) + "px"
+		})
+		if !reStyleOffset.MatchString(style) {
+			return true
+		}
+
+		node.setAttr("style", style)
+		if !in {
+			outside = append(outside, node)
+		}
+
+		// descendants are positioned relative to the element
+		return false
+	})
+
+	for _, node := range outside {
+		removeNode(node)
+	}
+
+	return os.WriteFile(path, renderDocument(doc, xhtml), 0o644)
+}
+
+// transformBackground applies fn to the background image of the page, embedded
+// or stored in the outdir with its renditions, and resizes the element from the
+// width to the new width and height. fn returns the new image with its width in
+// pixels, listed in the `srcset` attribute.
+func transformBackground(outdir string, n int, background *htmlNode, width, newWidth, newHeight float64, fn func([]byte) ([]byte, int, error)) error {
+	src, _ := background.attr("src")
+
+	if payload, ok := strings.CutPrefix(src, "data:image/png;base64,"); ok {
+		data, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return err
+		}
+		if data, _, err = fn(data); err != nil {
+			return err
+		}
+		background.setAttr("src", "data:image/png;base64,"+base64.StdEncoding.EncodeToString(data))
+	} else {
+		// renditions are transformed the same way, and listed with new widths
+		widths := make(map[string]int)
+
+		entries, err := os.ReadDir(outdir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !reBackgroundFile.MatchString(name) || backgroundPage(name) != n {
+				continue
+			}
+
+			path := filepath.Join(outdir, name)
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			data, w, err := fn(data)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				return err
+			}
+			widths[name] = w
+		}
+
+		if srcset, ok := background.attr("srcset"); ok {
+			candidates := strings.Split(srcset, ",")
+			for i, candidate := range candidates {
+				url, descriptor, _ := strings.Cut(strings.TrimSpace(candidate), " ")
+				if w, ok := widths[url]; ok && strings.HasSuffix(descriptor, "w") {
+					candidates[i] = fmt.Sprintf("%s %dw", url, w)
+				}
+			}
+			background.setAttr("srcset", strings.Join(candidates, ", "))
+		}
+	}
+
+	if sizes, ok := background.attr("sizes"); ok {
+		background.setAttr("sizes", strings.ReplaceAll(sizes, formatPixels(width)+"px", formatPixels(newWidth)+"px"))
+	}
+	background.setAttr("width", formatPixels(newWidth))
+	background.setAttr("height", formatPixels(newHeight))
+
+	return nil
+}
+
+// findBackground returns the background image element of the page, or nil.
+func findBackground(doc *htmlNode) *htmlNode {
+	var background *htmlNode
+	doc.walk(func(node *htmlNode) bool {
+		if id, _ := node.attr("id"); background == nil && node.kind == elementNode && node.tag == "img" && id == "background" {
+			background = node
+		}
+		return background == nil
+	})
+
+	return background
+}
+
+// removeNode removes the node from its parent.
+func removeNode(node *htmlNode) {
+	parent := node.parent
+	for i, child := range parent.children {
+		if child == node {
+			parent.children = append(parent.children[:i], parent.children[i+1:]...)
+			return
+		}
+	}
+}
+
+// reBackgroundPage matches the page number in the name of the background file.
+var reBackgroundPage = regexp.MustCompile(`^page(\d+)`)
+
+// backgroundPage returns the page number of the background image file.
+func backgroundPage(name string) int {
+	match := reBackgroundPage.FindStringSubmatch(name)
+	if match == nil {
+		return 0
+	}
+
+	n, _ := strconv.Atoi(match[1])
+
+	return n
+}
+
+// cropPages creates the stage cropping the pages to the regions given for them.
+func cropPages(boxes func(inpath, outdir string) (map[int]cropBox, error)) stage {
+	return func(_ context.Context, inpath, outdir string) error {
+		regions, err := boxes(inpath, outdir)
+		if errors.Is(err, ErrUnsupportedPDF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for n, box := range regions {
+			if _, err := os.Stat(filepath.Join(outdir, fmt.Sprintf("page%d.html", n))); err != nil {
+				continue
+			}
+
+			if err := cropPage(outdir, n, box); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// ----------------------------------------------------------------------------
+// -- page boxes options
+// ----------------------------------------------------------------------------
+
+// Crop the converted pages to the page box of the PDF file, e.g. `PageBoxTrim` to
+// leave out printer's marks and bleed. `pdftohtml` converts the crop box, so
+// the backgrounds are cropped and the text moved in post-processing. Pages
+// without the box are kept as they are.
+//
+// The boxes are read by the package itself, pages of encrypted files are not
+// cropped.
+func WithPageBox(box PageBox) option {
+	return func(c *Command) {
+		switch box {
+		case PageBoxCrop:
+			return
+		case PageBoxBleed, PageBoxTrim, PageBoxArt:
+		default:
+			c.errs = append(c.errs, fmt.Errorf("pdftohtml: unknown page box %q", box))
+			return
+		}
+
+		c.stages = append(c.stages, cropPages(func(inpath, _ string) (map[int]cropBox, error) {
+			return c.pageBoxes(inpath, box)
+		}))
+	}
+}
+
+// Crop all converted pages to the rectangle, in points from the top-left corner
+// of the page, like `WithPageBox` does with the boxes of the PDF file.
+func WithCropRect(x, y, w, h float64) option {
+	return func(c *Command) {
+		if w <= 0 || h <= 0 || x < 0 || y < 0 {
+			c.errs = append(c.errs, fmt.Errorf("pdftohtml: invalid crop rectangle %g,%g %gx%g", x, y, w, h))
+			return
+		}
+
+		c.stages = append(c.stages, cropPages(func(_, outdir string) (map[int]cropBox, error) {
+			names, err := listHTML(outdir)
+			if err != nil {
+				return nil, err
+			}
+
+			sx, sy := c.pixelScale()
+			boxes := make(map[int]cropBox)
+			for _, name := range names {
+				if n := pageNumber(name); n > 0 {
+					boxes[n] = cropBox{X: x * sx, Y: y * sy, W: w * sx, H: h * sy}
+				}
+			}
+
+			return boxes, nil
+		}))
+	}
+}
@@ -0,0 +1,150 @@
+package pdftohtml
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- asset deduplication
+// ----------------------------------------------------------------------------
+
+// assetExts are extensions of the images and fonts deduplicated in the output.
+var assetExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true,
+	".ttf": true, ".otf": true, ".woff": true, ".woff2": true,
+}
+
+// DedupeAssets collapses images and fonts with identical content in the outdir
+// into a single file, and rewrites references to the removed copies in the HTML
+// and CSS files. It returns the number of bytes saved.
+//
+// Of the identical files, the one with the shortest name, or the first one in
+// lexical order, is kept.
+func DedupeAssets(outdir string) (int64, error) {
+	entries, err := os.ReadDir(outdir)
+	if err != nil {
+		return 0, err
+	}
+
+	groups := make(map[string][]string) // hash -> names
+	sizes := make(map[string]int64)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !assetExts[strings.ToLower(filepath.Ext(name))] {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return 0, err
+		}
+		if info.Size() == 0 {
+			continue
+		}
+
+		hash, err := hashFile(filepath.Join(outdir, name))
+		if err != nil {
+			return 0, err
+		}
+		groups[hash] = append(groups[hash], name)
+		sizes[name] = info.Size()
+	}
+
+	replace := make(map[string]string) // duplicate -> kept name
+	for _, names := range groups {
+		if len(names) < 2 {
+			continue
+		}
+
+		sort.Slice(names, func(i, j int) bool {
+			if len(names[i]) != len(names[j]) {
+				return len(names[i]) < len(names[j])
+			}
+			return names[i] < names[j]
+		})
+		for _, name := range names[1:] {
+			replace[name] = names[0]
+		}
+	}
+	if len(replace) == 0 {
+		return 0, nil
+	}
+
+	// references are rewritten first, so the output is never left with links
+	// to removed files
+	if err := rewriteReferences(outdir, replace); err != nil {
+		return 0, err
+	}
+
+	var saved int64
+	for name := range replace {
+		if err := os.Remove(filepath.Join(outdir, name)); err != nil {
+			return saved, err
+		}
+		saved += sizes[name]
+	}
+
+	return saved, nil
+}
+
+// rewriteReferences replaces references to the files in the HTML and CSS files
+// of the outdir.
+func rewriteReferences(outdir string, replace map[string]string) error {
+	names := make([]string, 0, len(replace))
+	for name := range replace {
+		names = append(names, regexp.QuoteMeta(name))
+	}
+
+	// names are bounded the way they appear in attributes, srcset and url()
+	re := regexp.MustCompile(`(^|["'(\s,])(` + strings.Join(names, "|") + `)(["')\s,]|$)`)
+
+	rewrite := func(_ string, data []byte) ([]byte, error) {
+		return re.ReplaceAllFunc(data, func(match []byte) []byte {
+			sub := re.FindSubmatch(match)
+
+			return append(append(sub[1], replace[string(sub[2])]...), sub[3]...)
+		}), nil
+	}
+
+	if err := rewriteHTML(outdir, rewrite); err != nil {
+		return err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(outdir, "*.css"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		data, _ = rewrite(path, data)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+// -- asset deduplication options
+// ----------------------------------------------------------------------------
+
+// Collapse identical images and fonts in the output into a single file, e.g. the
+// same logo or font subset repeated on every page. See `DedupeAssets`.
+func WithAssetDeduplication() option {
+	return func(c *Command) {
+		c.stages = append(c.stages, func(_ context.Context, _, outdir string) error {
+			_, err := DedupeAssets(outdir)
+			return err
+		})
+	}
+}
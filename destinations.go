@@ -0,0 +1,216 @@
+package pdftohtml
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- named destinations
+// ----------------------------------------------------------------------------
+
+// DestinationsName is the name of the named destinations map in the outdir.
+const DestinationsName = "destinations.json"
+
+// Destination is the target of the named destination in the generated HTML.
+type Destination struct {
+	Page   int     `json:"page"`
+	Top    float64 `json:"top,omitempty"` // in pixels of the generated HTML
+	Anchor string  `json:"anchor"`        // id of the anchor element in the page
+	Href   string  `json:"href"`          // e.g. "page3.html#dest-chapter3"
+}
+
+// Destinations maps names of the named destinations to their targets.
+type Destinations map[string]Destination
+
+// NamedDestinations reads the named destinations of the PDF file, with the
+// targets in the coordinates of the HTML generated by the command. Destinations
+// pointing to missing pages are skipped.
+//
+// Destinations are read by the package itself, which does not read encrypted
+// files, see `ErrUnsupportedPDF`.
+func (c *Command) NamedDestinations(inpath string) (Destinations, error) {
+	r, err := openPDFFile(inpath)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := r.pages()
+	_, sy := c.pixelScale()
+
+	type named struct {
+		name string
+		dest pdfDest
+	}
+	var list []named
+	r.namedDests(func(name string, dest any) {
+		if d, ok := r.destination(dest, pages); ok {
+			list = append(list, named{name, d})
+		}
+	})
+
+	// anchors are derived in order, so the same file gets the same anchors
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].name < list[j].name
+	})
+
+	dests := make(Destinations, len(list))
+	used := make(map[string]bool)
+	for _, n := range list {
+		if _, ok := dests[n.name]; ok {
+			continue
+		}
+
+		dest := Destination{Page: n.dest.page + 1, Anchor: destinationAnchor(n.name, used)}
+		if n.dest.hasTop {
+			dest.Top = max(0, (pages[n.dest.page].cropBox[3]-n.dest.top)*sy)
+		}
+		dest.Href = fmt.Sprintf("page%d.html#%s", dest.Page, dest.Anchor)
+
+		dests[n.name] = dest
+	}
+
+	return dests, nil
+}
+
+// destinationAnchor returns the unique id of the anchor of the named destination,
+// with characters other than letters, digits, '-', '_' and '.' replaced.
+func destinationAnchor(name string, used map[string]bool) string {
+	id := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	id = "dest-" + id
+
+	anchor := id
+	for i := 2; used[anchor]; i++ {
+		anchor = fmt.Sprintf("%s-%d", id, i)
+	}
+	used[anchor] = true
+
+	return anchor
+}
+
+// Resolve translates the fragment of the link to the PDF file, without the '#',
+// to the location in the generated HTML. The open parameters "nameddest=name"
+// and "page=n" are supported, as well as the bare name of the destination.
+func (d Destinations) Resolve(fragment string) (string, bool) {
+	for _, param := range strings.Split(fragment, "&") {
+		key, val, ok := strings.Cut(param, "=")
+		if !ok {
+			key, val = "nameddest", param
+		}
+		if v, err := url.QueryUnescape(val); err == nil {
+			val = v
+		}
+
+		switch key {
+		case "nameddest":
+			if dest, ok := d[val]; ok {
+				return dest.Href, true
+			}
+		case "page":
+			if n, err := strconv.Atoi(val); err == nil && n > 0 {
+				return fmt.Sprintf("page%d.html", n), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// ReadDestinations reads the named destinations map written to the outdir.
+func ReadDestinations(outdir string) (Destinations, error) {
+	data, err := os.ReadFile(filepath.Join(outdir, DestinationsName))
+	if err != nil {
+		return nil, err
+	}
+
+	var dests Destinations
+	if err := json.Unmarshal(data, &dests); err != nil {
+		return nil, err
+	}
+
+	return dests, nil
+}
+
+// insertDestinations adds the anchors of the named destinations to the pages in
+// the outdir, and writes the map of the destinations found in them.
+func insertDestinations(outdir string, dests Destinations) error {
+	byPage := make(map[int][]Destination)
+	for name, dest := range dests {
+		if _, err := os.Stat(filepath.Join(outdir, fmt.Sprintf("page%d.html", dest.Page))); err != nil {
+			delete(dests, name)
+			continue
+		}
+		byPage[dest.Page] = append(byPage[dest.Page], dest)
+	}
+
+	for n, list := range byPage {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Anchor < list[j].Anchor
+		})
+
+		var b strings.Builder
+		for _, dest := range list {
+			fmt.Fprintf(&b, "<a id=\"%s\" style=\"position:absolute; left:0px; top:%spx;\"></a>\n",
+				dest.Anchor, formatPixels(dest.Top))
+		}
+
+		path := filepath.Join(outdir, fmt.Sprintf("page%d.html", n))
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(path, insertBodyEnd(data, []byte(b.String())), 0o644); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(dests, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(outdir, DestinationsName), data, 0o644)
+}
+
+// ----------------------------------------------------------------------------
+// -- named destinations options
+// ----------------------------------------------------------------------------
+
+// Add anchors of the named destinations of the PDF file to the pages, and write
+// `destinations.json` mapping the names to the pages and anchors, so deep links
+// like `doc.pdf#nameddest=chapter3` can be translated with `Destinations.Resolve`.
+//
+// Destinations are read by the package itself, destinations of encrypted files
+// are not mapped.
+func WithNamedDestinations() option {
+	return func(c *Command) {
+		c.stages = append(c.stages, func(_ context.Context, inpath, outdir string) error {
+			dests, err := c.NamedDestinations(inpath)
+			if errors.Is(err, ErrUnsupportedPDF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			return insertDestinations(outdir, dests)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package pdftohtml
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- diagnostics
+// ----------------------------------------------------------------------------
+
+//go:embed example/example.pdf
+var samplePDF []byte
+
+// DiagnosticReport describes the environment the command runs in and the result
+// of converting the embedded sample PDF.
+type DiagnosticReport struct {
+	Path     string        // absolute path of the executable
+	Version  string        // version reported by the executable
+	Args     []string      // arguments of the command
+	OS       string        // operating system and architecture
+	TempDir  string        // directory for temporary files
+	Writable bool          // whether the temporary directory is writable
+	Duration time.Duration // duration of the sample conversion
+	Files    []string      // files generated by the sample conversion
+}
+
+// Diagnose converts the embedded sample PDF and verifies that the expected files
+// appear in the output. The report is filled as far as diagnostics got, also
+// when the returned error is not nil.
+func (c *Command) Diagnose(ctx context.Context) (*DiagnosticReport, error) {
+	report := &DiagnosticReport{
+		Path:    c.path,
+		Args:    c.Args(),
+		OS:      runtime.GOOS + "/" + runtime.GOARCH,
+		TempDir: os.TempDir(),
+	}
+
+	version, err := DetectVersion(ctx, c.path)
+	if err != nil {
+		return report, err
+	}
+	report.Version = version.String()
+
+	workdir, err := os.MkdirTemp("", "pdftohtml-diagnose-*")
+	if err != nil {
+		return report, err
+	}
+	defer os.RemoveAll(workdir)
+	report.Writable = true
+
+	inpath := filepath.Join(workdir, "sample.pdf")
+	outdir := filepath.Join(workdir, "output")
+
+	if err := os.WriteFile(inpath, samplePDF, 0o644); err != nil {
+		return report, err
+	}
+
+	start := time.Now()
+	err = c.Run(ctx, inpath, outdir)
+	report.Duration = time.Since(start)
+	if err != nil {
+		return report, err
+	}
+
+	entries, err := os.ReadDir(outdir)
+	if err != nil {
+		return report, err
+	}
+	for _, entry := range entries {
+		report.Files = append(report.Files, entry.Name())
+	}
+
+	var errs []error
+	for _, name := range []string{"index.html", "page1.html"} {
+		if _, err := os.Stat(filepath.Join(outdir, name)); err != nil {
+			errs = append(errs, fmt.Errorf("pdftohtml: expected output file %q: %w", name, err))
+		}
+	}
+
+	return report, errors.Join(errs...)
+}
@@ -0,0 +1,362 @@
+package pdftohtml
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"io"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- conversion diff
+// ----------------------------------------------------------------------------
+
+// DiffStatus tells how the page changed between the conversions.
+type DiffStatus string
+
+const (
+	DiffUnchanged DiffStatus = "unchanged"
+	DiffChanged   DiffStatus = "changed"
+	DiffAdded     DiffStatus = "added"
+	DiffRemoved   DiffStatus = "removed"
+)
+
+// DiffOp is the operation of the line in the text diff.
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = " "
+	DiffInsert DiffOp = "+"
+	DiffDelete DiffOp = "-"
+)
+
+// DiffReport describes changes between two conversions, page by page.
+type DiffReport struct {
+	Pages []PageDiff `json:"pages"`
+}
+
+// PageDiff describes changes of a single page.
+type PageDiff struct {
+	Number int        `json:"number"`
+	Status DiffStatus `json:"status"`
+	Text   []DiffLine `json:"text,omitempty"` // empty if the text is the same
+	// Perceptual distance of the backgrounds, from 0 for the same images up to
+	// 64, or -1 if either page has no background.
+	ImageDistance int  `json:"image-distance"`
+	ImageChanged  bool `json:"image-changed"`
+}
+
+// DiffLine is a single line of the text diff.
+type DiffLine struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
+// diffImageThreshold is the perceptual distance of backgrounds considered as
+// changed, smaller differences are rendering noise.
+const diffImageThreshold = 5
+
+// diffMaxCells bounds the size of the table of the line diff.
+const diffMaxCells = 4_000_000
+
+// Diff compares the conversions in the outdirs, e.g. of two versions of the same
+// document, comparing the text of pages in reading order, and their backgrounds
+// perceptually. Pages are matched by their numbers.
+func Diff(oldOutdir, newOutdir string) (*DiffReport, error) {
+	oldPages, err := diffPages(oldOutdir)
+	if err != nil {
+		return nil, err
+	}
+	newPages, err := diffPages(newOutdir)
+	if err != nil {
+		return nil, err
+	}
+
+	last := 0
+	for n := range oldPages {
+		last = max(last, n)
+	}
+	for n := range newPages {
+		last = max(last, n)
+	}
+
+	report := &DiffReport{}
+	for n := 1; n <= last; n++ {
+		oldPage, inOld := oldPages[n]
+		newPage, inNew := newPages[n]
+
+		page := PageDiff{Number: n, ImageDistance: -1}
+		switch {
+		case !inOld && !inNew:
+			continue
+		case !inOld:
+			page.Status = DiffAdded
+			page.Text = diffLines(nil, newPage.lines)
+		case !inNew:
+			page.Status = DiffRemoved
+			page.Text = diffLines(oldPage.lines, nil)
+		default:
+			page.Status = DiffUnchanged
+
+			if !slices.Equal(oldPage.lines, newPage.lines) {
+				page.Status = DiffChanged
+				page.Text = diffLines(oldPage.lines, newPage.lines)
+			}
+
+			if oldPage.hash != nil && newPage.hash != nil {
+				page.ImageDistance = bits.OnesCount64(*oldPage.hash ^ *newPage.hash)
+				if page.ImageChanged = page.ImageDistance > diffImageThreshold; page.ImageChanged {
+					page.Status = DiffChanged
+				}
+			}
+		}
+
+		report.Pages = append(report.Pages, page)
+	}
+
+	return report, nil
+}
+
+// Changed reports whether any page changed.
+func (r *DiffReport) Changed() bool {
+	for _, page := range r.Pages {
+		if page.Status != DiffUnchanged {
+			return true
+		}
+	}
+
+	return false
+}
+
+// diffPage is the content of the page compared by `Diff`.
+type diffPage struct {
+	lines []string
+	hash  *uint64 // difference hash of the background
+}
+
+// diffPages reads the pages of the conversion in the outdir.
+func diffPages(outdir string) (map[int]diffPage, error) {
+	names, err := listHTML(outdir)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make(map[int]diffPage)
+	for _, name := range names {
+		n := pageNumber(name)
+		if n == 0 {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(outdir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		page := diffPage{lines: pageLines(data)}
+
+		if tag := reBackground.Find(data); tag != nil {
+			src, _ := attrValue(tag, "src")
+
+			img, err := loadImage(outdir, src)
+			if err != nil && !errors.Is(err, image.ErrFormat) {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			if img != nil {
+				hash := differenceHash(img)
+				page.hash = &hash
+			}
+		}
+
+		pages[n] = page
+	}
+
+	return pages, nil
+}
+
+// pageLines returns the text lines of the page in reading order, one per text
+// block of the generated HTML.
+func pageLines(data []byte) []string {
+	doc, _ := parseDocument(data)
+
+	body := doc.find("body")
+	if body == nil {
+		return nil
+	}
+
+	var lines []string
+	body.walk(func(n *htmlNode) bool {
+		if n.kind != elementNode || n.tag != "div" {
+			return true
+		}
+
+		class, _ := n.attr("class")
+		if !strings.Contains(" "+class+" ", " txt ") {
+			return true
+		}
+
+		if line := strings.Join(strings.Fields(n.textContent()), " "); line != "" {
+			lines = append(lines, line)
+		}
+
+		return false
+	})
+
+	// pages not generated by `pdftohtml` are compared by their text lines
+	if lines == nil {
+		for _, line := range strings.Split(body.textContent(), "\n") {
+			if line = strings.Join(strings.Fields(line), " "); line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	return lines
+}
+
+// diffLines returns the line diff of the longest common subsequence.
+func diffLines(a, b []string) []DiffLine {
+	var diff []DiffLine
+
+	// too large pages are compared as wholly replaced
+	if len(a)*len(b) > diffMaxCells {
+		for _, line := range a {
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: line})
+		}
+		for _, line := range b {
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: line})
+		}
+
+		return diff
+	}
+
+	// lcs[i][j] is the length of the common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			diff = append(diff, DiffLine{Op: DiffEqual, Text: a[i]})
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+
+	return diff
+}
+
+// differenceHash returns the perceptual hash of the image: the sign of the
+// luminance gradient between neighbouring cells of the 9x8 grid.
+func differenceHash(img image.Image) uint64 {
+	bounds := img.Bounds()
+
+	var grid [8][9]float64
+	for y := range 8 {
+		for x := range 9 {
+			// mean luminance of the cell, sampled sparsely
+			x0 := bounds.Min.X + x*bounds.Dx()/9
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/9
+			y0 := bounds.Min.Y + y*bounds.Dy()/8
+			y1 := bounds.Min.Y + (y+1)*bounds.Dy()/8
+			step := max(1, min(x1-x0, y1-y0)/16)
+
+			var sum, count float64
+			for py := y0; py < y1; py += step {
+				for px := x0; px < x1; px += step {
+					sum += float64(color.GrayModel.Convert(img.At(px, py)).(color.Gray).Y)
+					count++
+				}
+			}
+			if count > 0 {
+				grid[y][x] = sum / count
+			}
+		}
+	}
+
+	var hash uint64
+	for y := range 8 {
+		for x := range 8 {
+			hash <<= 1
+			if grid[y][x] < grid[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+
+	return hash
+}
+
+// ----------------------------------------------------------------------------
+// -- conversion diff view
+// ----------------------------------------------------------------------------
+
+// WriteHTML writes the report as the standalone HTML page, with deleted lines
+// struck through and inserted lines highlighted.
+func (r *DiffReport) WriteHTML(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"UTF-8\">\n<title>Diff</title>\n<style>\n")
+	b.WriteString("body{font-family:sans-serif;}pre{white-space:pre-wrap;}")
+	b.WriteString("del{background-color:#fdd;}ins{background-color:#dfd;text-decoration:none;}\n")
+	b.WriteString("</style>\n</head>\n<body>\n")
+
+	for _, page := range r.Pages {
+		if page.Status == DiffUnchanged {
+			continue
+		}
+
+		fmt.Fprintf(&b, "<h2 id=\"page%d\">Page %d: %s</h2>\n", page.Number, page.Number, page.Status)
+		if page.ImageChanged {
+			fmt.Fprintf(&b, "<p>Background changed (distance %d of 64).</p>\n", page.ImageDistance)
+		}
+
+		if len(page.Text) > 0 {
+			b.WriteString("<pre>")
+			for _, line := range page.Text {
+				text := html.EscapeString(line.Text)
+				switch line.Op {
+				case DiffInsert:
+					b.WriteString("<ins>+ " + text + "</ins>\n")
+				case DiffDelete:
+					b.WriteString("<del>- " + text + "</del>\n")
+				default:
+					b.WriteString("  " + text + "\n")
+				}
+			}
+			b.WriteString("</pre>\n")
+		}
+	}
+
+	if !r.Changed() {
+		b.WriteString("<p>No changes.</p>\n")
+	}
+	b.WriteString("</body>\n</html>\n")
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
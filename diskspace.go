@@ -0,0 +1,81 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// ----------------------------------------------------------------------------
+// -- disk space check
+// ----------------------------------------------------------------------------
+
+// ErrInsufficientSpace is returned when the file system of the outdir has not
+// enough free space for the estimated output.
+var ErrInsufficientSpace = errors.New("pdftohtml: insufficient disk space")
+
+// checkDiskSpace asserts that the file system of the outdir has free space for
+// the output estimated by `Plan`, multiplied by the safety factor.
+func (c *Command) checkDiskSpace(ctx context.Context, inpath, outdir string, factor float64) error {
+	// outdir and its parents may be created by the conversion
+	dir := outdir
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+
+	free, err := freeSpace(dir)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	plan, err := c.Plan(ctx, inpath, outdir)
+	if err != nil {
+		return err
+	}
+
+	need := float64(plan.Size()) * factor
+	if need > float64(free) {
+		return fmt.Errorf("%w: %.0f bytes needed, %d bytes free in %q", ErrInsufficientSpace, math.Ceil(need), free, dir)
+	}
+
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+// -- disk space check options
+// ----------------------------------------------------------------------------
+
+// Check the free space of the file system of the outdir before the conversion,
+// and fail with `ErrInsufficientSpace` if it is smaller than the output size
+// estimated by `Plan`, multiplied by the safety factor, instead of running out of
+// space halfway through.
+//
+// The estimate is rough and does not count fonts and files of post-processing
+// stages, so the factor should leave room for them, e.g. 2. The check is skipped
+// on platforms without the free space information.
+//
+// The factor must be at least 1.
+func WithDiskSpaceCheck(factor float64) option {
+	return func(c *Command) {
+		if !c.checkRange(RangeError{Option: "disk space factor", Value: factor, Min: 1, Max: math.Inf(+1)}) {
+			return
+		}
+
+		c.checks = append(c.checks, func(ctx context.Context, inpath, outdir string) error {
+			return c.checkDiskSpace(ctx, inpath, outdir, factor)
+		})
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package pdftohtml
+
+import "errors"
+
+// freeSpace is not supported on the platform.
+func freeSpace(path string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin || freebsd
+
+package pdftohtml
+
+import "syscall"
+
+// freeSpace returns the space, in bytes, available to unprivileged users on the
+// file system of the path.
+func freeSpace(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
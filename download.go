@@ -0,0 +1,237 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- download
+// ----------------------------------------------------------------------------
+
+// ErrDownloadFailed is returned when the PDF file cannot be downloaded from the
+// URL, e.g. because of the status or the content type of the response.
+var ErrDownloadFailed = errors.New("pdftohtml: download failed")
+
+// ErrDownloadTooLarge is returned when the downloaded PDF file exceeds the size
+// limit.
+var ErrDownloadTooLarge = errors.New("pdftohtml: download too large")
+
+// downloadPolicy describes how PDF files are downloaded by `RunURL`.
+type downloadPolicy struct {
+	client    *http.Client
+	limit     int64
+	redirects int // -1 to follow none, as 0 is the default
+	auth      func(*http.Request) error
+}
+
+// pdfContentTypes are the content types the PDF files are served with. Servers
+// often do not know better than the generic binary type.
+var pdfContentTypes = map[string]bool{
+	"application/pdf":          true,
+	"application/x-pdf":        true,
+	"application/octet-stream": true,
+	"binary/octet-stream":      true,
+}
+
+// RunURL downloads the PDF file from the HTTP(S) URL into the temporary
+// directory, and converts it like `Run`. The download is removed once
+// converted.
+//
+// The response must be successful, of the PDF or the generic binary content
+// type, or without one, and within the size limit. Redirects are followed up
+// to the limit, but never from HTTPS to HTTP. The file is named after the last
+// segment of the final URL path, so the manifest tells the source.
+func (c *Command) RunURL(ctx context.Context, rawURL, outdir string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDownloadFailed, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrDownloadFailed, u.Scheme)
+	}
+
+	tmpdir, err := os.MkdirTemp("", "pdftohtml-download-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpdir)
+
+	inpath, err := c.download(ctx, u, tmpdir)
+	if err != nil {
+		return err
+	}
+
+	return c.Run(ctx, inpath, outdir)
+}
+
+// download writes the response of the URL into the file in the directory, and
+// returns its path.
+func (c *Command) download(ctx context.Context, u *url.URL, dir string) (string, error) {
+	policy := c.downloadPolicy()
+
+	client := *policy.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > policy.redirects {
+			return fmt.Errorf("%w: stopped after %d redirects", ErrDownloadFailed, policy.redirects)
+		}
+		if req.URL.Scheme != "https" && via[len(via)-1].URL.Scheme == "https" {
+			return fmt.Errorf("%w: redirect from HTTPS to %q", ErrDownloadFailed, req.URL.Scheme)
+		}
+		if policy.auth != nil {
+			return policy.auth(req)
+		}
+
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDownloadFailed, err)
+	}
+	req.Header.Set("Accept", "application/pdf, application/octet-stream;q=0.9")
+	if policy.auth != nil {
+		if err := policy.auth(req); err != nil {
+			return "", err
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) && errors.Is(urlErr.Err, ErrDownloadFailed) {
+			return "", urlErr.Err
+		}
+
+		return "", fmt.Errorf("%w: %w", ErrDownloadFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("%w: %s", ErrDownloadFailed, resp.Status)
+	}
+	if header := resp.Header.Get("Content-Type"); header != "" {
+		if typ, _, err := mime.ParseMediaType(header); err != nil || !pdfContentTypes[typ] {
+			return "", fmt.Errorf("%w: content type %q", ErrDownloadFailed, header)
+		}
+	}
+	if resp.ContentLength > policy.limit {
+		return "", fmt.Errorf("%w: %d bytes", ErrDownloadTooLarge, resp.ContentLength)
+	}
+
+	inpath := filepath.Join(dir, inputName(resp.Request.URL.Path))
+	f, err := os.Create(inpath)
+	if err != nil {
+		return "", err
+	}
+
+	n, err := io.Copy(f, io.LimitReader(resp.Body, policy.limit+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDownloadFailed, err)
+	}
+	if n > policy.limit {
+		return "", fmt.Errorf("%w: over %d bytes", ErrDownloadTooLarge, policy.limit)
+	}
+
+	return inpath, nil
+}
+
+// downloadPolicy returns the download policy with the defaults.
+func (c *Command) downloadPolicy() downloadPolicy {
+	policy := c.downloads
+	if policy.client == nil {
+		policy.client = http.DefaultClient
+	}
+	if policy.limit == 0 {
+		policy.limit = 256 << 20
+	}
+	if policy.redirects < 0 {
+		policy.redirects = 0
+	} else if policy.redirects == 0 {
+		policy.redirects = 10
+	}
+
+	return policy
+}
+
+// inputName returns the name of the file fetched from the slash-separated path,
+// e.g. of the URL: its last segment, if it names the PDF file.
+func inputName(p string) string {
+	name := path.Base(p)
+	if !strings.EqualFold(path.Ext(name), ".pdf") || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "input.pdf"
+	}
+
+	return name
+}
+
+// ----------------------------------------------------------------------------
+// -- download options
+// ----------------------------------------------------------------------------
+
+// Specifies the HTTP client downloading PDF files in `RunURL`, e.g. with the
+// timeout or the proxy. Its redirect policy is replaced by the one of
+// `WithDownloadRedirects`.
+//
+// The default is `http.DefaultClient`.
+func WithDownloadClient(client *http.Client) option {
+	return func(c *Command) {
+		c.downloads.client = client
+	}
+}
+
+// Specifies the maximum size, in bytes, of PDF files downloaded in `RunURL`,
+// or fetched in `RunObject`.
+// Larger downloads fail with `ErrDownloadTooLarge`.
+//
+// The default is 256 MiB. The size must be greater than 0.
+func WithDownloadMaxSize(bytes int64) option {
+	return func(c *Command) {
+		if !c.checkRange(RangeError{Option: "download max size", Value: float64(bytes), Min: 0, Max: math.Inf(+1), Exclusive: true}) {
+			return
+		}
+
+		c.downloads.limit = bytes
+	}
+}
+
+// Specifies the maximum number of redirects followed in `RunURL`, 0 to follow
+// none.
+//
+// The default is 10.
+func WithDownloadRedirects(n int) option {
+	return func(c *Command) {
+		if !c.checkRange(RangeError{Option: "download redirects", Value: float64(n), Min: 0, Max: math.Inf(+1)}) {
+			return
+		}
+
+		c.downloads.redirects = n
+		if n == 0 {
+			c.downloads.redirects = -1
+		}
+	}
+}
+
+// Call the hook for every request of `RunURL`, including the redirects, e.g. to
+// set the authorization header. The hook decides by the URL of the request,
+// whether redirected hosts get the credentials.
+//
+// Returning an error from the hook fails the download with that error.
+func WithDownloadAuth(fn func(req *http.Request) error) option {
+	return func(c *Command) {
+		c.downloads.auth = fn
+	}
+}
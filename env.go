@@ -0,0 +1,95 @@
+package pdftohtml
+
+import (
+	"maps"
+	"os"
+	"sort"
+)
+
+// ----------------------------------------------------------------------------
+// -- environment
+// ----------------------------------------------------------------------------
+
+// inheritedEnv are variables passed on from the environment of the process, so
+// the executable finds its config, fonts and system libraries. Library paths
+// point executables installed outside of the system directories, e.g. in the
+// `/opt/bin` of an AWS Lambda layer, to their libraries in `/opt/lib`.
+var inheritedEnv = []string{
+	"PATH", "HOME", "USERPROFILE", "SYSTEMROOT", "WINDIR", "TEMP", "TMP",
+	"FONTCONFIG_FILE", "FONTCONFIG_PATH",
+	"LD_LIBRARY_PATH",
+	"DYLD_LIBRARY_PATH", "DYLD_FALLBACK_LIBRARY_PATH", "DYLD_FRAMEWORK_PATH", "DYLD_FALLBACK_FRAMEWORK_PATH",
+}
+
+// environ returns the minimal environment of the executable: UTF-8 locale, the
+// directory for temporary files and the inherited variables, overridden by the
+// configured ones.
+func (c *Command) environ() []string {
+	return minimalEnviron(os.TempDir(), inheritedEnv, c.env)
+}
+
+// minimalEnviron returns the environment with UTF-8 locale, the directory for
+// temporary files and the variables inherited from the process, overridden by
+// the configured ones. Every executable the package runs gets one, so it does
+// not depend on the environment of the process.
+func minimalEnviron(tmpdir string, inherited []string, configured map[string]string) []string {
+	env := map[string]string{
+		"LANG":   "C.UTF-8",
+		"LC_ALL": "C.UTF-8",
+		"TMPDIR": tmpdir,
+	}
+	for _, key := range inherited {
+		if value, ok := os.LookupEnv(key); ok {
+			env[key] = value
+		}
+	}
+	for key, value := range configured {
+		env[key] = value
+	}
+
+	// default LC_ALL would take precedence over the configured LANG
+	if _, ok := configured["LC_ALL"]; !ok && configured["LANG"] != "" {
+		delete(env, "LC_ALL")
+	}
+
+	list := make([]string, 0, len(env))
+	for key, value := range env {
+		list = append(list, key+"="+value)
+	}
+	sort.Strings(list)
+
+	return list
+}
+
+// sandboxEnviron returns the environment of the executable in the sandbox or the
+// container, where nothing is inherited from the host.
+func sandboxEnviron(configured map[string]string) []string {
+	env := map[string]string{"PATH": "/usr/local/bin:/usr/bin:/bin", "HOME": "/tmp"}
+	maps.Copy(env, configured)
+
+	return minimalEnviron("/tmp", nil, env)
+}
+
+// ----------------------------------------------------------------------------
+// -- environment options
+// ----------------------------------------------------------------------------
+
+// Set environment variables of the executable.
+//
+// By default the executable does not inherit the environment of the process. It
+// runs with UTF-8 locale, `TMPDIR` of the process, and `PATH`, `HOME`, font config,
+// library path and system variables passed on. The variables override those defaults.
+//
+// Other Xpdf tools the command runs, e.g. `pdfinfo` or `pdftopng`, get the same
+// environment, and so does the executable run by `WithGVisorCommand` and
+// `WithKubeCommand`, with the sandbox's own `PATH`, `HOME` and `TMPDIR`.
+func WithEnv(env map[string]string) option {
+	return func(c *Command) {
+		if c.env == nil {
+			c.env = make(map[string]string, len(env))
+		}
+		for key, value := range env {
+			c.env[key] = value
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package pdftohtml
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMinimalEnviron(t *testing.T) {
+	t.Setenv("PDFTOHTML_TEST_INHERITED", "yes")
+	t.Setenv("PDFTOHTML_TEST_SECRET", "no")
+
+	tests := []struct {
+		name       string
+		configured map[string]string
+		want       []string
+	}{
+		{
+			name: "defaults",
+			want: []string{"LANG=C.UTF-8", "LC_ALL=C.UTF-8", "PDFTOHTML_TEST_INHERITED=yes", "TMPDIR=/scratch"},
+		},
+		{
+			name:       "configured language",
+			configured: map[string]string{"LANG": "pl_PL.UTF-8", "PDFTOHTML_TEST_INHERITED": "overridden"},
+			want:       []string{"LANG=pl_PL.UTF-8", "PDFTOHTML_TEST_INHERITED=overridden", "TMPDIR=/scratch"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := minimalEnviron("/scratch", []string{"PDFTOHTML_TEST_INHERITED"}, tt.configured)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSandboxEnviron(t *testing.T) {
+	t.Setenv("PATH", "/host/bin")
+
+	got := sandboxEnviron(map[string]string{"FONTCONFIG_PATH": "/fonts"})
+	want := []string{"FONTCONFIG_PATH=/fonts", "HOME=/tmp", "LANG=C.UTF-8", "LC_ALL=C.UTF-8", "PATH=/usr/local/bin:/usr/bin:/bin", "TMPDIR=/tmp"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
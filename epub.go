@@ -0,0 +1,269 @@
+package pdftohtml
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- epub export
+// ----------------------------------------------------------------------------
+
+// epubMediaTypes maps extensions of the output files to their media types.
+var epubMediaTypes = map[string]string{
+	".xhtml": "application/xhtml+xml",
+	".png":   "image/png",
+	".jpg":   "image/jpeg",
+	".css":   "text/css",
+	".ttf":   "font/ttf",
+	".otf":   "font/otf",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+}
+
+// epubItem is a single file of the publication.
+type epubItem struct {
+	id, href, mediaType, properties string
+	number                          int // page number, 0 if not a page
+	data                            []byte
+}
+
+// ExportEPUB writes the EPUB 3 publication with the pages converted in the
+// outdir to w. The spine follows page order, pages are re-serialized as XHTML,
+// and images and fonts are included. The cover is the social preview image, if
+// rendered, or the background of the first page.
+//
+// The navigation follows the outline, e.g. from `Command.Outline`. Without it,
+// it lists the pages, by their labels if `WithPageLabels` wrote them.
+func ExportEPUB(outdir string, w io.Writer, title, language string, outline []OutlineItem) error {
+	names, err := listHTML(outdir)
+	if err != nil {
+		return err
+	}
+
+	var items, pages []epubItem
+	ident := sha256.New()
+
+	for _, name := range names {
+		n := pageNumber(name)
+		if n == 0 {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(outdir, name))
+		if err != nil {
+			return err
+		}
+
+		doc := parseXHTML(data)
+		doc.walk(func(node *htmlNode) bool {
+			// links between pages point to the XHTML files
+			if href, ok := node.attr("href"); ok && pageNumber(strings.SplitN(href, "#", 2)[0]) > 0 {
+				node.setAttr("href", strings.Replace(href, ".html", ".xhtml", 1))
+			}
+
+			return true
+		})
+
+		page := epubItem{
+			id:        fmt.Sprintf("page%d", n),
+			href:      fmt.Sprintf("page%d.xhtml", n),
+			mediaType: epubMediaTypes[".xhtml"],
+			number:    n,
+			data:      renderXHTML(doc),
+		}
+		ident.Write(page.data)
+		pages = append(pages, page)
+	}
+	if len(pages) == 0 {
+		return ErrEmptyOutput
+	}
+
+	entries, err := os.ReadDir(outdir)
+	if err != nil {
+		return err
+	}
+
+	cover := "page1.png"
+	if _, err := os.Stat(filepath.Join(outdir, PreviewImageName)); err == nil {
+		cover = PreviewImageName
+	}
+
+	for i, entry := range entries {
+		mediaType, ok := epubMediaTypes[strings.ToLower(filepath.Ext(entry.Name()))]
+		if !entry.Type().IsRegular() || !ok {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(outdir, entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		item := epubItem{id: fmt.Sprintf("asset%d", i), href: entry.Name(), mediaType: mediaType, data: data}
+		if entry.Name() == cover {
+			item.properties = "cover-image"
+		}
+		items = append(items, item)
+	}
+
+	labels, err := ReadPageLabels(outdir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	id := "urn:sha256:" + hex.EncodeToString(ident.Sum(nil))
+	items = append(items, epubItem{
+		id:         "nav",
+		href:       "nav.xhtml",
+		mediaType:  epubMediaTypes[".xhtml"],
+		properties: "nav",
+		data:       epubNav(title, language, pages, outline, labels),
+	})
+	items = append(items, pages...)
+
+	return writeEPUB(w, epubPackage(id, title, language, items, pages), items)
+}
+
+// epubNav returns the navigation document built from the outline, or listing
+// the pages without it.
+func epubNav(title, language string, pages []epubItem, outline []OutlineItem, labels []string) []byte {
+	available := make(map[int]bool, len(pages))
+	for _, page := range pages {
+		available[page.number] = true
+	}
+
+	if len(outline) == 0 {
+		for _, page := range pages {
+			outline = append(outline, OutlineItem{Title: "Page " + pageLabel(labels, page.number), Page: page.number})
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE html>\n")
+	fmt.Fprintf(&b, "<html xmlns=\"%s\" xmlns:epub=\"http://www.idpf.org/2007/ops\" lang=\"%s\" xml:lang=\"%s\">\n",
+		xhtmlNamespace, xmlEscaper.Replace(language), xmlEscaper.Replace(language))
+	fmt.Fprintf(&b, "<head><meta charset=\"UTF-8\" /><title>%s</title></head>\n<body>\n", xmlEscaper.Replace(title))
+	b.WriteString("<nav epub:type=\"toc\" id=\"toc\">\n")
+
+	list := epubNavItems(outline, available, labels)
+	if list == "" {
+		// the outline points only to pages not converted
+		list = epubNavItems([]OutlineItem{{Title: "Page " + pageLabel(labels, pages[0].number), Page: pages[0].number}}, available, labels)
+	}
+	b.WriteString(list)
+	b.WriteString("</nav>\n</body>\n</html>\n")
+
+	return []byte(b.String())
+}
+
+// epubNavItems returns the nested list of the outline items, or an empty string
+// if none of them is available. Items pointing to missing pages are labels of
+// their children, or left out without them.
+func epubNavItems(items []OutlineItem, available map[int]bool, labels []string) string {
+	var b strings.Builder
+	for _, item := range items {
+		children := epubNavItems(item.Children, available, labels)
+
+		// links of the navigation must not be empty
+		title := item.Title
+		if strings.TrimSpace(title) == "" {
+			title = "Page " + pageLabel(labels, item.Page)
+		}
+
+		label := xmlEscaper.Replace(title)
+		if available[item.Page] {
+			label = fmt.Sprintf("<a href=\"page%d.xhtml\">%s</a>", item.Page, label)
+		} else if children != "" {
+			label = "<span>" + label + "</span>"
+		} else {
+			continue
+		}
+
+		b.WriteString("<li>" + label + children + "</li>\n")
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+
+	return "<ol>\n" + b.String() + "</ol>\n"
+}
+
+// epubPackage returns the package document describing the publication.
+func epubPackage(id, title, language string, items, spine []epubItem) []byte {
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	b.WriteString("<package xmlns=\"http://www.idpf.org/2007/opf\" version=\"3.0\" unique-identifier=\"id\">\n")
+	b.WriteString("<metadata xmlns:dc=\"http://purl.org/dc/elements/1.1/\">\n")
+	fmt.Fprintf(&b, "<dc:identifier id=\"id\">%s</dc:identifier>\n", xmlEscaper.Replace(id))
+	fmt.Fprintf(&b, "<dc:title>%s</dc:title>\n", xmlEscaper.Replace(title))
+	fmt.Fprintf(&b, "<dc:language>%s</dc:language>\n", xmlEscaper.Replace(language))
+	fmt.Fprintf(&b, "<meta property=\"dcterms:modified\">%s</meta>\n", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	b.WriteString("<meta name=\"cover\" content=\"cover\" />\n</metadata>\n<manifest>\n")
+	for _, item := range items {
+		id := item.id
+		if item.properties == "cover-image" {
+			id = "cover"
+		}
+		fmt.Fprintf(&b, "<item id=\"%s\" href=\"%s\" media-type=\"%s\"", id, xmlEscaper.Replace(item.href), item.mediaType)
+		if item.properties != "" {
+			fmt.Fprintf(&b, " properties=\"%s\"", item.properties)
+		}
+		b.WriteString(" />\n")
+	}
+	b.WriteString("</manifest>\n<spine>\n")
+	for _, item := range spine {
+		fmt.Fprintf(&b, "<itemref idref=\"%s\" />\n", item.id)
+	}
+	b.WriteString("</spine>\n</package>\n")
+
+	return []byte(b.String())
+}
+
+// writeEPUB writes the OCF container with the package document and the items.
+func writeEPUB(w io.Writer, opf []byte, items []epubItem) error {
+	zw := zip.NewWriter(w)
+
+	// the mimetype goes first and uncompressed, so it can be sniffed
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, "application/epub+zip"); err != nil {
+		return err
+	}
+
+	container := `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml" /></rootfiles>
+</container>
+`
+	files := []epubItem{
+		{href: "META-INF/container.xml", data: []byte(container)},
+		{href: "OEBPS/content.opf", data: opf},
+	}
+	for _, item := range items {
+		item.href = "OEBPS/" + item.href
+		files = append(files, item)
+	}
+
+	for _, item := range files {
+		f, err := zw.Create(item.href)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(item.data); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
@@ -0,0 +1,56 @@
+package pdftohtml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEPUBNav(t *testing.T) {
+	pages := []epubItem{{number: 1, href: "page1.xhtml"}, {number: 2, href: "page2.xhtml"}}
+
+	tests := []struct {
+		name    string
+		outline []OutlineItem
+		labels  []string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:   "pages",
+			labels: []string{"i"},
+			want:   []string{`<a href="page1.xhtml">Page i</a>`, `<a href="page2.xhtml">Page 2</a>`},
+		},
+		{
+			name: "outline",
+			outline: []OutlineItem{
+				{Title: "Part <1>", Children: []OutlineItem{{Title: "Intro", Page: 1}, {Title: "Missing", Page: 7}}},
+				{Title: "", Page: 2},
+				{Title: "Gone", Page: 9},
+			},
+			want:    []string{"<li><span>Part &lt;1&gt;</span><ol>\n<li><a href=\"page1.xhtml\">Intro</a></li>\n</ol>\n</li>", `<a href="page2.xhtml">Page 2</a>`},
+			notWant: []string{"Missing", "Gone", "<ol>\n</ol>"},
+		},
+		{
+			name:    "outline of missing pages",
+			outline: []OutlineItem{{Title: "Gone", Page: 9}},
+			want:    []string{`<a href="page1.xhtml">Page 1</a>`},
+			notWant: []string{"Gone"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nav := string(epubNav("Title", "en", pages, tt.outline, tt.labels))
+			for _, want := range tt.want {
+				if !strings.Contains(nav, want) {
+					t.Errorf("%s does not contain %s", nav, want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(nav, notWant) {
+					t.Errorf("%s contains %s", nav, notWant)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package pdftohtml
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- conversion estimate
+// ----------------------------------------------------------------------------
+
+// Estimation predicts the duration and the output size of the conversion, e.g.
+// to schedule conversions or show the expected time of completion.
+type Estimation struct {
+	Pages    int           // number of pages to convert
+	Samples  []int         // pages converted in the trial
+	Duration time.Duration // predicted duration of `pdftohtml`, without post-processing stages
+	Size     int64         // predicted size of the output, in bytes
+}
+
+// Parameters of the trial conversion.
+const (
+	estimateSamples = 3   // pages converted in the trial, spread over the document
+	estimateDPI     = 36  // resolution of the trial backgrounds
+	estimateRaster  = 0.5 // assumed share of rendering the backgrounds in the page time
+)
+
+// Estimate predicts the duration and the output size of the conversion of the
+// PDF file with the options. A few pages are converted in the trial at low
+// resolution, and the results are scaled to the resolution of the options and
+// the page count reported by `pdfinfo`, see `Info`.
+//
+// Start of the process is measured with `pdftohtml -v` and counted once. Fonts
+// are counted as extracted from the sampled pages only, so the size of documents
+// with many fonts is underestimated.
+func Estimate(ctx context.Context, inpath string, opts ...option) (*Estimation, error) {
+	c, err := NewCommand(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpdir, err := os.MkdirTemp("", "pdftohtml-estimate-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpdir)
+
+	plan, err := c.Plan(ctx, inpath, tmpdir)
+	if err != nil {
+		return nil, err
+	}
+
+	est := &Estimation{Pages: len(plan.Pages)}
+	if est.Pages == 0 {
+		return est, nil
+	}
+
+	count := min(estimateSamples, est.Pages)
+	for i := range count {
+		est.Samples = append(est.Samples, plan.Pages[i*(est.Pages-1)/max(count-1, 1)])
+	}
+
+	// version is not needed, only the time the process takes to start
+	start := time.Now()
+	_, _ = DetectVersion(ctx, c.path)
+	startup := time.Since(start)
+
+	// backgrounds grow with the square of the resolution
+	dpi := float64(planDefaultDPI)
+	if c.opts.Resolution > 0 {
+		dpi = float64(c.opts.Resolution)
+	}
+	pixels := dpi * dpi / (estimateDPI * estimateDPI)
+
+	trial := *c
+	trial.onPage = nil
+
+	var elapsed time.Duration
+	var pageBytes, imageBytes, fontBytes, otherBytes float64
+	for _, n := range est.Samples {
+		o := c.opts
+		o.PageFrom, o.PageTo = uint64(n), uint64(n)
+		o.Resolution = estimateDPI
+
+		rundir := filepath.Join(tmpdir, strconv.Itoa(n))
+
+		start := time.Now()
+		if err := trial.exec(ctx, o, inpath, rundir); err != nil {
+			return nil, err
+		}
+		elapsed += max(time.Since(start)-startup, 0)
+
+		err := filepath.WalkDir(rundir, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size := float64(info.Size())
+
+			switch name := d.Name(); {
+			case name == "index.html":
+				otherBytes = max(otherBytes, size)
+			case reFontFile.MatchString(name):
+				fontBytes += size
+			case reImageFile.MatchString(name):
+				imageBytes += size * pixels
+			case c.opts.EmbedBackground && pageNumber(name) > 0:
+				// embedded backgrounds are most of the page
+				pageBytes += size * pixels
+			default:
+				pageBytes += size
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	samples := float64(len(est.Samples))
+	perPage := float64(elapsed) / samples * (1 - estimateRaster + estimateRaster*pixels)
+
+	est.Duration = startup + time.Duration(perPage*float64(est.Pages))
+	est.Size = int64((pageBytes+imageBytes)/samples*float64(est.Pages) + fontBytes + otherBytes +
+		planIndexLinkSize*float64(est.Pages))
+
+	return est, nil
+}
@@ -0,0 +1,120 @@
+package pdftohtml
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- lifecycle events
+// ----------------------------------------------------------------------------
+
+// EventType is the step of the lifecycle of the conversion.
+type EventType string
+
+const (
+	// EventQueued is emitted when the conversion waits for the free slot, e.g.
+	// of the `Tenant`.
+	EventQueued EventType = "queued"
+	// EventStarted is emitted when the command starts the conversion.
+	EventStarted EventType = "started"
+	// EventPage is emitted when the page is converted, before post-processing.
+	EventPage EventType = "page"
+	// EventStage is emitted when the post-processing stage is done.
+	EventStage EventType = "stage"
+	// EventUploaded is emitted when the output is stored by `RunObject`, or by
+	// the caller storing it otherwise, see `EmitEvent`.
+	EventUploaded EventType = "uploaded"
+	// EventFinished is emitted when the conversion succeeds.
+	EventFinished EventType = "finished"
+	// EventFailed is emitted when the conversion fails.
+	EventFailed EventType = "failed"
+)
+
+// Event describes the step of the lifecycle of the conversion.
+type Event struct {
+	Type     EventType     `json:"type"`
+	Time     time.Time     `json:"time"`
+	Input    string        `json:"input,omitempty"`    // path of the PDF file, as given to the command
+	Page     int           `json:"page,omitempty"`     // number of the converted page
+	Stage    string        `json:"stage,omitempty"`    // name of the finished stage
+	Location string        `json:"location,omitempty"` // where the output was uploaded
+	Duration time.Duration `json:"duration,omitempty"` // of the stage or the conversion, in nanoseconds in JSON
+	Error    string        `json:"error,omitempty"`    // why the conversion failed
+}
+
+type eventsKey struct{}
+
+// WithEvents returns the context whose conversions report their lifecycle to
+// the callback: commands run with it, also by the `Pipeline`, `Tenant` and other
+// runners, emit events of every conversion.
+//
+// The callback is called synchronously, also from concurrent conversions, so it
+// must be safe for concurrent use and return quickly. Events do not tell the
+// conversions apart, apart from the input, so concurrent conversions should get
+// own contexts with the callbacks adding the job ID, if needed.
+func WithEvents(ctx context.Context, fn func(Event)) context.Context {
+	return context.WithValue(ctx, eventsKey{}, fn)
+}
+
+// eventsFrom returns the event callback of the context, or nil.
+func eventsFrom(ctx context.Context) func(Event) {
+	fn, _ := ctx.Value(eventsKey{}).(func(Event))
+
+	return fn
+}
+
+// EmitEvent reports the event to the callback of the context, if any, e.g. the
+// `EventUploaded` once the caller stores the output. Missing time is set to now.
+func EmitEvent(ctx context.Context, e Event) {
+	events := eventsFrom(ctx)
+	if events == nil {
+		return
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	events(e)
+}
+
+// EventLog returns the callback writing every event as the line of JSON, e.g.
+// for log aggregation, safe for concurrent use. Write errors are ignored.
+func EventLog(w io.Writer) func(Event) {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+
+	return func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		_ = enc.Encode(e)
+	}
+}
+
+// reStageSuffix matches suffixes of names of closures and method values.
+var reStageSuffix = regexp.MustCompile(`(?:\.func\d+|\.gowrap\d+|\.\d+|-fm)+$`)
+
+// stageName returns the name of the stage function, the option adding it for
+// the closures, e.g. `WithSitemap`.
+func stageName(s stage) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(s).Pointer())
+	if fn == nil {
+		return "stage"
+	}
+
+	name := reStageSuffix.ReplaceAllString(fn.Name(), "")
+	name = name[strings.LastIndex(name, "/")+1:] // package path
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[i+1:] // package name
+	}
+
+	return strings.TrimPrefix(name, "(*Command).")
+}
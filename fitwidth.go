@@ -0,0 +1,81 @@
+package pdftohtml
+
+import (
+	"context"
+	"math"
+	"strconv"
+)
+
+// ----------------------------------------------------------------------------
+// -- target width
+// ----------------------------------------------------------------------------
+
+// pageZooms returns the zoom of every page of the PDF file fitting the page to
+// the target width. Pages are read by the package itself, files it does not read
+// get the zoom of the first page reported by `pdfinfo` for all pages.
+func (c *Command) pageZooms(ctx context.Context, opts OptionSet, inpath string) ([]float64, error) {
+	var widths []float64
+	if r, err := openPDFFile(inpath); err == nil {
+		for _, page := range r.pages() {
+			// `pdftohtml` renders the crop box, turned by the rotation
+			width := page.cropBox[2] - page.cropBox[0]
+			if page.rotate%180 != 0 {
+				width = page.cropBox[3] - page.cropBox[1]
+			}
+			widths = append(widths, math.Abs(width))
+		}
+	}
+
+	if len(widths) == 0 {
+		// passwords that opened the file are in the options of the run
+		info := *c
+		info.opts = opts
+
+		i, err := info.Info(ctx, inpath)
+		if err != nil {
+			return nil, err
+		}
+
+		widths = make([]float64, i.Pages)
+		for n := range widths {
+			widths[n] = i.Width
+		}
+	}
+
+	zooms := make([]float64, len(widths))
+	for n, width := range widths {
+		if width <= 0 {
+			width = 612 // letter size is the default of the Xpdf tools
+		}
+
+		// zoom is passed with three significant digits, so pages of nearly the
+		// same width share the run
+		zooms[n], _ = strconv.ParseFloat(strconv.FormatFloat(c.width/width, 'e', 2, 64), 64)
+	}
+
+	return zooms, nil
+}
+
+// ----------------------------------------------------------------------------
+// -- target width options
+// ----------------------------------------------------------------------------
+
+// Specifies the width, in pixels, every page is zoomed to, instead of the single
+// zoom of `WithInitialZoom`, e.g. for documents mixing A4 and A3 pages.
+//
+// The zoom is computed from the crop box of every page, turned by the rotation.
+// `pdftohtml` takes the single zoom, so it is run for every range of pages of the
+// same zoom and the outputs are merged, like with `WithPages`. Stages placing
+// elements by the geometry of the PDF file, e.g. forms or media, assume the
+// zoom of `WithInitialZoom`, so they are not meant to be combined with it.
+//
+// The width must be greater than 0.
+func WithTargetWidth(px float64) option {
+	return func(c *Command) {
+		if !c.checkRange(RangeError{Option: "target width", Value: px, Min: 0, Max: math.Inf(+1), Exclusive: true}) {
+			return
+		}
+
+		c.width = px
+	}
+}
@@ -0,0 +1,50 @@
+package pdftohtml
+
+import (
+	"flag"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- flags
+// ----------------------------------------------------------------------------
+
+// RegisterFlags defines flags for all settings of the config in the flag set,
+// and returns the config populated when the flag set is parsed.
+//
+// Flags are named like config file settings, e.g. `-zoom` or `-embed-fonts`. List
+// settings accept comma separated values and can be repeated.
+func RegisterFlags(fs *flag.FlagSet) *Config {
+	cfg := &Config{}
+
+	for _, key := range configKeys {
+		switch f := key.field(cfg).(type) {
+		case *string:
+			fs.StringVar(f, key.name, "", key.usage)
+		case *Preset:
+			fs.Func(key.name, key.usage, func(s string) error {
+				*f = Preset(s)
+				return nil
+			})
+		case *bool:
+			fs.BoolVar(f, key.name, false, key.usage)
+		case *uint64:
+			fs.Uint64Var(f, key.name, 0, key.usage)
+		case *int64:
+			fs.Int64Var(f, key.name, 0, key.usage)
+		case *float64:
+			fs.Float64Var(f, key.name, 0, key.usage)
+		case *[]string:
+			fs.Func(key.name, key.usage, func(s string) error {
+				for _, item := range strings.Split(s, ",") {
+					if item = strings.TrimSpace(item); item != "" {
+						*f = append(*f, item)
+					}
+				}
+				return nil
+			})
+		}
+	}
+
+	return cfg
+}
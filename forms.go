@@ -0,0 +1,404 @@
+package pdftohtml
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- form fields
+// ----------------------------------------------------------------------------
+
+// FormField is the widget of the AcroForm field, i.e. its appearance on the page.
+// Fields with widgets on multiple pages, and radio buttons, have one per widget.
+type FormField struct {
+	Name     string   // fully qualified name, e.g. "address.city"
+	Type     string   // "text", "checkbox", "radio", "choice", "button" or "signature"
+	Page     int      // 0 if the page of the widget is unknown
+	Value    string   // current value of the field
+	Export   string   // value of the checked state, for checkboxes and radio buttons
+	Options  []string // options of the choice field
+	Required bool
+	ReadOnly bool
+	MaxLen   int
+	// Box of the widget, in pixels of the generated HTML.
+	X, Y, W, H float64
+}
+
+// Flags of the AcroForm fields.
+const (
+	formFlagReadOnly   = 1 << 0
+	formFlagRequired   = 1 << 1
+	formFlagRadio      = 1 << 15
+	formFlagPushbutton = 1 << 16
+)
+
+// FormFields reads the AcroForm fields of the PDF file, with the widgets in the
+// coordinates of the HTML generated by the command.
+//
+// Fields are read by the package itself, which does not read encrypted files,
+// see `ErrUnsupportedPDF`.
+func (c *Command) FormFields(inpath string) ([]FormField, error) {
+	r, err := openPDFFile(inpath)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := r.pages()
+	sx, sy := c.pixelScale()
+
+	// widgets refer to their pages, or are found in the annotations of the pages
+	pageOf := make(map[pdfRef]int)
+	for i, page := range pages {
+		pageOf[page.ref] = i + 1
+	}
+	annotPage := make(map[pdfRef]int)
+	for i, page := range pages {
+		for _, annot := range r.array(page.dict["Annots"]) {
+			if ref, ok := annot.(pdfRef); ok {
+				annotPage[ref] = i + 1
+			}
+		}
+	}
+
+	type inherited struct {
+		name, ft, value string
+		flags           int
+		opts            pdfArray
+		maxLen          int
+	}
+
+	var fields []FormField
+	seen := make(map[pdfRef]bool)
+
+	var walk func(obj any, in inherited, depth int)
+	walk = func(obj any, in inherited, depth int) {
+		if ref, ok := obj.(pdfRef); ok {
+			if seen[ref] {
+				return
+			}
+			seen[ref] = true
+		}
+
+		node := r.dict(obj)
+		if node == nil || depth > pdfMaxDepth {
+			return
+		}
+
+		if t := r.text(node["T"]); t != "" {
+			if in.name != "" {
+				in.name += "."
+			}
+			in.name += t
+		}
+		if ft, ok := r.resolve(node["FT"]).(pdfName); ok {
+			in.ft = string(ft)
+		}
+		if ff, ok := r.number(node["Ff"]); ok {
+			in.flags = int(ff)
+		}
+		switch v := r.resolve(node["V"]).(type) {
+		case pdfName:
+			in.value = string(v)
+		case pdfString:
+			in.value = r.text(v)
+		}
+		if opts := r.array(node["Opt"]); opts != nil {
+			in.opts = opts
+		}
+		if n, ok := r.number(node["MaxLen"]); ok {
+			in.maxLen = int(n)
+		}
+
+		kids := r.array(node["Kids"])
+		for _, kid := range kids {
+			walk(kid, in, depth+1)
+		}
+		if len(kids) > 0 {
+			return
+		}
+
+		rect, ok := r.rect(node["Rect"])
+		if !ok || in.name == "" {
+			return
+		}
+
+		field := FormField{
+			Name:     in.name,
+			Value:    in.value,
+			Required: in.flags&formFlagRequired != 0,
+			ReadOnly: in.flags&formFlagReadOnly != 0,
+			MaxLen:   in.maxLen,
+		}
+
+		if ref, ok := node["P"].(pdfRef); ok {
+			field.Page = pageOf[ref]
+		}
+		if ref, ok := obj.(pdfRef); ok && field.Page == 0 {
+			field.Page = annotPage[ref]
+		}
+
+		switch in.ft {
+		case "Tx":
+			field.Type = "text"
+		case "Ch":
+			field.Type = "choice"
+			for _, opt := range in.opts {
+				// options are either values, or pairs of export value and text
+				if pair := r.array(opt); len(pair) > 0 {
+					opt = pair[0]
+				}
+				field.Options = append(field.Options, r.text(opt))
+			}
+		case "Sig":
+			field.Type = "signature"
+		case "Btn":
+			switch {
+			case in.flags&formFlagPushbutton != 0:
+				field.Type = "button"
+			case in.flags&formFlagRadio != 0:
+				field.Type = "radio"
+			default:
+				field.Type = "checkbox"
+			}
+			for state := range r.dict(r.dict(node["AP"])["N"]) {
+				if state != "Off" {
+					field.Export = string(state)
+				}
+			}
+			if field.Export == "" {
+				field.Export = "Yes"
+			}
+		default:
+			return
+		}
+
+		if field.Page > 0 {
+			page := pages[field.Page-1]
+			field.X = (rect[0] - page.cropBox[0]) * sx
+			field.Y = (page.cropBox[3] - rect[3]) * sy
+			field.W = (rect[2] - rect[0]) * sx
+			field.H = (rect[3] - rect[1]) * sy
+		}
+
+		fields = append(fields, field)
+	}
+
+	for _, field := range r.array(r.dict(r.catalog()["AcroForm"])["Fields"]) {
+		walk(field, inherited{}, 0)
+	}
+
+	return fields, nil
+}
+
+// reStyleBox matches the position and size properties of the inline style.
+var reStyleBox = regexp.MustCompile(`(?i)(?:^|[;\s])(left|top|width|height)\s*:\s*(-?[0-9.]+)px`)
+
+// formControls calls fn for every form control of the page, with the field whose
+// widget it was generated for. Controls are matched by name, or by position when
+// `pdftohtml` left them unnamed.
+func formControls(doc *htmlNode, fields []FormField, fn func(*htmlNode, FormField)) {
+	byName := make(map[string]FormField)
+	for _, field := range fields {
+		if _, ok := byName[field.Name]; !ok {
+			byName[field.Name] = field
+		}
+	}
+
+	doc.walk(func(n *htmlNode) bool {
+		if n.kind != elementNode || (n.tag != "input" && n.tag != "select" && n.tag != "textarea") {
+			return true
+		}
+
+		if name, ok := n.attr("name"); ok {
+			// radio buttons of the group share the name, but not the export value
+			val, _ := n.attr("value")
+			for _, field := range fields {
+				if field.Name == name && (field.Type != "radio" || field.Export == val) {
+					fn(n, field)
+					return false
+				}
+			}
+			if field, ok := byName[name]; ok {
+				fn(n, field)
+			}
+			return false
+		}
+
+		style, _ := n.attr("style")
+		box := make(map[string]float64)
+		for _, match := range reStyleBox.FindAllStringSubmatch(style, -1) {
+			box[strings.ToLower(match[1])], _ = strconv.ParseFloat(match[2], 64)
+		}
+		if _, ok := box["left"]; !ok {
+			return false
+		}
+		cx, cy := box["left"]+box["width"]/2, box["top"]+box["height"]/2
+
+		// the closest widget containing the center, with the rounding tolerated
+		const tolerance = 2
+		best, dist := -1, math.Inf(1)
+		for i, field := range fields {
+			if cx < field.X-tolerance || cx > field.X+field.W+tolerance ||
+				cy < field.Y-tolerance || cy > field.Y+field.H+tolerance {
+				continue
+			}
+			if d := math.Hypot(cx-field.X-field.W/2, cy-field.Y-field.H/2); d < dist {
+				best, dist = i, d
+			}
+		}
+		if best >= 0 {
+			fn(n, fields[best])
+		}
+
+		return false
+	})
+}
+
+// formValue returns the value as the text of the form control.
+func formValue(val any) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// formChecked reports whether the value checks the checkbox or radio button with
+// the export value.
+func formChecked(val any, export string) bool {
+	switch v := val.(type) {
+	case bool:
+		return v
+	case string:
+		if strings.EqualFold(v, export) {
+			return true
+		}
+		checked, _ := strconv.ParseBool(v)
+		return checked && export == "Yes"
+	default:
+		return false
+	}
+}
+
+// fillControl sets the value of the form control.
+func fillControl(n *htmlNode, field FormField, val any) {
+	switch {
+	case n.tag == "textarea":
+		n.children = nil
+		n.appendChild(&htmlNode{kind: textNode, text: formValue(val)})
+
+	case n.tag == "select":
+		selected := make(map[string]bool)
+		if list, ok := val.([]any); ok {
+			for _, v := range list {
+				selected[formValue(v)] = true
+			}
+		} else {
+			selected[formValue(val)] = true
+		}
+		n.walk(func(m *htmlNode) bool {
+			if m.kind == elementNode && m.tag == "option" {
+				v, ok := m.attr("value")
+				if !ok {
+					v = strings.TrimSpace(m.textContent())
+				}
+				m.removeAttr("selected")
+				if selected[v] {
+					m.setAttr("selected", "")
+				}
+				return false
+			}
+			return true
+		})
+
+	default:
+		switch typ, _ := n.attr("type"); strings.ToLower(typ) {
+		case "checkbox", "radio":
+			n.removeAttr("checked")
+			if formChecked(val, field.Export) {
+				n.setAttr("checked", "")
+			}
+		case "button", "submit", "reset", "image", "file":
+		default:
+			n.setAttr("value", formValue(val))
+		}
+	}
+}
+
+// FillForm sets the values of the form controls generated with
+// `WithEmbedFormFields` in the pages converted from the PDF file into the outdir.
+// Values are keyed by the fully qualified field names: strings and numbers for
+// text and choice fields, lists for multiple choices, and booleans or the export
+// values for checkboxes and radio buttons. Fields missing from the values keep
+// their generated state, unknown names are ignored.
+func (c *Command) FillForm(inpath, outdir string, values map[string]any) error {
+	fields, err := c.FormFields(inpath)
+	if err != nil {
+		return err
+	}
+
+	byPage := make(map[int][]FormField)
+	for _, field := range fields {
+		byPage[field.Page] = append(byPage[field.Page], field)
+	}
+
+	return rewriteHTML(outdir, func(name string, data []byte) ([]byte, error) {
+		n := pageNumber(name)
+		fields := byPage[n]
+		if n == 0 || len(fields) == 0 {
+			return data, nil
+		}
+
+		doc, xhtml := parseDocument(data)
+		formControls(doc, fields, func(n *htmlNode, field FormField) {
+			if val, ok := values[field.Name]; ok {
+				fillControl(n, field, val)
+			}
+		})
+
+		return renderDocument(doc, xhtml), nil
+	})
+}
+
+// FillFormJSON is `FillForm` with the values read from the JSON object.
+func (c *Command) FillFormJSON(inpath, outdir string, r io.Reader) error {
+	var values map[string]any
+	if err := json.NewDecoder(r).Decode(&values); err != nil {
+		return err
+	}
+
+	return c.FillForm(inpath, outdir, values)
+}
+
+// ----------------------------------------------------------------------------
+// -- form fields options
+// ----------------------------------------------------------------------------
+
+// Convert AcroForm fields to HTML input elements, like `WithEmbedFormFields`, and
+// pre-fill them with the values keyed by the field names, see `Command.FillForm`.
+// Fields of encrypted files are not filled.
+func WithFormValues(values map[string]any) option {
+	return func(c *Command) {
+		c.opts.FormFields = true
+		c.stages = append(c.stages, func(_ context.Context, inpath, outdir string) error {
+			if err := c.FillForm(inpath, outdir, values); err != nil && !errors.Is(err, ErrUnsupportedPDF) {
+				return err
+			}
+
+			return nil
+		})
+	}
+}
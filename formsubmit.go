@@ -0,0 +1,215 @@
+package pdftohtml
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- form submission
+// ----------------------------------------------------------------------------
+
+// FormSchemaName is the name of the JSON schema of the form fields in the outdir.
+const FormSchemaName = "form.schema.json"
+
+// nameControl sets the name attribute of the form control to the field name,
+// with the constraints of the field.
+func nameControl(n *htmlNode, field FormField) {
+	n.setAttr("name", field.Name)
+
+	typ, _ := n.attr("type")
+	if typ = strings.ToLower(typ); n.tag == "input" && (typ == "checkbox" || typ == "radio") {
+		// radio buttons of the group are submitted as the single value
+		if field.Type == "radio" {
+			typ = "radio"
+			n.setAttr("type", typ)
+		}
+		n.setAttr("value", field.Export)
+		// read-only state does not apply to checkboxes
+		if field.ReadOnly {
+			n.setAttr("disabled", "")
+		}
+		if field.Required && typ == "radio" {
+			n.setAttr("required", "")
+		}
+		return
+	}
+
+	if field.ReadOnly {
+		n.setAttr("readonly", "")
+	}
+	if field.Required {
+		n.setAttr("required", "")
+	}
+	if field.MaxLen > 0 && n.tag != "select" {
+		n.setAttr("maxlength", strconv.Itoa(field.MaxLen))
+	}
+}
+
+// wrapForm moves the content of the body into the form submitted to the action,
+// and adds the submit button below the page.
+func wrapForm(doc *htmlNode, action, method string) {
+	body := doc.find("body")
+	if body == nil {
+		return
+	}
+
+	form := &htmlNode{kind: elementNode, tag: "form", attrs: []htmlAttr{
+		{Key: "class", Val: "pdftohtml-form"},
+		{Key: "action", Val: action},
+		{Key: "method", Val: method},
+	}}
+	for _, child := range body.children {
+		form.appendChild(child)
+	}
+	body.children = nil
+
+	submit := &htmlNode{kind: elementNode, tag: "button", attrs: []htmlAttr{
+		{Key: "type", Val: "submit"},
+		{Key: "class", Val: "pdftohtml-submit"},
+	}}
+	if h, ok := backgroundHeight(doc); ok {
+		submit.setAttr("style", fmt.Sprintf("position:absolute; left:0px; top:%spx;", formatPixels(h)))
+	}
+	submit.appendChild(&htmlNode{kind: textNode, text: "Submit"})
+	form.appendChild(submit)
+	form.appendChild(&htmlNode{kind: textNode, text: "\n"})
+
+	body.appendChild(form)
+}
+
+// formSchema returns the JSON schema of the values submitted by the form.
+func formSchema(fields []FormField) map[string]any {
+	props := make(map[string]map[string]any)
+	required := []string{}
+
+	for _, field := range fields {
+		if field.Type == "button" || field.Type == "signature" {
+			continue
+		}
+
+		prop, ok := props[field.Name]
+		if !ok {
+			prop = map[string]any{"type": "string"}
+			if field.Required {
+				required = append(required, field.Name)
+			}
+			if field.ReadOnly {
+				prop["readOnly"] = true
+			}
+		}
+
+		switch field.Type {
+		case "text":
+			if field.MaxLen > 0 {
+				prop["maxLength"] = field.MaxLen
+			}
+			if field.Value != "" {
+				prop["default"] = field.Value
+			}
+		case "checkbox", "radio":
+			// unchecked controls are not submitted at all
+			enum, _ := prop["enum"].([]string)
+			prop["enum"] = append(enum, field.Export)
+			if field.Value == field.Export {
+				prop["default"] = field.Value
+			}
+		case "choice":
+			if len(field.Options) > 0 {
+				prop["enum"] = field.Options
+			}
+			if field.Value != "" {
+				prop["default"] = field.Value
+			}
+		}
+
+		props[field.Name] = prop
+	}
+
+	schema := map[string]any{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"type":       "object",
+		"properties": props,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// wireForm names the form controls in the pages converted into the outdir,
+// wraps them in forms submitted to the action, and writes the schema of the
+// fields.
+func (c *Command) wireForm(inpath, outdir, action, method string) error {
+	fields, err := c.FormFields(inpath)
+	if err != nil {
+		return err
+	}
+
+	byPage := make(map[int][]FormField)
+	for _, field := range fields {
+		byPage[field.Page] = append(byPage[field.Page], field)
+	}
+
+	err = rewriteHTML(outdir, func(name string, data []byte) ([]byte, error) {
+		n := pageNumber(name)
+		fields := byPage[n]
+		if n == 0 || len(fields) == 0 {
+			return data, nil
+		}
+
+		doc, xhtml := parseDocument(data)
+		formControls(doc, fields, nameControl)
+		wrapForm(doc, action, method)
+
+		return renderDocument(doc, xhtml), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(formSchema(fields), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(outdir, FormSchemaName), data, 0o644)
+}
+
+// ----------------------------------------------------------------------------
+// -- form submission options
+// ----------------------------------------------------------------------------
+
+// Convert AcroForm fields to HTML input elements, like `WithEmbedFormFields`, and
+// make them submittable: controls are named after the fully qualified field
+// names, the content of every page with fields is wrapped in the `form` sent to
+// the action with the method ("get" or "post"), and `form.schema.json` describes
+// the submitted values.
+//
+// Every page is a separate form, submitting the fields of the page only. Fields
+// of encrypted files are not wired.
+func WithFormSubmission(action, method string) option {
+	return func(c *Command) {
+		method = strings.ToLower(method)
+		if method != "get" && method != "post" {
+			c.errs = append(c.errs, fmt.Errorf("pdftohtml: invalid form method %q", method))
+			return
+		}
+
+		c.opts.FormFields = true
+		c.stages = append(c.stages, func(_ context.Context, inpath, outdir string) error {
+			if err := c.wireForm(inpath, outdir, action, method); err != nil && !errors.Is(err, ErrUnsupportedPDF) {
+				return err
+			}
+
+			return nil
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package pdftohtml
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- static-site front matter
+// ----------------------------------------------------------------------------
+
+// frontMatter creates YAML front matter describing the generated HTML file.
+func frontMatter(title, source string, page int, date time.Time) []byte {
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "title: %s\n", strconv.Quote(title))
+	if page > 0 {
+		fmt.Fprintf(&b, "page: %d\n", page)
+	}
+	fmt.Fprintf(&b, "source: %s\n", strconv.Quote(source))
+	fmt.Fprintf(&b, "date: %s\n", date.Format(time.RFC3339))
+	b.WriteString("---\n")
+
+	return []byte(b.String())
+}
+
+// Prepend YAML front matter (title, page number, source document, date) to every
+// generated HTML file, so the outdir can be published as content of static-site
+// generators like Hugo or Jekyll.
+//
+// The title is taken from the HTML title, falling back to the source document name
+// and page number. The date is the time of the conversion.
+func WithFrontMatter() option {
+	return func(c *Command) {
+		c.stages = append(c.stages, func(_ context.Context, inpath, outdir string) error {
+			source := filepath.Base(inpath)
+			date := time.Now()
+
+			return rewriteHTML(outdir, func(name string, data []byte) ([]byte, error) {
+				page := pageNumber(name)
+
+				title := htmlTitle(data)
+				if title == "" {
+					title = strings.TrimSuffix(source, filepath.Ext(source))
+					if page > 0 {
+						title += " - page " + strconv.Itoa(page)
+					}
+				}
+
+				return append(frontMatter(title, source, page, date), data...), nil
+			})
+		})
+	}
+}
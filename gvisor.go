@@ -0,0 +1,192 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// ----------------------------------------------------------------------------
+// -- gvisor executor
+// ----------------------------------------------------------------------------
+
+// GVisorRunner runs every conversion in a gVisor sandbox with `runsc`, so the
+// executable never talks to the host kernel directly.
+//
+// The sandbox has no network, the read-only root filesystem with the executable,
+// and only the directory with the input and the output mounted from the host.
+type GVisorRunner struct {
+	runsc    string
+	rootfs   string
+	platform string
+	rootless bool
+	args     []string
+	env      map[string]string
+}
+
+// runscEnv are variables passed on to `runsc`, which keeps the state of rootless
+// sandboxes in the runtime directory.
+var runscEnv = []string{"PATH", "HOME", "XDG_RUNTIME_DIR"}
+
+// NewGVisorRunner creates new runner converting in sandboxes with the root
+// filesystem, which has `pdftohtml` in `/usr/local/bin`, `/usr/bin` or `/bin`.
+func NewGVisorRunner(rootfs string, opts ...gvisorOption) (*GVisorRunner, error) {
+	r := &GVisorRunner{runsc: "runsc"}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	var err error
+	if r.rootfs, err = filepath.Abs(rootfs); err != nil {
+		return nil, err
+	}
+	if r.runsc, err = exec.LookPath(r.runsc); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Run converts the PDF file in the sandbox and moves the output into the outdir.
+func (r *GVisorRunner) Run(ctx context.Context, inpath, outdir string) error {
+	// like the executable, refuse to mix the output with existing files
+	if _, err := os.Stat(outdir); err == nil {
+		return fmt.Errorf("pdftohtml: outdir %q: %w", outdir, fs.ErrExist)
+	}
+
+	workdir, err := os.MkdirTemp("", "pdftohtml-gvisor-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workdir)
+
+	iodir := filepath.Join(workdir, "io")
+	if err := os.Mkdir(iodir, 0o777); err != nil {
+		return err
+	}
+	if err := copyFile(inpath, filepath.Join(iodir, "input.pdf")); err != nil {
+		return err
+	}
+
+	config, err := json.Marshal(r.spec(iodir))
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(workdir, "config.json"), config, 0o644); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	id := "pdftohtml-" + hex.EncodeToString(suffix)
+
+	args := []string{"--network=none"}
+	if r.platform != "" {
+		args = append(args, "--platform="+r.platform)
+	}
+	if r.rootless {
+		args = append(args, "--rootless")
+	}
+
+	var output bytes.Buffer
+
+	env := minimalEnviron(os.TempDir(), runscEnv, nil)
+
+	cmd := exec.CommandContext(ctx, r.runsc, append(args, "run", "--bundle", workdir, id)...)
+	cmd.Env = env
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err = cmd.Run()
+
+	// sandbox outlives the killed `runsc` process
+	cleanup := exec.Command(r.runsc, append(args, "delete", "--force", id)...)
+	cleanup.Env = env
+	cleanup.Run()
+
+	if err != nil {
+		return fmt.Errorf("pdftohtml: runsc: %w: %s", err, bytes.TrimSpace(output.Bytes()))
+	}
+
+	return moveTree(filepath.Join(iodir, "output"), outdir)
+}
+
+// spec returns the OCI runtime specification of the sandbox converting the input
+// in the directory.
+func (r *GVisorRunner) spec(iodir string) map[string]any {
+	args := append(append([]string{"pdftohtml"}, r.args...), "--", "/work/input.pdf", "/work/output")
+
+	return map[string]any{
+		"ociVersion": "1.0.0",
+		"process": map[string]any{
+			"user": map[string]int{"uid": 0, "gid": 0},
+			"args": args,
+			"env":  sandboxEnviron(r.env),
+			"cwd":  "/",
+		},
+		"root":     map[string]any{"path": r.rootfs, "readonly": true},
+		"hostname": "pdftohtml",
+		"mounts": []any{
+			map[string]any{"destination": "/proc", "type": "proc", "source": "proc"},
+			map[string]any{"destination": "/tmp", "type": "tmpfs", "source": "tmpfs"},
+			map[string]any{"destination": "/work", "type": "bind", "source": iodir, "options": []string{"rbind", "rw"}},
+		},
+		"linux": map[string]any{
+			"namespaces": []any{
+				map[string]string{"type": "pid"},
+				map[string]string{"type": "network"},
+				map[string]string{"type": "ipc"},
+				map[string]string{"type": "uts"},
+				map[string]string{"type": "mount"},
+			},
+		},
+	}
+}
+
+// ----------------------------------------------------------------------------
+// -- gvisor executor options
+// ----------------------------------------------------------------------------
+
+type gvisorOption func(*GVisorRunner)
+
+// Set custom location for `runsc` executable.
+func WithRunscPath(path string) gvisorOption {
+	return func(r *GVisorRunner) {
+		r.runsc = path
+	}
+}
+
+// Set the gVisor platform intercepting system calls, e.g. `systrap`, `ptrace`
+// or `kvm`. By default the platform is chosen by `runsc`.
+func WithGVisorPlatform(platform string) gvisorOption {
+	return func(r *GVisorRunner) {
+		r.platform = platform
+	}
+}
+
+// Run sandboxes without root privileges on the host.
+func WithGVisorRootless() gvisorOption {
+	return func(r *GVisorRunner) {
+		r.rootless = true
+	}
+}
+
+// Run the sandboxed executable with the arguments and the environment variables
+// of the command, e.g. its page range and zoom.
+//
+// Post-processing stages of the command are not run.
+func WithGVisorCommand(cmd *Command) gvisorOption {
+	return func(r *GVisorRunner) {
+		r.args = cmd.Args()
+		r.env = maps.Clone(cmd.env)
+	}
+}
@@ -0,0 +1,157 @@
+package pdftohtml
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newStubRunsc returns the `runsc` stub converting the input into page1.html,
+// which logs its arguments and keeps the spec of the sandbox in the directory.
+func newStubRunsc(t *testing.T) (path, dir string) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not executable on Windows")
+	}
+
+	dir = t.TempDir()
+	path = filepath.Join(dir, "runsc")
+	writeScript(t, path, `echo "$@" >> '`+dir+`/args'
+while [ $# -gt 0 ]; do
+	case "$1" in
+	run) bundle=$3; break ;;
+	delete) exit 0 ;;
+	esac
+	shift
+done
+cp "$bundle/config.json" '`+dir+`/config.json'
+mkdir "$bundle/io/output" && cp "$bundle/io/input.pdf" "$bundle/io/output/page1.html"
+`)
+
+	return path, dir
+}
+
+func TestGVisorRunner(t *testing.T) {
+	runsc, dir := newStubRunsc(t)
+
+	cmd := &Command{env: map[string]string{"LANG": "pl_PL.UTF-8"}}
+	WithInitialZoom(1.5)(cmd)
+
+	r, err := NewGVisorRunner("rootfs", WithRunscPath(runsc), WithGVisorPlatform("systrap"), WithGVisorRootless(), WithGVisorCommand(cmd))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmp := t.TempDir()
+	inpath := filepath.Join(tmp, "in.pdf")
+	if err := os.WriteFile(inpath, []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outdir := filepath.Join(tmp, "out")
+
+	if err := r.Run(context.Background(), inpath, outdir); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outdir, "page1.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "%PDF-1.4" {
+		t.Errorf("got output %q, want the converted input", data)
+	}
+
+	// the sandbox is deleted after the run
+	data, err = os.ReadFile(filepath.Join(dir, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(calls) != 2 {
+		t.Fatalf("got calls %q, want run and delete", calls)
+	}
+	run, del := strings.Fields(calls[0]), strings.Fields(calls[1])
+	if want := []string{"--network=none", "--platform=systrap", "--rootless", "run", "--bundle"}; !slices.Equal(run[:5], want) {
+		t.Errorf("got run %q, want %q", run, want)
+	}
+	if want := []string{"--network=none", "--platform=systrap", "--rootless", "delete", "--force", run[6]}; !slices.Equal(del, want) {
+		t.Errorf("got delete %q, want %q", del, want)
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Process struct {
+			Args []string `json:"args"`
+			Env  []string `json:"env"`
+		} `json:"process"`
+		Root struct {
+			Path     string `json:"path"`
+			Readonly bool   `json:"readonly"`
+		} `json:"root"`
+		Mounts []struct {
+			Destination string `json:"destination"`
+			Type        string `json:"type"`
+		} `json:"mounts"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	want := append(append([]string{"pdftohtml"}, cmd.Args()...), "--", "/work/input.pdf", "/work/output")
+	if len(cmd.Args()) == 0 || !slices.Equal(spec.Process.Args, want) {
+		t.Errorf("got args %q, want %q", spec.Process.Args, want)
+	}
+	if want := []string{"HOME=/tmp", "LANG=pl_PL.UTF-8", "PATH=/usr/local/bin:/usr/bin:/bin", "TMPDIR=/tmp"}; !slices.Equal(spec.Process.Env, want) {
+		t.Errorf("got env %q, want %q", spec.Process.Env, want)
+	}
+	if !filepath.IsAbs(spec.Root.Path) || !spec.Root.Readonly {
+		t.Errorf("got root %+v, want read-only absolute path", spec.Root)
+	}
+	var work bool
+	for _, m := range spec.Mounts {
+		work = work || m.Destination == "/work" && m.Type == "bind"
+	}
+	if !work {
+		t.Errorf("got mounts %+v, want /work bound", spec.Mounts)
+	}
+}
+
+func TestGVisorRunnerErrors(t *testing.T) {
+	runsc, _ := newStubRunsc(t)
+
+	tmp := t.TempDir()
+	inpath := filepath.Join(tmp, "in.pdf")
+	if err := os.WriteFile(inpath, []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewGVisorRunner("rootfs", WithRunscPath(filepath.Join(tmp, "runsc"))); err == nil {
+		t.Error("missing runsc: got nil error")
+	}
+
+	r, err := NewGVisorRunner("rootfs", WithRunscPath(runsc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(context.Background(), inpath, tmp); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("existing outdir: got %v, want fs.ErrExist", err)
+	}
+
+	// failure of the sandbox is reported with its output
+	writeScript(t, runsc, "case \"$1\" in delete) exit 0 ;; esac\necho 'sandbox failed'\nexit 1\n")
+	err = r.Run(context.Background(), inpath, filepath.Join(tmp, "out"))
+	if err == nil || !strings.Contains(err.Error(), "sandbox failed") {
+		t.Errorf("got %v, want the output of runsc", err)
+	}
+}
@@ -0,0 +1,365 @@
+package pdftohtml
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- http handler
+// ----------------------------------------------------------------------------
+
+// Handler serves the file system, converting PDF files to HTML on the first
+// request and serving the cached output afterwards.
+//
+// Conversion output of `docs/a.pdf` is served under `docs/a.pdf/`, e.g. the
+// index as `docs/a.pdf/` and the pages as `docs/a.pdf/page1.html`. Other files
+// are served as they are.
+type Handler struct {
+	fsys    http.FileSystem
+	runner  Runner
+	cache   string
+	files   http.Handler
+	timeout time.Duration
+
+	lru     *Cache // optional, bounds the cache directory
+	flights flights
+	outputs sync.Map // PDF file name -> *OutputHandler of its current outdir
+}
+
+// defaultHandlerTimeout is the default time limit of a single conversion of the
+// handler.
+const defaultHandlerTimeout = 5 * time.Minute
+
+// NewHandler creates new handler serving the file system and converting PDF
+// files with the runner into the cache directory.
+//
+// The cache is keyed by the path, size and modification time of the PDF file,
+// so changed files are converted again. Stale entries are not removed.
+func NewHandler(fsys http.FileSystem, runner Runner, cacheDir string, opts ...handlerOption) (*Handler, error) {
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	h := &Handler{
+		fsys:    fsys,
+		runner:  runner,
+		cache:   cacheDir,
+		files:   http.FileServer(fsys),
+		timeout: defaultHandlerTimeout,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h, nil
+}
+
+// NewCachedHandler creates new handler like `NewHandler`, keeping the output in
+// the bounded cache, so least recently requested documents are evicted.
+func NewCachedHandler(fsys http.FileSystem, runner Runner, cache *Cache, opts ...handlerOption) *Handler {
+	h := &Handler{
+		fsys:    fsys,
+		runner:  runner,
+		cache:   cache.Root(),
+		files:   http.FileServer(fsys),
+		timeout: defaultHandlerTimeout,
+		lru:     cache,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, rest, ok := splitPDFPath(path.Clean("/" + r.URL.Path))
+	if !ok {
+		h.files.ServeHTTP(w, r)
+		return
+	}
+
+	// relative links of the index require the trailing slash
+	if rest == "" && !strings.HasSuffix(r.URL.Path, "/") {
+		http.Redirect(w, r, path.Base(name)+"/", http.StatusMovedPermanently)
+		return
+	}
+
+	// conversion is shared with concurrent requests, so it outlives this one
+	ctx := context.WithoutCancel(r.Context())
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	outdir, err := h.convert(ctx, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		h.outputs.Delete(name)
+		http.NotFound(w, r)
+		return
+	}
+	var limited *RateLimitError
+	if errors.As(err, &limited) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limited.RetryAfter.Seconds()))))
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	r = r.Clone(r.Context())
+	r.URL.Path = "/" + rest
+	h.output(name, outdir).ServeHTTP(w, r)
+}
+
+// output returns the handler of the outdir of the PDF file. Handlers are kept
+// per file, replaced once the file changes and dropped once it is removed, so
+// they do not accumulate for outdirs no longer served.
+func (h *Handler) output(name, outdir string) *OutputHandler {
+	if v, ok := h.outputs.Load(name); ok && v.(*OutputHandler).outdir == outdir {
+		return v.(*OutputHandler)
+	}
+
+	output := NewOutputHandler(outdir)
+	h.outputs.Store(name, output)
+
+	return output
+}
+
+// splitPDFPath splits the URL path into the path of the PDF file and the path
+// within its conversion output.
+func splitPDFPath(urlPath string) (string, string, bool) {
+	parts := strings.Split(urlPath, "/")
+	for i, part := range parts {
+		if strings.EqualFold(path.Ext(part), ".pdf") {
+			return strings.Join(parts[:i+1], "/"), strings.Join(parts[i+1:], "/"), true
+		}
+	}
+
+	return "", "", false
+}
+
+// convert returns the cached output of the PDF file, converting it first if
+// needed.
+func (h *Handler) convert(ctx context.Context, name string) (string, error) {
+	f, key, err := openPDF(h.fsys, name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if h.lru != nil {
+		if outdir, ok := h.lru.Get(key); ok {
+			return outdir, nil
+		}
+	}
+
+	outdir := filepath.Join(h.cache, key)
+
+	return outdir, h.flights.do(outdir, func() error {
+		return h.convertFile(ctx, f, key)
+	})
+}
+
+// openPDF opens the PDF file in the file system, and returns it with the cache
+// key derived from its path, size and modification time.
+func openPDF(fsys http.FileSystem, name string) (http.File, string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	info, err := f.Stat()
+	if err == nil && info.IsDir() {
+		err = fs.ErrNotExist
+	}
+	if err != nil {
+		f.Close()
+		return nil, "", err
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%d", name, info.Size(), info.ModTime().UnixNano())))
+
+	return f, hex.EncodeToString(sum[:]), nil
+}
+
+// flights deduplicates concurrent creation of cache entries.
+type flights struct {
+	mu       sync.Mutex
+	inflight map[string]*conversion
+}
+
+// conversion is the creation in progress, waited for by concurrent callers.
+type conversion struct {
+	done chan struct{}
+	err  error
+}
+
+// do calls fn to create the path, unless it already exists. Concurrent callers
+// for the same path wait for a single call.
+func (f *flights) do(path string, fn func() error) error {
+	f.mu.Lock()
+	if c, ok := f.inflight[path]; ok {
+		f.mu.Unlock()
+		<-c.done
+		return c.err
+	}
+	if _, err := os.Stat(path); err == nil {
+		f.mu.Unlock()
+		return nil
+	}
+
+	if f.inflight == nil {
+		f.inflight = make(map[string]*conversion)
+	}
+	c := &conversion{done: make(chan struct{})}
+	f.inflight[path] = c
+	f.mu.Unlock()
+
+	c.err = fn()
+
+	f.mu.Lock()
+	delete(f.inflight, path)
+	f.mu.Unlock()
+	close(c.done)
+
+	return c.err
+}
+
+// convertFile converts the PDF file in a workspace in the cache directory and
+// moves the output under the key once complete.
+func (h *Handler) convertFile(ctx context.Context, f io.Reader, key string) error {
+	workdir, err := os.MkdirTemp(h.cache, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workdir)
+
+	inpath := filepath.Join(workdir, "input.pdf")
+	tmpdir := filepath.Join(workdir, "output")
+
+	dst, err := os.Create(inpath)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, f)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := h.runner.Run(ctx, inpath, tmpdir); err != nil {
+		return err
+	}
+
+	if h.lru != nil {
+		_, err := h.lru.Put(key, tmpdir)
+		return err
+	}
+
+	return os.Rename(tmpdir, filepath.Join(h.cache, key))
+}
+
+// ----------------------------------------------------------------------------
+// -- http handler options
+// ----------------------------------------------------------------------------
+
+type handlerOption func(*Handler)
+
+// Limit the time of a single conversion of the handler. Conversions are shared
+// by concurrent requests of the same file and not canceled when the requesting
+// client goes away, the limit stops the ones which never finish.
+//
+// The default is 5 minutes, 0 disables the limit.
+func WithHandlerTimeout(d time.Duration) handlerOption {
+	return func(h *Handler) {
+		h.timeout = d
+	}
+}
+
+// ----------------------------------------------------------------------------
+// -- output handler
+// ----------------------------------------------------------------------------
+
+// OutputHandler serves the conversion output in the outdir with the entity tags
+// recorded in the manifest, so conditional requests are answered without the
+// content.
+//
+// Files missing from the manifest, or all of them without the manifest, are
+// served with the modification time only.
+type OutputHandler struct {
+	outdir string
+	files  http.Handler
+
+	mu       sync.Mutex
+	modified time.Time         // modification time of the loaded manifest
+	etags    map[string]string // file name -> entity tag
+}
+
+// NewOutputHandler creates new handler serving the conversion output in the outdir.
+func NewOutputHandler(outdir string) *OutputHandler {
+	return &OutputHandler{outdir: outdir, files: http.FileServer(http.Dir(outdir))}
+}
+
+func (h *OutputHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" || name == "." {
+		name = "index.html"
+	}
+
+	if etag, ok := h.etag(name); ok {
+		w.Header().Set("ETag", etag)
+	}
+	h.files.ServeHTTP(w, r)
+}
+
+// etag returns the entity tag of the file, reloading the manifest once it changes.
+func (h *OutputHandler) etag(name string) (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	info, err := os.Stat(filepath.Join(h.outdir, ManifestName))
+	if err != nil {
+		return "", false
+	}
+
+	if !info.ModTime().Equal(h.modified) {
+		m, err := ReadManifest(h.outdir)
+		if err != nil {
+			return "", false
+		}
+
+		h.etags = make(map[string]string, len(m.Files))
+		for _, f := range m.Files {
+			if f.Hash != "" {
+				h.etags[f.Name] = f.ETag()
+			}
+		}
+		h.modified = info.ModTime()
+	}
+
+	etag, ok := h.etags[name]
+
+	return etag, ok
+}
@@ -0,0 +1,194 @@
+package pdftohtml
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// copyInput is the runner writing the input as the index and the first page.
+func copyInput(calls *atomic.Int64) Runner {
+	return convertFunc(func(_ context.Context, inpath, outdir string) error {
+		calls.Add(1)
+
+		data, err := os.ReadFile(inpath)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(outdir, 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(outdir, "index.html"), data, 0o644); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(outdir, "page1.html"), data, 0o644)
+	})
+}
+
+// serve sends the GET request to the handler and returns the response.
+func serve(h http.Handler, target string) (*http.Response, string) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+	resp := rec.Result()
+	body, _ := io.ReadAll(resp.Body)
+
+	return resp, string(body)
+}
+
+func TestHandler(t *testing.T) {
+	root := t.TempDir()
+	pdf := filepath.Join(root, "docs", "a.pdf")
+	if err := os.MkdirAll(filepath.Dir(pdf), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pdf, []byte("first"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls atomic.Int64
+	h, err := NewHandler(http.Dir(root), copyInput(&calls), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, _ := serve(h, "/docs/a.pdf")
+	if resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != "/docs/a.pdf/" {
+		t.Errorf("got %d to %q, want redirect to the index", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	for _, target := range []string{"/docs/a.pdf/", "/docs/a.pdf/page1.html"} {
+		resp, body := serve(h, target)
+		if resp.StatusCode != http.StatusOK || body != "first" {
+			t.Errorf("%s: got %d %q, want the output", target, resp.StatusCode, body)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("converted %d times, want once", n)
+	}
+
+	// changed file is converted again, replacing the handler of its output
+	if err := os.WriteFile(pdf, []byte("second"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(time.Hour)
+	if err := os.Chtimes(pdf, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	if _, body := serve(h, "/docs/a.pdf/"); body != "second" {
+		t.Errorf("got %q, want the output of the changed file", body)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("converted %d times, want twice", n)
+	}
+	if n := countOutputs(h); n != 1 {
+		t.Errorf("got %d output handlers, want 1", n)
+	}
+
+	// removed file drops the handler of its output
+	if err := os.Remove(pdf); err != nil {
+		t.Fatal(err)
+	}
+	if resp, _ := serve(h, "/docs/a.pdf/"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("got %d, want not found", resp.StatusCode)
+	}
+	if n := countOutputs(h); n != 0 {
+		t.Errorf("got %d output handlers, want 0", n)
+	}
+}
+
+// countOutputs returns the number of output handlers kept by the handler.
+func countOutputs(h *Handler) int {
+	n := 0
+	h.outputs.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+
+	return n
+}
+
+func TestHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		runner     Runner
+		status     int
+		retryAfter string
+	}{
+		{
+			name: "timeout",
+			runner: runnerFunc(func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}),
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "rate limited",
+			runner: runnerFunc(func(context.Context) error {
+				return &RateLimitError{RetryAfter: 1500 * time.Millisecond}
+			}),
+			status:     http.StatusTooManyRequests,
+			retryAfter: "2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			if err := os.WriteFile(filepath.Join(root, "a.pdf"), []byte("%PDF-1.4"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			h, err := NewHandler(http.Dir(root), tt.runner, t.TempDir(), WithHandlerTimeout(10*time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, _ := serve(h, "/a.pdf/")
+			if resp.StatusCode != tt.status {
+				t.Errorf("got %d, want %d", resp.StatusCode, tt.status)
+			}
+			if got := resp.Header.Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("got Retry-After %q, want %q", got, tt.retryAfter)
+			}
+		})
+	}
+}
+
+func TestCachedHandler(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.pdf", "b.pdf"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cache, err := NewCache(t.TempDir(), WithCacheMaxEntries(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls atomic.Int64
+	h := NewCachedHandler(http.Dir(root), copyInput(&calls), cache)
+
+	for _, target := range []string{"/a.pdf/", "/a.pdf/", "/b.pdf/", "/a.pdf/"} {
+		if _, body := serve(h, target); body != target[1:6] {
+			t.Errorf("%s: got %q", target, body)
+		}
+	}
+
+	// a.pdf is evicted by b.pdf and converted again
+	if n := calls.Load(); n != 3 {
+		t.Errorf("converted %d times, want 3", n)
+	}
+	if got := cache.Stats(); got.Entries != 1 || got.Evictions != 2 {
+		t.Errorf("got %+v, want 1 entry and 2 evictions", got)
+	}
+}
@@ -0,0 +1,157 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- hi-dpi backgrounds
+// ----------------------------------------------------------------------------
+
+// renderBackgrounds renders the page backgrounds of the PDF file again at the
+// resolution and moves them into the outdir as `page<N><suffix>.png`, for pages
+// still in the outdir. It returns the numbers of the rendered pages.
+func (c *Command) renderBackgrounds(ctx context.Context, opts OptionSet, inpath, outdir string, dpi uint64, suffix string) ([]int, error) {
+	tmpdir, err := os.MkdirTemp(filepath.Dir(outdir), ".pdftohtml-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpdir)
+
+	opts.Resolution = dpi
+	opts.EmbedBackground = false
+
+	// pages of the extra run are not reported
+	aux := *c
+	aux.onPage = nil
+	if err := aux.exec(ctx, opts, inpath, filepath.Join(tmpdir, "output")); err != nil {
+		return nil, err
+	}
+
+	names, err := listHTML(outdir)
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []int
+	for _, name := range names {
+		n := pageNumber(name)
+		if n == 0 {
+			continue
+		}
+
+		src := filepath.Join(tmpdir, "output", fmt.Sprintf("page%d.png", n))
+		dst := filepath.Join(outdir, fmt.Sprintf("page%d%s.png", n, suffix))
+
+		err := os.Rename(src, dst)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // page without background
+		}
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, n)
+	}
+
+	return pages, nil
+}
+
+// setBackgroundAttr sets the attribute on the background image of the page.
+func setBackgroundAttr(data []byte, key, val string) []byte {
+	return reBackground.ReplaceAllFunc(data, func(tag []byte) []byte {
+		end := len(tag) - 1
+		if bytes.HasSuffix(tag, []byte("/>")) {
+			end--
+		}
+
+		attr := fmt.Sprintf(` %s="%s"`, key, html.EscapeString(val))
+
+		return append(append(bytes.TrimRight(tag[:end:end], " "), attr...), tag[end:]...)
+	})
+}
+
+// baseResolution returns the resolution of the background images.
+func (c *Command) baseResolution() uint64 {
+	if c.opts.Resolution > 0 {
+		return c.opts.Resolution
+	}
+
+	return planDefaultDPI
+}
+
+// renderDensities renders the backgrounds for the pixel densities and lists them
+// in the `srcset` attribute of the background images.
+func (c *Command) renderDensities(ctx context.Context, opts OptionSet, inpath, outdir string) error {
+	srcsets := make(map[int][]string)
+	for _, density := range c.densities {
+		suffix := "@" + strconv.FormatFloat(density, 'f', -1, 64) + "x"
+		dpi := uint64(math.Round(float64(c.baseResolution()) * density))
+
+		pages, err := c.renderBackgrounds(ctx, opts, inpath, outdir, dpi, suffix)
+		if err != nil {
+			return err
+		}
+
+		for _, n := range pages {
+			srcsets[n] = append(srcsets[n], fmt.Sprintf("page%d%s.png %sx", n, suffix, strconv.FormatFloat(density, 'f', -1, 64)))
+		}
+	}
+
+	for n, srcset := range srcsets {
+		path := filepath.Join(outdir, fmt.Sprintf("page%d.html", n))
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		// the src of the image is the 1x candidate
+		data = setBackgroundAttr(data, "srcset", strings.Join(srcset, ", "))
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+// -- hi-dpi options
+// ----------------------------------------------------------------------------
+
+// Render page backgrounds also for the pixel densities, e.g. 2 for retina
+// displays, and list them in the `srcset` attribute of the background images,
+// so browsers download only the one matching the display. Without densities,
+// 2x backgrounds are rendered.
+//
+// Every density is rendered with an extra run of `pdftohtml`, at the resolution
+// multiplied by the density. The density must be greater than 1 and at most 8.
+func WithHiDPIBackgrounds(densities ...float64) option {
+	return func(c *Command) {
+		if len(densities) == 0 {
+			densities = []float64{2}
+		}
+
+		for _, density := range densities {
+			if !c.checkRange(RangeError{Option: "pixel density", Value: density, Min: 1, Max: 8, Exclusive: true}) {
+				return
+			}
+		}
+
+		if len(c.variants) > 0 {
+			c.errs = append(c.errs, errors.New("pdftohtml: hi-DPI backgrounds can't be combined with responsive backgrounds"))
+			return
+		}
+
+		c.densities = densities
+	}
+}
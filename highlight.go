@@ -0,0 +1,137 @@
+package pdftohtml
+
+import (
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- search-term highlighting
+// ----------------------------------------------------------------------------
+
+// highlightStyle keeps the color of the highlighted text, so the invisible text
+// layer over scanned pages stays invisible and only the background shows.
+const highlightStyle = "mark.pdftohtml-highlight{color:inherit;background-color:rgba(255,220,0,0.5);}"
+
+// Highlight wraps occurrences of the terms in the text of the page files in the
+// outdir in `mark` elements, matching case-insensitively.
+//
+// Terms are matched within a single text run of the generated HTML only, so a
+// phrase broken across lines or fonts is not found. The files are rewritten in
+// place, highlight a copy of the output to keep the original, and the hashes of
+// the manifest no longer match.
+func Highlight(outdir string, terms []string) error {
+	terms = compactTerms(terms)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	return rewriteHTML(outdir, func(name string, data []byte) ([]byte, error) {
+		if pageNumber(name) == 0 {
+			return data, nil
+		}
+
+		doc, xhtml := parseDocument(data)
+
+		if !highlightNode(doc, terms) {
+			return data, nil
+		}
+
+		if head := doc.find("head"); head != nil {
+			style := &htmlNode{kind: elementNode, tag: "style"}
+			style.appendChild(&htmlNode{kind: textNode, text: highlightStyle, raw: true})
+			head.appendChild(style)
+		}
+
+		return renderDocument(doc, xhtml), nil
+	})
+}
+
+// compactTerms returns the non-empty terms, trimmed, longest first, so longer
+// terms win over their prefixes.
+func compactTerms(terms []string) []string {
+	var compact []string
+	for _, term := range terms {
+		if term = strings.TrimSpace(term); term != "" {
+			compact = append(compact, term)
+		}
+	}
+
+	// insertion sort, the list of terms is short
+	for i := 1; i < len(compact); i++ {
+		for j := i; j > 0 && len(compact[j]) > len(compact[j-1]); j-- {
+			compact[j], compact[j-1] = compact[j-1], compact[j]
+		}
+	}
+
+	return compact
+}
+
+// highlightNode wraps the matches in the text nodes of n, and reports whether
+// anything matched.
+func highlightNode(n *htmlNode, terms []string) bool {
+	if n.kind == elementNode && (n.tag == "head" || n.tag == "mark" || rawTextElements[n.tag]) {
+		return false
+	}
+
+	matched := false
+
+	var children []*htmlNode
+	for _, child := range n.children {
+		if child.kind != textNode || child.raw {
+			matched = highlightNode(child, terms) || matched
+			children = append(children, child)
+			continue
+		}
+
+		text := child.text
+		for {
+			i, j := matchTerms(text, terms)
+			if i < 0 {
+				break
+			}
+			matched = true
+
+			if i > 0 {
+				children = append(children, &htmlNode{kind: textNode, text: text[:i], parent: n})
+			}
+
+			mark := &htmlNode{kind: elementNode, tag: "mark", attrs: []htmlAttr{{Key: "class", Val: "pdftohtml-highlight"}}, parent: n}
+			mark.appendChild(&htmlNode{kind: textNode, text: text[i:j]})
+			children = append(children, mark)
+
+			text = text[j:]
+		}
+
+		if text != "" {
+			children = append(children, &htmlNode{kind: textNode, text: text, parent: n})
+		}
+	}
+	n.children = children
+
+	return matched
+}
+
+// matchTerms returns the bounds of the first occurrence of any of the terms in
+// the text, or -1.
+func matchTerms(text string, terms []string) (int, int) {
+	// lowercase text is searched only if the offsets stay the same
+	lower := strings.ToLower(text)
+	folded := len(lower) == len(text)
+	if !folded {
+		lower = text
+	}
+
+	start, end := -1, -1
+	for _, term := range terms {
+		t := term
+		if folded {
+			t = strings.ToLower(term)
+		}
+
+		if i := strings.Index(lower, t); i >= 0 && (start < 0 || i < start) {
+			start, end = i, i+len(t)
+		}
+	}
+
+	return start, end
+}
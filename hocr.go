@@ -0,0 +1,108 @@
+package pdftohtml
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// ----------------------------------------------------------------------------
+// -- hOCR export
+// ----------------------------------------------------------------------------
+
+// hocrLine is the line of words of the page, with the bounding box of all of
+// them.
+type hocrLine struct {
+	words          []Word
+	x0, y0, x1, y1 float64
+}
+
+// hocrLines groups the words of the page, in the reading order, into lines. The
+// line breaks where the word does not overlap the middle of the line, or starts
+// left of the previous word.
+func hocrLines(words []Word) []hocrLine {
+	var lines []hocrLine
+	for _, word := range words {
+		if n := len(lines); n > 0 {
+			line := &lines[n-1]
+			prev := line.words[len(line.words)-1]
+			middle := (line.y0 + line.y1) / 2
+			if word.Y <= middle && word.Y+word.H >= middle && word.X >= prev.X {
+				line.words = append(line.words, word)
+				line.x0, line.y0 = min(line.x0, word.X), min(line.y0, word.Y)
+				line.x1, line.y1 = max(line.x1, word.X+word.W), max(line.y1, word.Y+word.H)
+				continue
+			}
+		}
+
+		lines = append(lines, hocrLine{words: []Word{word}, x0: word.X, y0: word.Y, x1: word.X + word.W, y1: word.Y + word.H})
+	}
+
+	return lines
+}
+
+// hocrBox returns the `bbox` property of the box, in whole pixels.
+func hocrBox(x0, y0, x1, y1 float64) string {
+	return fmt.Sprintf("bbox %d %d %d %d", int(math.Floor(x0)), int(math.Floor(y0)), int(math.Ceil(x1)), int(math.Ceil(y1)))
+}
+
+// ExportHOCR writes the hOCR document with the text layer of the pages
+// converted in the outdir to w, for OCR post-correction and digitization
+// tools. Pages, lines and words are marked up as `ocr_page`, `ocr_line` and
+// `ocrx_word`, with their bounding boxes in pixels of the generated HTML.
+//
+// The words are read from the word geometry in the outdir, which is written
+// by `WithWordGeometry`. Lines are derived from the positions of the words.
+func ExportHOCR(outdir string, w io.Writer, language string) error {
+	data, err := os.ReadFile(filepath.Join(outdir, WordsName))
+	if err != nil {
+		return err
+	}
+
+	var pages []PageWords
+	if err := json.Unmarshal(data, &pages); err != nil {
+		return err
+	}
+	if len(pages) == 0 {
+		return ErrEmptyOutput
+	}
+
+	lang := html.EscapeString(language)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(bw, "<!DOCTYPE html PUBLIC \"-//W3C//DTD XHTML 1.0 Transitional//EN\" \"http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd\">\n")
+	fmt.Fprintf(bw, "<html xmlns=\"http://www.w3.org/1999/xhtml\" xml:lang=\"%s\" lang=\"%s\">\n", lang, lang)
+	fmt.Fprintf(bw, "<head>\n<title></title>\n")
+	fmt.Fprintf(bw, "<meta http-equiv=\"Content-Type\" content=\"text/html; charset=utf-8\"/>\n")
+	fmt.Fprintf(bw, "<meta name=\"ocr-system\" content=\"go-pdftohtml\"/>\n")
+	fmt.Fprintf(bw, "<meta name=\"ocr-capabilities\" content=\"ocr_page ocr_line ocrx_word\"/>\n")
+	fmt.Fprintf(bw, "</head>\n<body>\n")
+
+	for i, page := range pages {
+		fmt.Fprintf(bw, "<div class=\"ocr_page\" id=\"page_%d\" title=\"%s; ppageno %d\">\n", page.Number, hocrBox(0, 0, page.Width, page.Height), i)
+
+		for j, line := range hocrLines(page.Words) {
+			fmt.Fprintf(bw, "<span class=\"ocr_line\" id=\"line_%d_%d\" title=\"%s\">", page.Number, j+1, hocrBox(line.x0, line.y0, line.x1, line.y1))
+			for k, word := range line.words {
+				if k > 0 {
+					bw.WriteString(" ")
+				}
+				fmt.Fprintf(bw, "<span class=\"ocrx_word\" id=\"word_%d_%d_%d\" title=\"%s\">%s</span>",
+					page.Number, j+1, k+1, hocrBox(word.X, word.Y, word.X+word.W, word.Y+word.H), html.EscapeString(word.Text))
+			}
+			bw.WriteString("</span>\n")
+		}
+
+		bw.WriteString("</div>\n")
+	}
+
+	bw.WriteString("</body>\n</html>\n")
+
+	return bw.Flush()
+}
@@ -0,0 +1,292 @@
+package pdftohtml
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- incremental conversion
+// ----------------------------------------------------------------------------
+
+// optionsHash returns the hash of the arguments, so changed options can be told
+// apart without storing the passwords.
+func (c *Command) optionsHash() string {
+	sum := sha256.Sum256([]byte(strings.Join(c.Args(), "\x00")))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// incrementalPlan is what the manifest in the outdir tells about the conversion.
+type incrementalPlan struct {
+	hash    string        // hash of the input
+	pages   []string      // hashes of the pages, nil if the input can't be read
+	current bool          // outdir contains complete output of the input already
+	changed pageSelection // pages to convert again, nil to convert all
+}
+
+// planIncremental compares the input with the manifest in the outdir.
+func (c *Command) planIncremental(inpath, outdir string) (incrementalPlan, error) {
+	var plan incrementalPlan
+
+	var err error
+	if plan.hash, err = hashFile(inpath); err != nil {
+		return plan, err
+	}
+
+	if r, err := openPDFFile(inpath); err == nil {
+		plan.pages = r.pageHashes()
+	} else if !errors.Is(err, ErrUnsupportedPDF) {
+		return plan, err
+	}
+
+	m, err := ReadManifest(outdir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return plan, nil
+	}
+	if err != nil {
+		return plan, err
+	}
+	if m.Options != c.optionsHash() || m.Partial {
+		return plan, nil
+	}
+	if m.Hash == plan.hash {
+		plan.current = true
+		return plan, nil
+	}
+
+	if c.reusesPages() && plan.pages != nil && len(plan.pages) == len(m.PageHashes) {
+		plan.changed = changedPages(m.PageHashes, plan.pages)
+	}
+
+	return plan, nil
+}
+
+// reusesPages reports whether pages of the previous conversion can be kept. The
+// output must be the one of the executable alone: post-processing stages and
+// extra renditions rewrite all pages, and page selections change which pages
+// are converted.
+func (c *Command) reusesPages() bool {
+	return len(c.stages) == 0 && len(c.densities) == 0 && len(c.variants) == 0 &&
+		len(c.pages) == 0 && c.chunk == 0 && c.width == 0 &&
+		c.opts.PageFrom == 0 && c.opts.PageTo == 0 &&
+		c.blank <= BlankPagesMark && c.xfa != XFAFallback
+}
+
+// changedPages returns the ranges of the pages with changed hashes, or nil if
+// no page changed, e.g. when only the metadata did.
+func changedPages(prev, next []string) pageSelection {
+	var ranges pageSelection
+	for i := range next {
+		if prev[i] == next[i] {
+			continue
+		}
+
+		n := uint64(i + 1)
+		if last := len(ranges) - 1; last >= 0 && ranges[last].to == n-1 {
+			ranges[last].to = n
+			continue
+		}
+		ranges = append(ranges, pageRange{from: n, to: n})
+	}
+
+	return ranges
+}
+
+// pageHashes returns the hex-encoded SHA-256 hashes of the pages, covering the
+// content, resources and annotations of every page.
+func (r *pdfReader) pageHashes() []string {
+	pages := r.pages()
+
+	// references to pages, e.g. of annotations, are not followed into other pages
+	refs := make(map[pdfRef]int, len(pages))
+	for i, page := range pages {
+		if page.ref != (pdfRef{}) {
+			refs[page.ref] = i + 1
+		}
+	}
+
+	hashes := make([]string, len(pages))
+	for i, page := range pages {
+		h := sha256.New()
+		fmt.Fprintf(h, "%v %v %d ", page.mediaBox, page.cropBox, page.rotate)
+		r.hashObject(h, page.dict, refs, make(map[pdfRef]bool), 0)
+		hashes[i] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	return hashes
+}
+
+// hashObject writes the object to the hash, with references replaced by the
+// objects they point to, so renumbered objects hash the same.
+func (r *pdfReader) hashObject(h hash.Hash, obj any, pages map[pdfRef]int, visiting map[pdfRef]bool, depth int) {
+	if depth > pdfMaxDepth {
+		h.Write([]byte("?"))
+		return
+	}
+
+	switch v := obj.(type) {
+	case pdfRef:
+		if n, ok := pages[v]; ok && depth > 0 {
+			fmt.Fprintf(h, "page%d ", n)
+			return
+		}
+		// cycles of other objects are cut
+		if visiting[v] {
+			h.Write([]byte("R "))
+			return
+		}
+		visiting[v] = true
+		r.hashObject(h, r.object(v.num), pages, visiting, depth+1)
+		delete(visiting, v)
+
+	case pdfDict:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			// the parent is the page tree, shared by all pages
+			if key != "Parent" {
+				keys = append(keys, string(key))
+			}
+		}
+		sort.Strings(keys)
+
+		h.Write([]byte("<< "))
+		for _, key := range keys {
+			fmt.Fprintf(h, "/%s ", key)
+			r.hashObject(h, v[pdfName(key)], pages, visiting, depth+1)
+		}
+		h.Write([]byte(">> "))
+
+	case pdfArray:
+		h.Write([]byte("[ "))
+		for _, item := range v {
+			r.hashObject(h, item, pages, visiting, depth+1)
+		}
+		h.Write([]byte("] "))
+
+	case *pdfStream:
+		r.hashObject(h, v.dict, pages, visiting, depth+1)
+		fmt.Fprintf(h, "stream %d ", len(v.raw))
+		h.Write(v.raw)
+
+	default:
+		fmt.Fprintf(h, "%T %v ", v, v)
+	}
+}
+
+// reconvert converts the pages of the ranges again, replacing them in the outdir
+// and keeping the other pages and the index, which lists all of them already.
+// Fonts no longer used by any page are removed.
+func (c *Command) reconvert(ctx context.Context, opts OptionSet, inpath, outdir string, ranges pageSelection) error {
+	tmpdir, err := os.MkdirTemp(filepath.Dir(outdir), ".pdftohtml-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// pages of the runs are reported once merged
+	aux := *c
+	aux.onPage = nil
+
+	for i, r := range ranges {
+		opts.PageFrom, opts.PageTo = r.from, r.to
+
+		rundir := filepath.Join(tmpdir, strconv.Itoa(i))
+		if err := aux.exec(ctx, opts, inpath, rundir); err != nil {
+			return err
+		}
+
+		err := os.Remove(filepath.Join(rundir, "index.html"))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		if err := mergeOutput(rundir, outdir); err != nil {
+			return err
+		}
+
+		if c.onPage != nil {
+			for n := int(r.from); n <= int(r.to); n++ {
+				if err := c.onPage(ctx, pageResult(outdir, n)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return removeUnusedFonts(outdir)
+}
+
+// removeUnusedFonts removes the extracted font files not referenced by any page
+// or stylesheet in the outdir.
+func removeUnusedFonts(outdir string) error {
+	entries, err := os.ReadDir(outdir)
+	if err != nil {
+		return err
+	}
+
+	var fonts []string
+	var markup [][]byte
+	for _, entry := range entries {
+		name := entry.Name()
+		if reFontFile.MatchString(name) {
+			fonts = append(fonts, name)
+			continue
+		}
+
+		if ext := filepath.Ext(name); ext == ".html" || ext == ".css" {
+			data, err := os.ReadFile(filepath.Join(outdir, name))
+			if err != nil {
+				return err
+			}
+			markup = append(markup, data)
+		}
+	}
+
+	for _, font := range fonts {
+		used := slices.ContainsFunc(markup, func(data []byte) bool {
+			return strings.Contains(string(data), font)
+		})
+		if used {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(outdir, font)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Skip the conversion when the manifest in the outdir shows it already contains
+// complete output of the same input, converted with the same options.
+//
+// When the input changed, only the pages whose content, resources or annotations
+// changed are converted again, and the other pages are kept, e.g. for the
+// 2-page amendment of the 1,000-page document. Pages are compared by hashes the
+// manifest records, read by the package itself. The whole document is converted
+// again, after the outdir is cleaned like with `WithOutdirClean`, when:
+//   - the page count, or only the metadata, changed,
+//   - the file is encrypted,
+//   - post-processing stages, extra background renditions, page selections, the
+//     XFA fallback or removal of blank pages rewrite the output.
+//
+// Post-processing stages are not compared, so output is not refreshed when only
+// the stages change.
+func WithIncremental() option {
+	return func(c *Command) {
+		c.incremental = true
+	}
+}
@@ -0,0 +1,99 @@
+package pdftohtml
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// threePages returns the PDF file of three pages with the contents, numbering
+// the objects from the first.
+func threePages(first int, contents ...string) []byte {
+	objects := make([]string, first-1)
+	for i := range objects {
+		objects[i] = "null"
+	}
+
+	ref := func(i int) string { return strconv.Itoa(first+i) + " 0 R" }
+	objects = append(objects,
+		"<< /Type /Catalog /Pages "+ref(1)+" >>",
+		"<< /Type /Pages /Kids ["+ref(2)+" "+ref(3)+" "+ref(4)+"] /Count 3 /MediaBox [0 0 612 792] >>",
+	)
+	for i := range 3 {
+		objects = append(objects, "<< /Type /Page /Parent "+ref(1)+" /Contents "+ref(5+i)+" >>")
+	}
+	for _, content := range contents {
+		objects = append(objects, "<< /Length "+strconv.Itoa(len(content))+" >>\nstream\n"+content+"\nendstream")
+	}
+
+	return pdfFile("<< /Size "+strconv.Itoa(len(objects)+1)+" /Root "+ref(0)+" >>", objects...)
+}
+
+func TestPageHashes(t *testing.T) {
+	hashes := func(data []byte) []string {
+		r, err := newPDFReader(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.pageHashes()
+	}
+
+	prev := hashes(threePages(1, "BT (a) Tj ET", "BT (b) Tj ET", "BT (c) Tj ET"))
+	if len(prev) != 3 || prev[0] == prev[1] {
+		t.Fatalf("got %q", prev)
+	}
+
+	// renumbered objects hash the same
+	if got := hashes(threePages(5, "BT (a) Tj ET", "BT (b) Tj ET", "BT (c) Tj ET")); !slices.Equal(got, prev) {
+		t.Errorf("renumbered: got %q, want %q", got, prev)
+	}
+
+	next := hashes(threePages(1, "BT (a) Tj ET", "BT (B) Tj ET", "BT (c) Tj ET"))
+	if got, want := changedPages(prev, next), (pageSelection{{from: 2, to: 2}}); !slices.Equal(got, want) {
+		t.Errorf("changed: got %v, want %v", got, want)
+	}
+}
+
+func TestChangedPages(t *testing.T) {
+	tests := []struct {
+		prev, next []string
+		want       pageSelection
+	}{
+		{[]string{"a", "b", "c"}, []string{"a", "b", "c"}, nil},
+		{[]string{"a", "b", "c", "d"}, []string{"x", "y", "c", "z"}, pageSelection{{from: 1, to: 2}, {from: 4, to: 4}}},
+	}
+
+	for _, tt := range tests {
+		if got := changedPages(tt.prev, tt.next); !slices.Equal(got, tt.want) {
+			t.Errorf("changedPages(%q, %q) = %v, want %v", tt.prev, tt.next, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveUnusedFonts(t *testing.T) {
+	outdir := t.TempDir()
+	files := map[string]string{
+		"page1.html": `<style>@font-face { src: url("ff0.woff"); }</style>`,
+		"page2.html": `<style>@font-face { src: url("ff3.woff"); }</style>`,
+		"ff0.woff":   "",
+		"ff1.woff":   "",
+		"ff3.woff":   "",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(outdir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := removeUnusedFonts(outdir); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]bool{"ff0.woff": true, "ff1.woff": false, "ff3.woff": true} {
+		if _, err := os.Stat(filepath.Join(outdir, name)); (err == nil) != want {
+			t.Errorf("%s: exists = %v, want %v", name, err == nil, want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ----------------------------------------------------------------------------
+// -- input validation
+// ----------------------------------------------------------------------------
+
+// ErrInvalidInput is returned when the input is not a readable PDF file.
+var ErrInvalidInput = errors.New("pdftohtml: invalid input")
+
+// pdfMagic is the header of every PDF file. The specification allows it to
+// appear anywhere in the first 1024 bytes.
+var pdfMagic = []byte("%PDF-")
+
+// checkInput asserts that the input is a readable PDF file and returns its
+// absolute path, which `pdftohtml` can never mistake for an option.
+func checkInput(inpath string) (string, error) {
+	abs, err := filepath.Abs(inpath)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%w: %q is not a regular file", ErrInvalidInput, inpath)
+	}
+
+	f, err := os.Open(abs)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	defer f.Close()
+
+	head := make([]byte, 1024)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+
+	if !bytes.Contains(head[:n], pdfMagic) {
+		return "", fmt.Errorf("%w: %q is not a PDF file", ErrInvalidInput, inpath)
+	}
+
+	return abs, nil
+}
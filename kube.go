@@ -0,0 +1,284 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- kubernetes job executor
+// ----------------------------------------------------------------------------
+
+// ErrJobFailed is returned when the Kubernetes Job running the conversion fails.
+var ErrJobFailed = errors.New("pdftohtml: job failed")
+
+// kubePollInterval is how often the status of the job is checked.
+const kubePollInterval = 2 * time.Second
+
+// KubeRunner runs every conversion as a Kubernetes Job, using `kubectl` with the
+// credentials of the current process. Unlike the executables converting files,
+// `kubectl` inherits the environment of the process, which its credential
+// plugins read. The job gets the minimal environment, see `WithEnv`.
+//
+// The input and the output are exchanged through a shared volume: the persistent
+// volume claim mounted in the job pod, and mounted locally in the local directory.
+type KubeRunner struct {
+	kubectl   string
+	image     string
+	claim     string
+	localDir  string
+	podDir    string
+	namespace string
+	args      []string
+	env       map[string]string
+	requests  map[string]string
+	limits    map[string]string
+	selector  map[string]string
+}
+
+// NewKubeRunner creates new runner converting in jobs of the image, which has
+// `pdftohtml` in the PATH. The claim is the persistent volume claim that is
+// mounted locally in the local directory.
+func NewKubeRunner(image, claim, localDir string, opts ...kubeOption) (*KubeRunner, error) {
+	r := &KubeRunner{kubectl: "kubectl", image: image, claim: claim, localDir: localDir, podDir: "/work"}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	var err error
+	if r.kubectl, err = exec.LookPath(r.kubectl); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Run converts the PDF file in the job and moves the output into the outdir.
+func (r *KubeRunner) Run(ctx context.Context, inpath, outdir string) error {
+	// like the executable, refuse to mix the output with existing files
+	if _, err := os.Stat(outdir); err == nil {
+		return fmt.Errorf("pdftohtml: outdir %q: %w", outdir, fs.ErrExist)
+	}
+
+	workdir, err := os.MkdirTemp(r.localDir, "job-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workdir)
+
+	if err := copyFile(inpath, filepath.Join(workdir, "input.pdf")); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := "pdftohtml-" + hex.EncodeToString(suffix)
+
+	manifest, err := json.Marshal(r.job(name, path.Join(r.podDir, filepath.Base(workdir))))
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.kubectlRun(ctx, manifest, "create", "-f", "-"); err != nil {
+		return err
+	}
+	defer r.kubectlRun(context.WithoutCancel(ctx), nil, "delete", "job", name, "--wait=false", "--cascade=background")
+
+	if err := r.wait(ctx, name); err != nil {
+		return err
+	}
+
+	return moveTree(filepath.Join(workdir, "output"), outdir)
+}
+
+// job returns the Job manifest converting the input in the pod directory.
+func (r *KubeRunner) job(name, dir string) map[string]any {
+	args := append(append([]string(nil), r.args...), "--", path.Join(dir, "input.pdf"), path.Join(dir, "output"))
+
+	var env []any
+	for _, kv := range sandboxEnviron(r.env) {
+		name, value, _ := strings.Cut(kv, "=")
+		env = append(env, map[string]string{"name": name, "value": value})
+	}
+
+	container := map[string]any{
+		"name":         "pdftohtml",
+		"image":        r.image,
+		"command":      []string{"pdftohtml"},
+		"args":         args,
+		"env":          env,
+		"volumeMounts": []any{map[string]any{"name": "work", "mountPath": r.podDir}},
+		"resources":    map[string]any{"requests": r.requests, "limits": r.limits},
+	}
+
+	return map[string]any{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata":   map[string]any{"name": name, "labels": map[string]string{"app": "pdftohtml"}},
+		"spec": map[string]any{
+			"backoffLimit":            0,
+			"ttlSecondsAfterFinished": 600,
+			"template": map[string]any{
+				"spec": map[string]any{
+					"restartPolicy": "Never",
+					"nodeSelector":  r.selector,
+					"containers":    []any{container},
+					"volumes": []any{map[string]any{
+						"name":                  "work",
+						"persistentVolumeClaim": map[string]string{"claimName": r.claim},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// wait polls the job until it succeeds or fails.
+func (r *KubeRunner) wait(ctx context.Context, name string) error {
+	ticker := time.NewTicker(kubePollInterval)
+	defer ticker.Stop()
+
+	for {
+		out, err := r.kubectlRun(ctx, nil, "get", "job", name, "-o", "jsonpath={.status.succeeded},{.status.failed}")
+		if err != nil {
+			return err
+		}
+
+		succeeded, failed, _ := strings.Cut(strings.TrimSpace(string(out)), ",")
+		if succeeded != "" && succeeded != "0" {
+			return nil
+		}
+		if failed != "" && failed != "0" {
+			logs, _ := r.kubectlRun(ctx, nil, "logs", "job/"+name, "--tail=20")
+			return fmt.Errorf("%w: %s: %s", ErrJobFailed, name, bytes.TrimSpace(logs))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// kubectlRun runs `kubectl` in the namespace, with the stdin if not nil, and
+// returns its output.
+func (r *KubeRunner) kubectlRun(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	if r.namespace != "" {
+		args = append([]string{"--namespace=" + r.namespace}, args...)
+	}
+
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, r.kubectl, args...)
+	cmd.Stderr = &stderr
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("pdftohtml: kubectl %s: %w: %s", args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return out, nil
+}
+
+// moveTree moves the directory tree, copying it when the destination is on
+// another filesystem.
+func moveTree(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, 0o755)
+		case d.Type().IsRegular():
+			return copyFile(path, target)
+		}
+
+		return nil
+	})
+}
+
+// ----------------------------------------------------------------------------
+// -- kubernetes job executor options
+// ----------------------------------------------------------------------------
+
+type kubeOption func(*KubeRunner)
+
+// Set custom location for `kubectl` executable.
+func WithKubectlPath(path string) kubeOption {
+	return func(r *KubeRunner) {
+		r.kubectl = path
+	}
+}
+
+// Set the namespace jobs are created in, instead of the one of the current context.
+func WithKubeNamespace(namespace string) kubeOption {
+	return func(r *KubeRunner) {
+		r.namespace = namespace
+	}
+}
+
+// Set the directory the shared volume is mounted in within the job pod.
+//
+// By default the volume is mounted in `/work`.
+func WithKubeMountPath(dir string) kubeOption {
+	return func(r *KubeRunner) {
+		r.podDir = dir
+	}
+}
+
+// Run the job with the arguments and the environment variables of the command,
+// e.g. its page range and zoom.
+//
+// Post-processing stages of the command are not run.
+func WithKubeCommand(cmd *Command) kubeOption {
+	return func(r *KubeRunner) {
+		r.args = cmd.Args()
+		r.env = maps.Clone(cmd.env)
+	}
+}
+
+// Set resource requests and limits of the job container, e.g. `cpu` and
+// `memory`, in the Kubernetes quantity notation.
+func WithKubeResources(requests, limits map[string]string) kubeOption {
+	return func(r *KubeRunner) {
+		r.requests, r.limits = requests, limits
+	}
+}
+
+// Schedule the job only on nodes with the labels.
+func WithKubeNodeSelector(labels map[string]string) kubeOption {
+	return func(r *KubeRunner) {
+		r.selector = labels
+	}
+}
@@ -0,0 +1,262 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ----------------------------------------------------------------------------
+// -- lazy page conversion
+// ----------------------------------------------------------------------------
+
+// LazyHandler serves the file system like `Handler`, but converts every page
+// of the PDF file separately, once it is first requested.
+//
+// The index of `docs/a.pdf` is served as `docs/a.pdf/` and page N converted in
+// its own directory as `docs/a.pdf/N/pageN.html`, so resources of different
+// pages never collide.
+type LazyHandler struct {
+	fsys  http.FileSystem
+	cmd   *Command
+	cache string
+	files http.Handler
+
+	flights flights
+	outputs sync.Map // PDF file name -> *lazyOutputs of its current document
+}
+
+// lazyOutputs are the handlers of the converted pages of the document.
+type lazyOutputs struct {
+	docdir string
+	pages  sync.Map // page number -> *OutputHandler
+}
+
+// NewLazyHandler creates new handler serving the file system and converting
+// pages with the command into the cache directory.
+//
+// The page range of the command is replaced for every page, other options are
+// kept.
+func NewLazyHandler(fsys http.FileSystem, cmd *Command, cacheDir string) (*LazyHandler, error) {
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &LazyHandler{fsys: fsys, cmd: cmd, cache: cacheDir, files: http.FileServer(fsys)}, nil
+}
+
+func (h *LazyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, rest, ok := splitPDFPath(path.Clean("/" + r.URL.Path))
+	if !ok {
+		h.files.ServeHTTP(w, r)
+		return
+	}
+
+	// relative links of the index require the trailing slash
+	if rest == "" && !strings.HasSuffix(r.URL.Path, "/") {
+		http.Redirect(w, r, path.Base(name)+"/", http.StatusMovedPermanently)
+		return
+	}
+
+	ctx := context.WithoutCancel(r.Context())
+
+	docdir, pages, err := h.prepare(ctx, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		h.outputs.Delete(name)
+	}
+	if err != nil {
+		lazyError(w, r, err)
+		return
+	}
+
+	page, file, _ := strings.Cut(rest, "/")
+	if page == "" {
+		http.ServeFile(w, r, filepath.Join(docdir, "index.html"))
+		return
+	}
+
+	n, err := strconv.Atoi(page)
+	if err != nil || n < 1 || n > pages {
+		http.NotFound(w, r)
+		return
+	}
+
+	if file == "" {
+		target := fmt.Sprintf("page%d.html", n)
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			target = page + "/" + target
+		}
+
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	}
+
+	outdir, err := h.convertPage(ctx, docdir, n)
+	if err != nil {
+		lazyError(w, r, err)
+		return
+	}
+
+	r = r.Clone(r.Context())
+	r.URL.Path = "/" + file
+	h.output(name, docdir, n, outdir).ServeHTTP(w, r)
+}
+
+// output returns the handler of the outdir of the page. Handlers are kept per
+// file, replaced once the file changes and dropped once it is removed, like the
+// ones of `Handler`.
+func (h *LazyHandler) output(name, docdir string, n int, outdir string) *OutputHandler {
+	v, ok := h.outputs.Load(name)
+	if !ok || v.(*lazyOutputs).docdir != docdir {
+		v = &lazyOutputs{docdir: docdir}
+		h.outputs.Store(name, v)
+	}
+
+	output, _ := v.(*lazyOutputs).pages.LoadOrStore(n, NewOutputHandler(outdir))
+
+	return output.(*OutputHandler)
+}
+
+// lazyError replies with the status matching the error.
+func lazyError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// Warm converts the pages of the PDF file in the file system ahead of requests.
+// Without pages, all pages are converted.
+func (h *LazyHandler) Warm(ctx context.Context, name string, pages ...int) error {
+	docdir, count, err := h.prepare(ctx, path.Clean("/"+name))
+	if err != nil {
+		return err
+	}
+
+	if len(pages) == 0 {
+		for n := 1; n <= count; n++ {
+			pages = append(pages, n)
+		}
+	}
+
+	for _, n := range pages {
+		if n < 1 || n > count {
+			return fmt.Errorf("pdftohtml: page %d out of range [1, %d]", n, count)
+		}
+
+		if _, err := h.convertPage(ctx, docdir, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prepare copies the PDF file into the cache, discovers its page count and
+// writes the index linking all pages. It returns the document directory and
+// the page count.
+func (h *LazyHandler) prepare(ctx context.Context, name string) (string, int, error) {
+	f, key, err := openPDF(h.fsys, name)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	docdir := filepath.Join(h.cache, key)
+
+	err = h.flights.do(docdir, func() error {
+		return h.prepareDocument(ctx, f, path.Base(name), docdir)
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(docdir, "pages"))
+	if err != nil {
+		return "", 0, err
+	}
+	pages, err := strconv.Atoi(string(data))
+
+	return docdir, pages, err
+}
+
+// prepareDocument creates the document directory with the input, its page count
+// and the index, moving it into place once complete.
+func (h *LazyHandler) prepareDocument(ctx context.Context, f io.Reader, title, docdir string) error {
+	workdir, err := os.MkdirTemp(h.cache, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workdir)
+
+	inpath := filepath.Join(workdir, "input.pdf")
+
+	dst, err := os.Create(inpath)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, f)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	info, err := h.cmd.Info(ctx, inpath)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(workdir, "pages"), []byte(strconv.Itoa(info.Pages)), 0o644)
+	if err != nil {
+		return err
+	}
+
+	var index strings.Builder
+	for n := 1; n <= info.Pages; n++ {
+		fmt.Fprintf(&index, "<a href=\"%d/page%d.html\">Page %d</a><br>\n", n, n, n)
+	}
+
+	if err := writeTextPage(filepath.Join(workdir, "index.html"), title, index.String()); err != nil {
+		return err
+	}
+
+	return os.Rename(workdir, docdir)
+}
+
+// convertPage converts the single page of the prepared document, unless it is
+// already converted, and returns its outdir.
+func (h *LazyHandler) convertPage(ctx context.Context, docdir string, n int) (string, error) {
+	outdir := filepath.Join(docdir, strconv.Itoa(n))
+
+	return outdir, h.flights.do(outdir, func() error {
+		tmpdir, err := os.MkdirTemp(docdir, ".tmp-*")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmpdir)
+
+		cmd := *h.cmd
+		cmd.opts.PageFrom, cmd.opts.PageTo = uint64(n), uint64(n)
+
+		output := filepath.Join(tmpdir, "output")
+		if err := cmd.Run(ctx, filepath.Join(docdir, "input.pdf"), output); err != nil {
+			return err
+		}
+
+		return os.Rename(output, outdir)
+	})
+}
@@ -0,0 +1,187 @@
+package pdftohtml
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newStubCommand returns the command running the `pdftohtml` stub writing the
+// first page of the range, with the `pdfinfo` stub reporting the page count.
+func newStubCommand(t *testing.T, pages int) *Command {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not executable on Windows")
+	}
+
+	bin := t.TempDir()
+	writeScript(t, filepath.Join(bin, "pdfinfo"), "echo 'Pages:          "+strconv.Itoa(pages)+"'\n")
+	writeScript(t, filepath.Join(bin, "pdftohtml"), `first=1
+while [ $# -gt 0 ]; do
+	case "$1" in
+	-f) first=$2; shift 2 ;;
+	--) shift; break ;;
+	*) shift ;;
+	esac
+done
+mkdir -p "$2" && echo "page $first" > "$2/page$first.html"
+`)
+
+	cmd, err := NewCommand(WithCustomPath(filepath.Join(bin, "pdftohtml")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cmd
+}
+
+func TestLazyHandler(t *testing.T) {
+	cmd := newStubCommand(t, 3)
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.pdf"), []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := t.TempDir()
+	h, err := NewLazyHandler(http.Dir(root), cmd, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		target   string
+		status   int
+		location string
+		body     string
+	}{
+		{"/a.pdf", http.StatusMovedPermanently, "/a.pdf/", ""},
+		{"/a.pdf/", http.StatusOK, "", `<a href="3/page3.html">Page 3</a>`},
+		{"/a.pdf/2", http.StatusFound, "/a.pdf/2/page2.html", ""},
+		{"/a.pdf/2/", http.StatusFound, "/a.pdf/2/page2.html", ""},
+		{"/a.pdf/2/page2.html", http.StatusOK, "", "page 2"},
+		{"/a.pdf/4/page4.html", http.StatusNotFound, "", ""},
+		{"/a.pdf/x/page1.html", http.StatusNotFound, "", ""},
+		{"/b.pdf/", http.StatusNotFound, "", ""},
+	}
+
+	for _, tt := range tests {
+		resp, body := serve(h, tt.target)
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: got %d, want %d", tt.target, resp.StatusCode, tt.status)
+		}
+		if got := resp.Header.Get("Location"); got != tt.location {
+			t.Errorf("%s: got location %q, want %q", tt.target, got, tt.location)
+		}
+		if !strings.Contains(body, tt.body) {
+			t.Errorf("%s: got %q, want %q in it", tt.target, body, tt.body)
+		}
+	}
+
+	// only the requested page is converted
+	converted, err := filepath.Glob(filepath.Join(cache, "*", "*", "page*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(converted) != 1 || filepath.Base(converted[0]) != "page2.html" {
+		t.Errorf("converted %q, want page 2 only", converted)
+	}
+}
+
+func TestLazyHandlerWarm(t *testing.T) {
+	cmd := newStubCommand(t, 3)
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.pdf"), []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := t.TempDir()
+	h, err := NewLazyHandler(http.Dir(root), cmd, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.Warm(context.Background(), "a.pdf", 4); err == nil {
+		t.Error("page out of range warmed")
+	}
+	if err := h.Warm(context.Background(), "a.pdf"); err != nil {
+		t.Fatal(err)
+	}
+
+	converted, err := filepath.Glob(filepath.Join(cache, "*", "*", "page*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(converted) != 3 {
+		t.Errorf("converted %q, want all 3 pages", converted)
+	}
+}
+
+func TestLazyHandlerOutputs(t *testing.T) {
+	cmd := newStubCommand(t, 3)
+
+	root := t.TempDir()
+	pdf := filepath.Join(root, "a.pdf")
+	if err := os.WriteFile(pdf, []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewLazyHandler(http.Dir(root), cmd, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := func() int {
+		n := 0
+		h.outputs.Range(func(_, _ any) bool {
+			n++
+			return true
+		})
+		return n
+	}
+
+	serve(h, "/a.pdf/1/page1.html")
+	serve(h, "/a.pdf/2/page2.html")
+
+	// changed file replaces the handlers of its pages
+	if err := os.WriteFile(pdf, []byte("%PDF-1.7"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(time.Hour)
+	if err := os.Chtimes(pdf, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	serve(h, "/a.pdf/1/page1.html")
+
+	v, ok := h.outputs.Load("/a.pdf")
+	if !ok || count() != 1 {
+		t.Fatalf("got %d documents, want the changed one", count())
+	}
+	pages := 0
+	v.(*lazyOutputs).pages.Range(func(_, _ any) bool {
+		pages++
+		return true
+	})
+	if pages != 1 {
+		t.Errorf("got %d page handlers, want 1", pages)
+	}
+
+	// removed file drops them
+	if err := os.Remove(pdf); err != nil {
+		t.Fatal(err)
+	}
+	if resp, _ := serve(h, "/a.pdf/1/page1.html"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("got %d, want not found", resp.StatusCode)
+	}
+	if count() != 0 {
+		t.Errorf("got %d documents, want none", count())
+	}
+}
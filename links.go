@@ -0,0 +1,171 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- internal links
+// ----------------------------------------------------------------------------
+
+// pageLink is the go-to link annotation of the page, in pixels of the generated
+// HTML.
+type pageLink struct {
+	page       int
+	x, y, w, h float64
+	target     int
+	top        float64
+	hasTop     bool
+}
+
+// internalLinks finds the link annotations of the PDF file going to its pages.
+func (c *Command) internalLinks(inpath string) ([]pageLink, error) {
+	r, err := openPDFFile(inpath)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := r.pages()
+	sx, sy := c.pixelScale()
+
+	var links []pageLink
+	for i, page := range pages {
+		for _, obj := range r.array(page.dict["Annots"]) {
+			annot := r.dict(obj)
+			if r.resolve(annot["Subtype"]) != pdfName("Link") {
+				continue
+			}
+
+			dest := annot["Dest"]
+			if dest == nil {
+				if action := r.dict(annot["A"]); r.resolve(action["S"]) == pdfName("GoTo") {
+					dest = action
+				}
+			}
+			d, ok := r.destination(dest, pages)
+			if !ok {
+				continue
+			}
+
+			rect, ok := r.rect(annot["Rect"])
+			if !ok {
+				continue
+			}
+
+			link := pageLink{
+				page:   i + 1,
+				x:      (rect[0] - page.cropBox[0]) * sx,
+				y:      (page.cropBox[3] - rect[3]) * sy,
+				w:      (rect[2] - rect[0]) * sx,
+				h:      (rect[3] - rect[1]) * sy,
+				target: d.page + 1,
+			}
+			if d.hasTop {
+				link.top, link.hasTop = max(0, (pages[d.page].cropBox[3]-d.top)*sy), true
+			}
+			links = append(links, link)
+		}
+	}
+
+	return links, nil
+}
+
+// insertLinks adds the links, and the anchors of their targets, to the pages in
+// the outdir. Links from or to missing pages are skipped.
+func insertLinks(outdir string, links []pageLink) error {
+	exists := func(n int) bool {
+		_, err := os.Stat(filepath.Join(outdir, fmt.Sprintf("page%d.html", n)))
+		return err == nil
+	}
+
+	markup := make(map[int]*strings.Builder)
+	builder := func(n int) *strings.Builder {
+		if markup[n] == nil {
+			markup[n] = &strings.Builder{}
+		}
+		return markup[n]
+	}
+
+	anchors := make(map[int]map[int]bool)
+	for _, link := range links {
+		if !exists(link.page) || !exists(link.target) {
+			continue
+		}
+
+		href := fmt.Sprintf("page%d.html", link.target)
+		if link.hasTop {
+			top := int(math.Round(link.top))
+			href += fmt.Sprintf("#link-%d", top)
+
+			if anchors[link.target] == nil {
+				anchors[link.target] = make(map[int]bool)
+			}
+			anchors[link.target][top] = true
+		}
+
+		fmt.Fprintf(builder(link.page), "<a class=\"pdftohtml-link\" style=\"position:absolute; left:%spx; top:%spx; width:%spx; height:%spx;\" href=\"%s\" aria-label=\"Go to page %d\"></a>\n",
+			formatPixels(link.x), formatPixels(link.y), formatPixels(link.w), formatPixels(link.h), href, link.target)
+	}
+
+	for n, tops := range anchors {
+		list := make([]int, 0, len(tops))
+		for top := range tops {
+			list = append(list, top)
+		}
+		sort.Ints(list)
+
+		for _, top := range list {
+			fmt.Fprintf(builder(n), "<a id=\"link-%d\" style=\"position:absolute; left:0px; top:%dpx;\"></a>\n", top, top)
+		}
+	}
+
+	for n, b := range markup {
+		path := filepath.Join(outdir, fmt.Sprintf("page%d.html", n))
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(path, insertBodyEnd(data, []byte(b.String())), 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+// -- internal links options
+// ----------------------------------------------------------------------------
+
+// Rewrite the internal links of the PDF file, e.g. cross references and tables
+// of contents, which `pdftohtml` drops, as links to the generated pages. Links
+// are placed over their areas in the pages, and jump to the top of the view of
+// the destination through `link-<top>` anchors.
+//
+// Only link annotations with go-to destinations are rewritten, references left
+// as plain text are not detected. Links are read by the package itself, links
+// of encrypted files are not rewritten.
+func WithInternalLinks() option {
+	return func(c *Command) {
+		c.stages = append(c.stages, func(_ context.Context, inpath, outdir string) error {
+			links, err := c.internalLinks(inpath)
+			if errors.Is(err, ErrUnsupportedPDF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			return insertLinks(outdir, links)
+		})
+	}
+}
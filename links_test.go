@@ -0,0 +1,76 @@
+package pdftohtml
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInternalLinks(t *testing.T) {
+	dir := t.TempDir()
+	inpath := filepath.Join(dir, "in.pdf")
+	data := pdfFile("<< /Size 6 /Root 1 0 R >>",
+		"<< /Type /Catalog /Pages 2 0 R /Names << /Dests << /Names [(intro) [4 0 R /XYZ 0 692 0]] >> >> >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /MediaBox [0 0 612 792] >>",
+		"<< /Type /Page /Parent 2 0 R /Annots [5 0 R << /Subtype /Link /Rect [0 0 10 10] /A << /S /URI /URI (http://example.com) >> >>] >>",
+		"<< /Type /Page /Parent 2 0 R /Annots [<< /Subtype /Link /Rect [100 700 200 720] /Dest [3 0 R /Fit] >>] >>",
+		"<< /Subtype /Link /Rect [72 692 172 712] /A << /S /GoTo /D (intro) >> >>",
+	)
+	if err := os.WriteFile(inpath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	links, err := (&Command{}).internalLinks(inpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []pageLink{
+		{page: 1, x: 72, y: 80, w: 100, h: 20, target: 2, top: 100, hasTop: true},
+		{page: 2, x: 100, y: 72, w: 100, h: 20, target: 1},
+	}
+	if len(links) != len(want) {
+		t.Fatalf("got %+v, want %+v", links, want)
+	}
+	for i := range want {
+		if links[i] != want[i] {
+			t.Errorf("link %d: got %+v, want %+v", i, links[i], want[i])
+		}
+	}
+
+	outdir := filepath.Join(dir, "out")
+	if err := os.Mkdir(outdir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"page1.html", "page2.html"} {
+		if err := os.WriteFile(filepath.Join(outdir, name), []byte("<html><body>\n</body></html>"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// links to pages not converted are skipped
+	links = append(links, pageLink{page: 1, target: 3})
+
+	if err := insertLinks(outdir, links); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, wants := range map[string][]string{
+		"page1.html": {`href="page2.html#link-100"`},
+		"page2.html": {`href="page1.html"`, `<a id="link-100"`},
+	} {
+		data, err := os.ReadFile(filepath.Join(outdir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range wants {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("%s: %s does not contain %s", name, data, want)
+			}
+		}
+		if strings.Contains(string(data), "page3.html") {
+			t.Errorf("%s: link to missing page: %s", name, data)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ----------------------------------------------------------------------------
+// -- low-color backgrounds
+// ----------------------------------------------------------------------------
+
+// reBackgroundFile matches names of the background images of the pages, also
+// of their extra renditions.
+var reBackgroundFile = regexp.MustCompile(`^page\d+(?:@[0-9.]+x|-[a-z][a-z0-9]*)?\.png$`)
+
+// reEmbeddedPNG matches the background image embedded as data URI.
+var reEmbeddedPNG = regexp.MustCompile(`(\ssrc\s*=\s*"data:image/png;base64,)([^"]*)"`)
+
+// grayPalette returns the palette of evenly spaced gray levels.
+func grayPalette(levels int) color.Palette {
+	p := make(color.Palette, levels)
+	for i := range p {
+		p[i] = color.Gray{Y: uint8(i * 255 / (levels - 1))}
+	}
+
+	return p
+}
+
+// reduceColors converts the image to the palette, with Floyd-Steinberg error
+// diffusion if dithered. Without the palette the image is converted to 8-bit
+// grayscale.
+func reduceColors(img image.Image, p color.Palette, dither bool) image.Image {
+	bounds := img.Bounds()
+
+	if p == nil {
+		dst := image.NewGray(bounds)
+		draw.Draw(dst, bounds, img, bounds.Min, draw.Src)
+		return dst
+	}
+
+	dst := image.NewPaletted(bounds, p)
+	if dither {
+		draw.FloydSteinberg.Draw(dst, bounds, img, bounds.Min)
+	} else {
+		draw.Draw(dst, bounds, img, bounds.Min, draw.Src)
+	}
+
+	return dst
+}
+
+// reducePNG re-encodes the PNG image with the reduced colors.
+func reducePNG(data []byte, p color.Palette, dither bool) ([]byte, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := enc.Encode(&buf, reduceColors(img, p, dither)); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// reduceBackgrounds creates the stage converting the background images of the
+// pages, stored in the outdir or embedded, to the palette.
+func reduceBackgrounds(p color.Palette, dither bool) stage {
+	return func(_ context.Context, _, outdir string) error {
+		entries, err := os.ReadDir(outdir)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() || !reBackgroundFile.MatchString(entry.Name()) {
+				continue
+			}
+
+			path := filepath.Join(outdir, entry.Name())
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			if data, err = reducePNG(data, p, dither); err != nil {
+				return err
+			}
+
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				return err
+			}
+		}
+
+		return rewriteHTML(outdir, func(name string, data []byte) ([]byte, error) {
+			if pageNumber(name) == 0 {
+				return data, nil
+			}
+
+			var err error
+			data = reBackground.ReplaceAllFunc(data, func(tag []byte) []byte {
+				return reEmbeddedPNG.ReplaceAllFunc(tag, func(attr []byte) []byte {
+					match := reEmbeddedPNG.FindSubmatch(attr)
+
+					img, derr := base64.StdEncoding.DecodeString(string(match[2]))
+					if derr == nil {
+						img, derr = reducePNG(img, p, dither)
+					}
+					if derr != nil {
+						err = derr
+						return attr
+					}
+
+					return []byte(string(match[1]) + base64.StdEncoding.EncodeToString(img) + `"`)
+				})
+			})
+
+			return data, err
+		})
+	}
+}
+
+// ----------------------------------------------------------------------------
+// -- low-color backgrounds options
+// ----------------------------------------------------------------------------
+
+// Convert the background images to grayscale, for archival where color is
+// irrelevant and storage is the constraint.
+//
+// The levels are the number of gray levels, between 2 (bilevel) and 256 (8-bit
+// grayscale). Fewer levels give smaller files, and dithering preserves shading
+// with them.
+func WithGrayscaleBackgrounds(levels int, dither bool) option {
+	return func(c *Command) {
+		if !c.checkRange(RangeError{Option: "gray levels", Value: float64(levels), Min: 2, Max: 256}) {
+			return
+		}
+
+		var p color.Palette
+		if levels < 256 || dither {
+			p = grayPalette(levels)
+		}
+
+		c.stages = append(c.stages, reduceBackgrounds(p, dither))
+	}
+}
+
+// Convert the background images to the 216 colors of the web-safe palette, with
+// optional dithering, for archival where accurate color is irrelevant and
+// storage is the constraint.
+func WithWebSafeBackgrounds(dither bool) option {
+	return func(c *Command) {
+		c.stages = append(c.stages, reduceBackgrounds(palette.WebSafe, dither))
+	}
+}
@@ -0,0 +1,202 @@
+package pdftohtml
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- manifest
+// ----------------------------------------------------------------------------
+
+// ManifestName is the name of the manifest file in the outdir.
+const ManifestName = "manifest.json"
+
+// Manifest describes the conversion output in the outdir.
+type Manifest struct {
+	Source  string `json:"source"`
+	Hash    string `json:"hash,omitempty"`    // SHA-256 of the input
+	Options string `json:"options,omitempty"` // SHA-256 of the arguments
+	// PageHashes are SHA-256 hashes of the content of the pages of the input, in
+	// page order, recorded by `WithIncremental`.
+	PageHashes []string       `json:"page-hashes,omitempty"`
+	Created    time.Time      `json:"created"`
+	Partial    bool           `json:"partial,omitempty"`
+	Pages      []ManifestPage `json:"pages"`
+	Files      []ManifestFile `json:"files"`
+
+	// Attachments are files embedded in the PDF file, extracted by `WithAttachments`.
+	Attachments []string `json:"attachments,omitempty"`
+	// Media are sounds and movies of the PDF file, extracted by `WithMedia`.
+	Media []string `json:"media,omitempty"`
+	// Warnings are problems of the PDF file the conversion went on despite, e.g.
+	// the XFA form with `WithXFAPolicy`.
+	Warnings []string `json:"warnings,omitempty"`
+	// Signatures are the digital signatures of the PDF file, detected by
+	// `WithSignatures`.
+	Signatures *Signatures `json:"signatures,omitempty"`
+	// Conformance is the conformance the PDF file claims, detected by
+	// `WithConformance`.
+	Conformance *Conformance `json:"conformance,omitempty"`
+}
+
+// ManifestPage describes a single converted page.
+type ManifestPage struct {
+	Number int    `json:"number"`
+	File   string `json:"file,omitempty"` // empty if the page was skipped
+	Blank  bool   `json:"blank,omitempty"`
+	// Rotation is the clockwise rotation, in degrees, applied to make the page
+	// upright, recorded by `WithUprightPages`.
+	Rotation int `json:"rotation,omitempty"`
+}
+
+// ManifestFile describes a single file in the outdir.
+type ManifestFile struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Hash     string    `json:"hash"` // SHA-256 of the content
+	Modified time.Time `json:"modified"`
+}
+
+// ETag returns the strong entity tag of the file for HTTP caching.
+func (f ManifestFile) ETag() string {
+	return `"` + f.Hash + `"`
+}
+
+// newManifest creates the manifest describing the current content of the outdir.
+func newManifest(inpath, outdir string) (*Manifest, error) {
+	m := &Manifest{Source: filepath.Base(inpath), Created: time.Now().UTC()}
+
+	err := filepath.WalkDir(outdir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+
+		name, err := filepath.Rel(outdir, path)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+
+		if name == ManifestName {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hash, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		m.Files = append(m.Files, ManifestFile{Name: name, Size: info.Size(), Hash: hash, Modified: info.ModTime().UTC()})
+
+		if n := pageNumber(name); n > 0 {
+			m.Pages = append(m.Pages, ManifestPage{Number: n, File: name})
+		}
+		if strings.HasPrefix(name, AttachmentsDir+"/") {
+			m.Attachments = append(m.Attachments, name)
+		}
+		if strings.HasPrefix(name, MediaDir+"/") {
+			m.Media = append(m.Media, name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(m.Pages, func(i, j int) bool {
+		return m.Pages[i].Number < m.Pages[j].Number
+	})
+
+	return m, nil
+}
+
+// markBlank marks the pages as blank, adding pages skipped in the output.
+func (m *Manifest) markBlank(pages []int) {
+	for _, n := range pages {
+		i := sort.Search(len(m.Pages), func(i int) bool {
+			return m.Pages[i].Number >= n
+		})
+
+		if i < len(m.Pages) && m.Pages[i].Number == n {
+			m.Pages[i].Blank = true
+			continue
+		}
+
+		m.Pages = slices.Insert(m.Pages, i, ManifestPage{Number: n, Blank: true})
+	}
+}
+
+// runRecord collects what the stages find out about the PDF file during the run,
+// for the manifest.
+type runRecord struct {
+	rotations   map[int]int
+	reconvert   pageSelection // pages converted again by `WithIncremental`
+	signatures  *Signatures
+	conformance *Conformance
+	timings     ReportTimings
+}
+
+type recordKey struct{}
+
+// recordFrom returns the record of the run in the context, or nil.
+func recordFrom(ctx context.Context) *runRecord {
+	record, _ := ctx.Value(recordKey{}).(*runRecord)
+
+	return record
+}
+
+// markRotated records the rotations of the pages.
+func (m *Manifest) markRotated(rotations map[int]int) {
+	for i, page := range m.Pages {
+		m.Pages[i].Rotation = rotations[page.Number]
+	}
+}
+
+// write writes the manifest into the outdir.
+func (m *Manifest) write(outdir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(outdir, ManifestName), data, 0o644)
+}
+
+// ReadManifest reads the manifest from the outdir.
+func ReadManifest(outdir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(outdir, ManifestName))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// ----------------------------------------------------------------------------
+// -- manifest options
+// ----------------------------------------------------------------------------
+
+// Write a `manifest.json` describing the converted pages and all generated files
+// into the outdir.
+func WithManifest() option {
+	return func(c *Command) {
+		c.manifest = true
+	}
+}
@@ -0,0 +1,475 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"html"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- markup parsing
+// ----------------------------------------------------------------------------
+
+// The parser below is deliberately small: it understands the markup the Xpdf
+// and Poppler tools generate, and recovers from common tag soup, but it is not
+// the complete HTML5 parsing algorithm.
+
+type nodeKind int
+
+const (
+	documentNode nodeKind = iota
+	elementNode
+	textNode
+	commentNode
+	doctypeNode
+)
+
+// htmlAttr is a single attribute with the unescaped value.
+type htmlAttr struct {
+	Key, Val string
+}
+
+// htmlNode is a node of the parsed document.
+type htmlNode struct {
+	kind     nodeKind
+	tag      string     // lowercase tag name of the element
+	attrs    []htmlAttr // attributes of the element
+	text     string     // unescaped text, comment or doctype
+	raw      bool       // text of the script or style element, never escaped
+	parent   *htmlNode
+	children []*htmlNode
+}
+
+// attr returns the value of the attribute of the element.
+func (n *htmlNode) attr(key string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+
+	return "", false
+}
+
+// setAttr sets the value of the attribute of the element.
+func (n *htmlNode) setAttr(key, val string) {
+	for i, a := range n.attrs {
+		if a.Key == key {
+			n.attrs[i].Val = val
+			return
+		}
+	}
+
+	n.attrs = append(n.attrs, htmlAttr{Key: key, Val: val})
+}
+
+// removeAttr removes the attribute of the element.
+func (n *htmlNode) removeAttr(key string) {
+	attrs := n.attrs[:0]
+	for _, a := range n.attrs {
+		if a.Key != key {
+			attrs = append(attrs, a)
+		}
+	}
+	n.attrs = attrs
+}
+
+// appendChild appends the node to the children of n.
+func (n *htmlNode) appendChild(child *htmlNode) {
+	child.parent = n
+	n.children = append(n.children, child)
+}
+
+// walk calls fn for n and all its descendants in document order, skipping the
+// children of nodes fn returns false for.
+func (n *htmlNode) walk(fn func(*htmlNode) bool) {
+	if !fn(n) {
+		return
+	}
+
+	for _, child := range n.children {
+		child.walk(fn)
+	}
+}
+
+// removeDoctype removes the doctype and whitespace around it from the document,
+// so it can be replaced with own prolog.
+func (n *htmlNode) removeDoctype() {
+	children := n.children[:0]
+	for _, child := range n.children {
+		if child.kind != doctypeNode && (child.kind != textNode || strings.TrimSpace(child.text) != "") {
+			children = append(children, child)
+		}
+	}
+	n.children = children
+}
+
+// find returns the first element with the tag, or nil.
+func (n *htmlNode) find(tag string) *htmlNode {
+	var found *htmlNode
+	n.walk(func(m *htmlNode) bool {
+		if found == nil && m.kind == elementNode && m.tag == tag {
+			found = m
+		}
+
+		return found == nil
+	})
+
+	return found
+}
+
+// textContent returns the concatenated text of the descendants.
+func (n *htmlNode) textContent() string {
+	var b strings.Builder
+	n.walk(func(m *htmlNode) bool {
+		if m.kind == textNode && !m.raw {
+			b.WriteString(m.text)
+		}
+
+		return true
+	})
+
+	return b.String()
+}
+
+// voidElements never have content nor end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true,
+	"img": true, "input": true, "link": true, "meta": true, "source": true,
+	"track": true, "wbr": true,
+}
+
+// booleanAttrs are attributes whose presence alone is their value.
+var booleanAttrs = map[string]bool{
+	"async": true, "autofocus": true, "checked": true, "defer": true, "disabled": true,
+	"hidden": true, "multiple": true, "readonly": true, "required": true, "selected": true,
+}
+
+// rawTextElements have content that is not markup.
+var rawTextElements = map[string]bool{"script": true, "style": true, "title": true, "textarea": true}
+
+// impliedEnd maps elements to the open elements their start tag closes.
+var impliedEnd = map[string][]string{
+	"p":      {"p"},
+	"div":    {"p"},
+	"ul":     {"p"},
+	"ol":     {"p"},
+	"table":  {"p"},
+	"li":     {"li"},
+	"dt":     {"dt", "dd"},
+	"dd":     {"dt", "dd"},
+	"tr":     {"tr", "td", "th"},
+	"td":     {"td", "th"},
+	"th":     {"td", "th"},
+	"option": {"option"},
+}
+
+// htmlMaxDepth is the deepest nesting of elements kept by the parser, so walking
+// and rendering the tree of hostile markup does not exhaust the stack.
+const htmlMaxDepth = 512
+
+// parseHTML parses the markup into the document tree.
+func parseHTML(data []byte) *htmlNode {
+	doc := &htmlNode{kind: documentNode}
+	open := []*htmlNode{doc}
+	top := func() *htmlNode { return open[len(open)-1] }
+
+	// closeTo pops open elements up to and including the topmost element with
+	// the tag, if there is one
+	closeTo := func(tag string) bool {
+		for i := len(open) - 1; i > 0; i-- {
+			if open[i].tag == tag {
+				open = open[:i]
+				return true
+			}
+		}
+
+		return false
+	}
+
+	s := string(data)
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '<')
+		if i != 0 {
+			if i < 0 {
+				i = len(s)
+			}
+			top().appendChild(&htmlNode{kind: textNode, text: html.UnescapeString(s[:i])})
+			s = s[i:]
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			end := strings.Index(s[4:], "-->")
+			if end < 0 {
+				end = len(s) - 4
+			}
+			top().appendChild(&htmlNode{kind: commentNode, text: s[4 : 4+end]})
+			s = s[min(len(s), 4+end+3):]
+
+		case strings.HasPrefix(s, "<!") || strings.HasPrefix(s, "<?"):
+			// truncated declaration runs to the end
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				end = len(s)
+			}
+			if len(s) >= 9 && strings.EqualFold(s[:9], "<!doctype") {
+				top().appendChild(&htmlNode{kind: doctypeNode, text: strings.TrimSpace(s[9:end])})
+			}
+			s = s[min(len(s), end+1):]
+
+		case strings.HasPrefix(s, "</"):
+			tag, rest, ok := parseEndTag(s)
+			if !ok {
+				top().appendChild(&htmlNode{kind: textNode, text: "<"})
+				s = s[1:]
+				continue
+			}
+			closeTo(tag)
+			s = rest
+
+		default:
+			node, selfClosing, rest, ok := parseStartTag(s)
+			if !ok {
+				top().appendChild(&htmlNode{kind: textNode, text: "<"})
+				s = s[1:]
+				continue
+			}
+			s = rest
+
+			for _, tag := range impliedEnd[node.tag] {
+				if top().tag == tag {
+					closeTo(tag)
+				}
+			}
+			top().appendChild(node)
+
+			switch {
+			case voidElements[node.tag] || selfClosing:
+			case rawTextElements[node.tag]:
+				end := indexEndTag(s, node.tag)
+				if end < 0 {
+					end = len(s)
+				}
+				text := &htmlNode{kind: textNode, text: s[:end]}
+				if node.tag == "script" || node.tag == "style" {
+					text.raw = true
+				} else {
+					text.text = html.UnescapeString(text.text)
+				}
+				if text.text != "" {
+					node.appendChild(text)
+				}
+
+				s = s[end:]
+				if _, rest, ok := parseEndTag(s); ok {
+					s = rest
+				}
+			case len(open) > htmlMaxDepth:
+				// children of too deeply nested elements become its siblings
+			default:
+				open = append(open, node)
+			}
+		}
+	}
+
+	return doc
+}
+
+// indexEndTag returns the index of the first end tag of the element in s, or
+// -1. Tag names are compared case-insensitively.
+func indexEndTag(s, tag string) int {
+	for i := 0; ; {
+		j := strings.Index(s[i:], "</")
+		if j < 0 {
+			return -1
+		}
+		i += j
+
+		name := s[i+2:]
+		if len(name) >= len(tag) && strings.EqualFold(name[:len(tag)], tag) && (len(name) == len(tag) || !isNameByte(name[len(tag)])) {
+			return i
+		}
+		i += 2
+	}
+}
+
+// isNameByte reports whether the byte may be part of a tag or attribute name.
+func isNameByte(b byte) bool {
+	return b != ' ' && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != '/' && b != '>' && b != '='
+}
+
+// parseEndTag parses the end tag at the beginning of s.
+func parseEndTag(s string) (tag, rest string, ok bool) {
+	i := 2
+	for i < len(s) && isNameByte(s[i]) {
+		i++
+	}
+	if i == 2 {
+		return "", s, false
+	}
+
+	end := strings.IndexByte(s[i:], '>')
+	if end < 0 {
+		return strings.ToLower(s[2:i]), "", true
+	}
+
+	return strings.ToLower(s[2:i]), s[i+end+1:], true
+}
+
+// parseStartTag parses the start tag with its attributes at the beginning of s.
+func parseStartTag(s string) (node *htmlNode, selfClosing bool, rest string, ok bool) {
+	i := 1
+	for i < len(s) && isNameByte(s[i]) {
+		i++
+	}
+	if i == 1 || !isLetter(s[1]) {
+		return nil, false, s, false
+	}
+
+	node = &htmlNode{kind: elementNode, tag: strings.ToLower(s[1:i])}
+
+	for i < len(s) {
+		switch c := s[i]; {
+		case c == '>':
+			return node, selfClosing, s[i+1:], true
+		case c == '/':
+			selfClosing = true
+			i++
+			continue
+		case !isNameByte(c):
+			i++
+			continue
+		}
+		selfClosing = false
+
+		start := i
+		for i < len(s) && isNameByte(s[i]) {
+			i++
+		}
+		attr := htmlAttr{Key: strings.ToLower(s[start:i])}
+
+		j := skipSpace(s, i)
+		if j < len(s) && s[j] == '=' {
+			j = skipSpace(s, j+1)
+			var val string
+			val, i = parseAttrValue(s, j)
+			attr.Val = html.UnescapeString(val)
+		}
+
+		if _, exists := node.attr(attr.Key); !exists {
+			node.attrs = append(node.attrs, attr)
+		}
+	}
+
+	return node, selfClosing, "", true
+}
+
+// parseAttrValue parses the quoted or unquoted attribute value at the index.
+func parseAttrValue(s string, i int) (string, int) {
+	if i >= len(s) {
+		return "", i
+	}
+
+	if q := s[i]; q == '"' || q == '\'' {
+		end := strings.IndexByte(s[i+1:], q)
+		if end < 0 {
+			return s[i+1:], len(s)
+		}
+
+		return s[i+1 : i+1+end], i + end + 2
+	}
+
+	start := i
+	for i < len(s) && s[i] != '>' && s[i] != ' ' && s[i] != '\t' && s[i] != '\n' && s[i] != '\r' {
+		i++
+	}
+
+	return s[start:i], i
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r' || s[i] == '\f') {
+		i++
+	}
+
+	return i
+}
+
+func isLetter(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+// ----------------------------------------------------------------------------
+// -- markup rendering
+// ----------------------------------------------------------------------------
+
+// renderHTML serializes the document as HTML, or as XHTML with void elements
+// self-closed, boolean attributes expanded and script and style content hidden
+// from the XML parser.
+func renderHTML(doc *htmlNode, xhtml bool) []byte {
+	var buf bytes.Buffer
+	renderNode(&buf, doc, xhtml)
+
+	return buf.Bytes()
+}
+
+// xmlEscaper escapes text and attribute values so they are valid in both HTML
+// and XML.
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+func renderNode(buf *bytes.Buffer, n *htmlNode, xhtml bool) {
+	switch n.kind {
+	case documentNode:
+		for _, child := range n.children {
+			renderNode(buf, child, xhtml)
+		}
+
+	case doctypeNode:
+		buf.WriteString("<!DOCTYPE " + n.text + ">")
+
+	case commentNode:
+		buf.WriteString("<!--" + strings.ReplaceAll(n.text, "--", "- -") + "-->")
+
+	case textNode:
+		switch {
+		case !n.raw:
+			buf.WriteString(xmlEscaper.Replace(n.text))
+		case xhtml && strings.ContainsAny(n.text, "<&"):
+			buf.WriteString("/*<![CDATA[*/" + strings.ReplaceAll(n.text, "]]>", "]]]]><![CDATA[>") + "/*]]>*/")
+		default:
+			buf.WriteString(n.text)
+		}
+
+	case elementNode:
+		buf.WriteString("<" + n.tag)
+		for _, a := range n.attrs {
+			val := a.Val
+			switch {
+			case xhtml && val == "" && booleanAttrs[a.Key]:
+				val = a.Key
+			case !xhtml && val == "":
+				// empty value is the same as no value in HTML
+				buf.WriteString(" " + a.Key)
+				continue
+			}
+			buf.WriteString(" " + a.Key + `="` + xmlEscaper.Replace(val) + `"`)
+		}
+
+		if voidElements[n.tag] {
+			if xhtml {
+				buf.WriteString(" />")
+			} else {
+				buf.WriteString(">")
+			}
+			return
+		}
+
+		buf.WriteString(">")
+		for _, child := range n.children {
+			renderNode(buf, child, xhtml)
+		}
+		buf.WriteString("</" + n.tag + ">")
+	}
+}
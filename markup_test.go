@@ -0,0 +1,163 @@
+package pdftohtml
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHTMLRoundTrip(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{
+			name: "elements and attributes",
+			in:   `<div class="txt" style="left:1px"><span>a &amp; b</span></div>`,
+			want: `<div class="txt" style="left:1px"><span>a &amp; b</span></div>`,
+		},
+		{
+			name: "void and boolean",
+			in:   `<p><br><input checked></p>`,
+			want: `<p><br><input checked></p>`,
+		},
+		{
+			name: "script is raw text",
+			in:   `<script>if (a < b && c) { x = "</div>"; }</script>`,
+			want: `<script>if (a < b && c) { x = "</div>"; }</script>`,
+		},
+		{
+			name: "style is raw text",
+			in:   `<style>p > span { color: red }</style>`,
+			want: `<style>p > span { color: red }</style>`,
+		},
+		{
+			name: "title is escapable raw text",
+			in:   `<title>a &lt; <b></title>`,
+			want: `<title>a &lt; &lt;b&gt;</title>`,
+		},
+		{
+			name: "comment",
+			in:   `<!-- note --><p>x</p>`,
+			want: `<!-- note --><p>x</p>`,
+		},
+		{
+			name: "comment with double dash",
+			in:   `<!-- a -- b -->`,
+			want: `<!-- a - - b -->`,
+		},
+		{
+			name: "doctype",
+			in:   `<!DOCTYPE html><html></html>`,
+			want: `<!DOCTYPE html><html></html>`,
+		},
+		{
+			name: "implied end",
+			in:   `<ul><li>a<li>b</ul>`,
+			want: `<ul><li>a</li><li>b</li></ul>`,
+		},
+		{
+			name: "unclosed elements",
+			in:   `<div><span>a`,
+			want: `<div><span>a</span></div>`,
+		},
+		{
+			name: "raw text end tag case",
+			in:   `<SCRIPT>a</scRipt><p>x</p>`,
+			want: `<script>a</script><p>x</p>`,
+		},
+		{
+			name: "raw text end tag prefix",
+			in:   `<title>a</titles></title>`,
+			want: `<title>a&lt;/titles&gt;</title>`,
+		},
+		{
+			name: "raw text after non-ASCII",
+			in:   `<p>İİİ</p><title>t</title><p>x</p>`,
+			want: `<p>İİİ</p><title>t</title><p>x</p>`,
+		},
+		{
+			name: "stray less than",
+			in:   `a < b`,
+			want: `a &lt; b`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(renderHTML(parseHTML([]byte(tt.in)), false))
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHTMLTruncated(t *testing.T) {
+	tests := []string{
+		"<!doctype",
+		"<!DOCTYPE html",
+		"<!",
+		"<?xml",
+		"<!--",
+		"<!-- comment",
+		"<div",
+		`<div class="a`,
+		"<div class=",
+		"</div",
+		"</",
+		"<script>var a",
+		"<script>var a</scr",
+		"<",
+	}
+
+	for _, in := range tests {
+		t.Run(in, func(t *testing.T) {
+			doc := parseHTML([]byte(in))
+			renderHTML(doc, false)
+			renderHTML(doc, true)
+		})
+	}
+}
+
+func TestParseHTMLHostile(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"raw text elements", strings.Repeat("<title>t</title>", 1<<15)},
+		{"unclosed raw text elements", strings.Repeat("<title></x>", 1<<15)},
+		{"deep nesting", strings.Repeat("<div>", 1<<20)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+
+			doc := parseHTML([]byte(tt.in))
+			renderHTML(doc, false)
+
+			depth := 0
+			for n := doc; len(n.children) > 0; n = n.children[len(n.children)-1] {
+				depth++
+			}
+			if depth > htmlMaxDepth+1 {
+				t.Errorf("depth %d, want at most %d", depth, htmlMaxDepth+1)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("took %v", elapsed)
+			}
+		})
+	}
+}
+
+func FuzzParseHTML(f *testing.F) {
+	f.Add([]byte(`<!DOCTYPE html><html><head><title>t</title></head><body><div class="txt">a</div></body></html>`))
+	f.Add([]byte(`<script>x</script><!-- c --><p a=b c='d' e>`))
+	f.Add([]byte("<!doctype"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		doc := parseHTML(data)
+		renderHTML(doc, false)
+		renderHTML(doc, true)
+	})
+}
@@ -0,0 +1,357 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- embedded media
+// ----------------------------------------------------------------------------
+
+// MediaDir is the directory in the outdir embedded media are extracted to.
+const MediaDir = "media"
+
+// MediaItem is a sound, movie or rich media annotation of the page, dropped by
+// `pdftohtml`.
+type MediaItem struct {
+	Page int
+	Kind string // "audio", "video", or "other" for e.g. Flash content
+	File string // path in the outdir of the extracted media, if embedded
+	URL  string // location of the external media, if not embedded
+	// Box of the annotation, in pixels of the generated HTML.
+	X, Y, W, H float64
+}
+
+// mediaTypes guesses the kind of media by the extension, if the content type
+// is not given.
+var mediaTypes = map[string]string{
+	".mp3": "audio", ".wav": "audio", ".aif": "audio", ".aiff": "audio", ".ogg": "audio",
+	".m4a": "audio", ".aac": "audio", ".flac": "audio", ".mp4": "video", ".m4v": "video",
+	".mov": "video", ".webm": "video", ".ogv": "video", ".avi": "video", ".mpg": "video",
+	".mpeg": "video",
+}
+
+// mediaExtractor extracts the media of the PDF file into the outdir.
+type mediaExtractor struct {
+	r      *pdfReader
+	outdir string
+	sx, sy float64
+	used   map[string]bool
+	items  []MediaItem
+}
+
+// extractMedia finds media annotations in the PDF file, and extracts embedded
+// media into the media directory of the outdir. Pages missing in the outdir are
+// skipped.
+func (c *Command) extractMedia(inpath, outdir string) ([]MediaItem, error) {
+	r, err := openPDFFile(inpath)
+	if err != nil {
+		return nil, err
+	}
+
+	sx, sy := c.pixelScale()
+	e := &mediaExtractor{r: r, outdir: outdir, sx: sx, sy: sy, used: make(map[string]bool)}
+
+	for i, page := range r.pages() {
+		n := i + 1
+		if _, err := os.Stat(filepath.Join(outdir, fmt.Sprintf("page%d.html", n))); err != nil {
+			continue
+		}
+
+		for _, annot := range r.array(page.dict["Annots"]) {
+			if err := e.annotation(n, page, r.dict(annot)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return e.items, nil
+}
+
+// annotation extracts the media of the annotation.
+func (e *mediaExtractor) annotation(n int, page pdfPage, annot pdfDict) error {
+	rect, ok := e.r.rect(annot["Rect"])
+	if !ok {
+		return nil
+	}
+
+	item := MediaItem{
+		Page: n,
+		X:    (rect[0] - page.cropBox[0]) * e.sx,
+		Y:    (page.cropBox[3] - rect[3]) * e.sy,
+		W:    (rect[2] - rect[0]) * e.sx,
+		H:    (rect[3] - rect[1]) * e.sy,
+	}
+
+	var err error
+	switch e.r.resolve(annot["Subtype"]) {
+	case pdfName("Screen"):
+		// rendition action plays the media clip
+		action := e.r.dict(annot["A"])
+		clip := e.r.dict(e.r.dict(action["R"])["C"])
+		if clip == nil {
+			return nil
+		}
+		kind := ""
+		if ct, ok := e.r.resolve(clip["CT"]).(pdfString); ok {
+			kind, _, _ = strings.Cut(string(ct), "/")
+		}
+		err = e.fileSpec(&item, clip["D"], kind)
+
+	case pdfName("Movie"):
+		err = e.fileSpec(&item, e.r.dict(annot["Movie"])["F"], "video")
+
+	case pdfName("Sound"):
+		err = e.sound(&item, annot["Sound"])
+
+	case pdfName("RichMedia"):
+		// the first asset is the main content, e.g. the Flash movie or the video
+		assets := e.r.dict(e.r.dict(annot["RichMediaContent"])["Assets"])
+		var first any
+		e.r.nameTree(assets, func(_ string, val any) {
+			if first == nil {
+				first = val
+			}
+		})
+		if first == nil {
+			return nil
+		}
+		err = e.fileSpec(&item, first, "")
+
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if item.File != "" || item.URL != "" {
+		e.items = append(e.items, item)
+	}
+
+	return nil
+}
+
+// fileSpec extracts the embedded file of the file specification, or takes the
+// URL of the external one.
+func (e *mediaExtractor) fileSpec(item *MediaItem, spec any, kind string) error {
+	var name string
+	var stream *pdfStream
+
+	switch v := e.r.resolve(spec).(type) {
+	case pdfString:
+		name = e.r.text(v)
+	case *pdfStream:
+		stream = v
+	case pdfDict:
+		for _, key := range []pdfName{"UF", "F", "Unix", "DOS", "Mac"} {
+			if name = e.r.text(v[key]); name != "" {
+				break
+			}
+		}
+		ef := e.r.dict(v["EF"])
+		for _, key := range []pdfName{"UF", "F"} {
+			if s, ok := e.r.resolve(ef[key]).(*pdfStream); ok {
+				stream = s
+				break
+			}
+		}
+	}
+
+	if kind != "audio" && kind != "video" {
+		kind = mediaTypes[strings.ToLower(path.Ext(name))]
+	}
+	if kind == "" {
+		kind = "other"
+	}
+	item.Kind = kind
+
+	if stream == nil {
+		// only web locations are playable from the output
+		if u, err := url.Parse(name); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			item.URL = name
+		}
+		return nil
+	}
+
+	data, err := e.r.decode(stream)
+	if err != nil {
+		return err
+	}
+
+	return e.write(item, name, data)
+}
+
+// sound converts the sound object into the WAV file.
+func (e *mediaExtractor) sound(item *MediaItem, obj any) error {
+	stream, ok := e.r.resolve(obj).(*pdfStream)
+	if !ok {
+		return nil
+	}
+	item.Kind = "audio"
+
+	data, err := e.r.decode(stream)
+	if err != nil {
+		return err
+	}
+	data = bytes.Clone(data) // unfiltered data is the file itself
+
+	rate, _ := e.r.number(stream.dict["R"])
+	channels, ok := e.r.number(stream.dict["C"])
+	if !ok {
+		channels = 1
+	}
+	bits, ok := e.r.number(stream.dict["B"])
+	if !ok {
+		bits = 8
+	}
+
+	// WAV samples of 8 bits are unsigned, wider ones signed little-endian
+	format := uint16(1)
+	switch e.r.resolve(stream.dict["E"]) {
+	case pdfName("Signed"):
+		if bits == 8 {
+			for i := range data {
+				data[i] ^= 0x80
+			}
+		}
+	case pdfName("muLaw"):
+		format, bits = 7, 8
+	case pdfName("ALaw"):
+		format, bits = 6, 8
+	default: // Raw
+		if bits == 16 {
+			for i := 0; i+1 < len(data); i += 2 {
+				data[i] ^= 0x80
+			}
+		}
+	}
+	if bits == 16 && format == 1 {
+		for i := 0; i+1 < len(data); i += 2 {
+			data[i], data[i+1] = data[i+1], data[i]
+		}
+	}
+
+	var wav bytes.Buffer
+	align := uint16(channels) * uint16(bits) / 8
+	wav.WriteString("RIFF")
+	binary.Write(&wav, binary.LittleEndian, uint32(36+len(data)))
+	wav.WriteString("WAVEfmt ")
+	binary.Write(&wav, binary.LittleEndian, struct {
+		Size             uint32
+		Format, Channels uint16
+		Rate, ByteRate   uint32
+		Align, Bits      uint16
+	}{16, format, uint16(channels), uint32(rate), uint32(rate) * uint32(align), align, uint16(bits)})
+	wav.WriteString("data")
+	binary.Write(&wav, binary.LittleEndian, uint32(len(data)))
+	wav.Write(data)
+
+	return e.write(item, "sound.wav", wav.Bytes())
+}
+
+// write writes the media into the media directory under the unique name.
+func (e *mediaExtractor) write(item *MediaItem, name string, data []byte) error {
+	dir := filepath.Join(e.outdir, MediaDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	if name == "" {
+		name = "media"
+	}
+	name = attachmentName(Attachment{Index: len(e.items) + 1, Name: name}, e.used)
+
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+		return err
+	}
+	item.File = MediaDir + "/" + name
+
+	return nil
+}
+
+// insertMedia adds the placeholders of the media at their positions in the pages.
+func insertMedia(outdir string, items []MediaItem) error {
+	byPage := make(map[int][]MediaItem)
+	for _, item := range items {
+		byPage[item.Page] = append(byPage[item.Page], item)
+	}
+
+	for n, items := range byPage {
+		var b strings.Builder
+		for _, item := range items {
+			src := item.URL
+			if item.File != "" {
+				dir, name := path.Split(item.File)
+				src = dir + url.PathEscape(name)
+			}
+
+			style := fmt.Sprintf("position:absolute; left:%spx; top:%spx; width:%spx; height:%spx;",
+				formatPixels(item.X), formatPixels(item.Y), formatPixels(item.W), formatPixels(item.H))
+
+			switch item.Kind {
+			case "audio", "video":
+				fmt.Fprintf(&b, "<%s class=\"pdftohtml-media\" style=\"%s\" src=\"%s\" controls preload=\"none\"></%s>\n",
+					item.Kind, style, html.EscapeString(src), item.Kind)
+			default:
+				label := "Embedded media"
+				if t := mime.TypeByExtension(path.Ext(src)); t != "" {
+					label += " (" + t + ")"
+				}
+				fmt.Fprintf(&b, "<a class=\"pdftohtml-media\" style=\"%s\" href=\"%s\">%s</a>\n",
+					style, html.EscapeString(src), html.EscapeString(label))
+			}
+		}
+
+		path := filepath.Join(outdir, fmt.Sprintf("page%d.html", n))
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(path, insertBodyEnd(data, []byte(b.String())), 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+// -- embedded media options
+// ----------------------------------------------------------------------------
+
+// Extract sounds, movies and rich media of the PDF file, which `pdftohtml` drops,
+// into the `media` directory of the outdir, and place `audio` and `video`
+// elements at their positions in the pages. External media are referenced only
+// by web URLs. Media of other kinds, e.g. Flash, are linked for download.
+//
+// Media are read by the package itself, media of encrypted files are not
+// extracted.
+func WithMedia() option {
+	return func(c *Command) {
+		c.stages = append(c.stages, func(_ context.Context, inpath, outdir string) error {
+			items, err := c.extractMedia(inpath, outdir)
+			if errors.Is(err, ErrUnsupportedPDF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			return insertMedia(outdir, items)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package pdftohtml
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"os"
+	"path/filepath"
+)
+
+// ----------------------------------------------------------------------------
+// -- mhtml export
+// ----------------------------------------------------------------------------
+
+// mhtmlBase is the location relative references of the archived files resolve
+// against.
+const mhtmlBase = "file:///pdftohtml/"
+
+// ExportMHTML writes the conversion output in the outdir as a single MHTML web
+// archive to w: the index followed by the pages and all files they reference,
+// which opens in browsers and email clients as it is.
+func ExportMHTML(outdir string, w io.Writer) error {
+	names, err := listHTML(outdir)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return ErrEmptyOutput
+	}
+
+	entries, err := os.ReadDir(outdir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && filepath.Ext(entry.Name()) != ".html" && entry.Name() != ManifestName {
+			names = append(names, entry.Name())
+		}
+	}
+
+	mw := multipart.NewWriter(w)
+
+	title := names[0]
+	if data, err := os.ReadFile(filepath.Join(outdir, names[0])); err == nil && htmlTitle(data) != "" {
+		title = htmlTitle(data)
+	}
+
+	_, err = fmt.Fprintf(w, "From: <Saved by pdftohtml>\r\n"+
+		"Subject: %s\r\n"+
+		"Snapshot-Content-Location: %s\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: multipart/related; type=\"text/html\"; boundary=\"%s\"\r\n\r\n",
+		mime.QEncoding.Encode("utf-8", title), mhtmlBase+names[0], mw.Boundary())
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err := writeMHTMLPart(mw, outdir, name); err != nil {
+			return err
+		}
+	}
+
+	return mw.Close()
+}
+
+// writeMHTMLPart writes the file as the part of the archive, text quoted-printable
+// and everything else in base64.
+func writeMHTMLPart(mw *multipart.Writer, outdir, name string) error {
+	data, err := os.ReadFile(filepath.Join(outdir, name))
+	if err != nil {
+		return err
+	}
+
+	mediaType := mime.TypeByExtension(filepath.Ext(name))
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+	text := filepath.Ext(name) == ".html" || filepath.Ext(name) == ".css"
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mediaType)
+	header.Set("Content-Location", mhtmlBase+name)
+	if text {
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+	} else {
+		header.Set("Content-Transfer-Encoding", "base64")
+	}
+
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	if text {
+		qw := quotedprintable.NewWriter(part)
+		if _, err := qw.Write(data); err != nil {
+			return err
+		}
+
+		return qw.Close()
+	}
+
+	// base64 lines must not exceed 76 characters
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := min(76, len(encoded))
+		if _, err := io.WriteString(part, encoded[:n]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+
+	return nil
+}
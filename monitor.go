@@ -0,0 +1,193 @@
+package pdftohtml
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- monitor
+// ----------------------------------------------------------------------------
+
+// Monitor tracks conversions of the runner, for introspection of what the
+// conversions are doing right now: the active conversions, the queues of the
+// watched tenants, the stats of the watched caches and the recent failures.
+//
+// The monitor serves the snapshot as JSON, so it can be mounted as the debug
+// endpoint, e.g. `mux.Handle("/debug/pdftohtml", m)`. It is also `expvar.Var`,
+// so it can be published with `expvar.Publish("pdftohtml", m)`.
+type Monitor struct {
+	runner      Runner
+	maxFailures int
+
+	mu       sync.Mutex
+	nextID   uint64
+	active   map[uint64]*MonitorConversion
+	failures []MonitorFailure // oldest first
+	tenants  map[string]*Tenant
+	caches   map[string]*Cache
+}
+
+// MonitorConversion is the conversion in progress.
+type MonitorConversion struct {
+	Input   string        `json:"input"`
+	Outdir  string        `json:"outdir"`
+	Started time.Time     `json:"started"`
+	Elapsed time.Duration `json:"elapsed"` // in nanoseconds in JSON
+}
+
+// MonitorFailure is the failed conversion.
+type MonitorFailure struct {
+	Input    string        `json:"input"`
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"` // in nanoseconds in JSON
+	Error    string        `json:"error"`
+}
+
+// MonitorSnapshot describes the conversions at the moment.
+type MonitorSnapshot struct {
+	Active   []MonitorConversion    `json:"active"`   // longest running first
+	Tenants  map[string]TenantStats `json:"tenants"`  // by tenant ID
+	Caches   map[string]CacheStats  `json:"caches"`   // by name given to `WatchCache`
+	Failures []MonitorFailure       `json:"failures"` // most recent first
+}
+
+// NewMonitor creates new monitor around the runner.
+func NewMonitor(runner Runner, opts ...monitorOption) *Monitor {
+	m := &Monitor{
+		runner:      runner,
+		maxFailures: 20,
+		active:      make(map[uint64]*MonitorConversion),
+		tenants:     make(map[string]*Tenant),
+		caches:      make(map[string]*Cache),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Run executes the conversion, tracking it while it runs.
+func (m *Monitor) Run(ctx context.Context, inpath, outdir string) error {
+	start := time.Now()
+
+	m.mu.Lock()
+	id := m.nextID
+	m.nextID++
+	m.active[id] = &MonitorConversion{Input: inpath, Outdir: outdir, Started: start}
+	m.mu.Unlock()
+
+	err := m.runner.Run(ctx, inpath, outdir)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.active, id)
+	if err != nil && m.maxFailures > 0 {
+		failure := MonitorFailure{Input: inpath, Time: time.Now(), Duration: time.Since(start), Error: err.Error()}
+		if len(m.failures) == m.maxFailures {
+			m.failures = append(m.failures[:0], m.failures[1:]...)
+		}
+		m.failures = append(m.failures, failure)
+	}
+
+	return err
+}
+
+// WatchTenant adds the queue of the tenant to the snapshots.
+func (m *Monitor) WatchTenant(t *Tenant) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tenants[t.ID()] = t
+}
+
+// WatchCache adds the stats of the cache to the snapshots, under the name.
+func (m *Monitor) WatchCache(name string, c *Cache) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.caches[name] = c
+}
+
+// Snapshot returns the current state of the conversions.
+func (m *Monitor) Snapshot() MonitorSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	s := MonitorSnapshot{
+		Active:   make([]MonitorConversion, 0, len(m.active)),
+		Tenants:  make(map[string]TenantStats, len(m.tenants)),
+		Caches:   make(map[string]CacheStats, len(m.caches)),
+		Failures: make([]MonitorFailure, 0, len(m.failures)),
+	}
+
+	for _, c := range m.active {
+		conversion := *c
+		conversion.Elapsed = now.Sub(c.Started)
+		s.Active = append(s.Active, conversion)
+	}
+	sort.Slice(s.Active, func(i, j int) bool {
+		return s.Active[i].Started.Before(s.Active[j].Started)
+	})
+
+	for id, t := range m.tenants {
+		s.Tenants[id] = t.Stats()
+	}
+	for name, c := range m.caches {
+		s.Caches[name] = c.Stats()
+	}
+
+	for i := len(m.failures) - 1; i >= 0; i-- {
+		s.Failures = append(s.Failures, m.failures[i])
+	}
+
+	return s
+}
+
+// String returns the snapshot as JSON, for `expvar`.
+func (m *Monitor) String() string {
+	data, err := json.Marshal(m.Snapshot())
+	if err != nil {
+		return "null"
+	}
+
+	return string(data)
+}
+
+// ServeHTTP responds with the snapshot as JSON.
+func (m *Monitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(m.Snapshot())
+}
+
+// ----------------------------------------------------------------------------
+// -- monitor options
+// ----------------------------------------------------------------------------
+
+type monitorOption func(*Monitor)
+
+// Set the number of the most recent failures kept by the monitor, 0 to keep none.
+//
+// The default is 20.
+func WithMonitorFailures(n int) monitorOption {
+	return func(m *Monitor) {
+		m.maxFailures = max(n, 0)
+	}
+}
@@ -0,0 +1,135 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------------------
+// -- markup normalization
+// ----------------------------------------------------------------------------
+
+var (
+	// reProlog matches the XML declaration and the doctype at the beginning.
+	reProlog = regexp.MustCompile(`(?is)^\x{FEFF}?\s*(?:<\?xml[^>]*\?>\s*)?(?:<!doctype[^>]*>\s*)?`)
+
+	// reCharsetMeta matches meta elements declaring the charset, in either form.
+	reCharsetMeta = regexp.MustCompile(`(?i)<meta\b[^>]*(?:\bcharset\s*=|\bhttp-equiv\s*=\s*["']?content-type)[^>]*>[ \t]*\n?`)
+
+	// reCharset matches the charset of the meta element or the encoding of the
+	// XML declaration.
+	reCharset = regexp.MustCompile(`(?i)(?:<\?xml[^>]*\bencoding|<meta\b[^>]*\bcharset)\s*=\s*["']?([a-z0-9._:-]+)`)
+
+	reHeadClose = regexp.MustCompile(`(?i)</head\s*>`)
+	reBodyTag   = regexp.MustCompile(`(?i)<body\b[^>]*>`)
+	reHTMLClose = regexp.MustCompile(`(?i)</html\s*>`)
+)
+
+// normalizeMarkup makes sure the document has the HTML5 doctype, the root, head
+// and body elements, and the single charset declaration. Documents in Latin-1
+// or Windows-1252 are transcoded to UTF-8, documents in other charsets keep
+// theirs.
+func normalizeMarkup(data []byte) []byte {
+	charset := "UTF-8"
+	if match := reCharset.FindSubmatch(data); match != nil {
+		if decoded, ok := decodeCharset(data, string(match[1])); ok {
+			data = decoded
+		} else {
+			charset = string(match[1])
+		}
+	}
+
+	data = reProlog.ReplaceAll(data, nil)
+	data = reCharsetMeta.ReplaceAll(data, nil)
+
+	if !reHTMLTag.Match(data) {
+		data = append(append([]byte("<html>\n"), data...), "\n</html>\n"...)
+	}
+
+	if !reHeadTag.Match(data) {
+		loc := reHTMLTag.FindIndex(data)
+		data = splice(data, loc[1], loc[1], []byte("\n<head>\n</head>"))
+	}
+
+	if !reBodyTag.Match(data) {
+		// everything between the head and the end of the root is the body
+		start := reHeadClose.FindIndex(data)
+		end := reHTMLClose.FindIndex(data)
+		if start != nil && end != nil && start[1] <= end[0] {
+			body := bytes.TrimSpace(data[start[1]:end[0]])
+			markup := append(append([]byte("\n<body>\n"), body...), "\n</body>\n"...)
+			data = splice(data, start[1], end[0], markup)
+		}
+	}
+
+	data = insertHead(data, []byte("\n<meta charset=\""+charset+"\">"))
+
+	return append([]byte("<!DOCTYPE html>\n"), data...)
+}
+
+// windows1252 maps bytes 0x80 to 0x9F of Windows-1252 to runes, the other bytes
+// map to the runes of the same value.
+var windows1252 = [32]rune{
+	'\u20ac', '\u0081', '\u201a', '\u0192', '\u201e', '\u2026', '\u2020', '\u2021',
+	'\u02c6', '\u2030', '\u0160', '\u2039', '\u0152', '\u008d', '\u017d', '\u008f',
+	'\u0090', '\u2018', '\u2019', '\u201c', '\u201d', '\u2022', '\u2013', '\u2014',
+	'\u02dc', '\u2122', '\u0161', '\u203a', '\u0153', '\u009d', '\u017e', '\u0178',
+}
+
+// decodeCharset returns the data in the charset transcoded to UTF-8, and false
+// if the charset is not supported. Latin-1 and ASCII are decoded as Windows-1252,
+// like browsers do.
+func decodeCharset(data []byte, charset string) ([]byte, bool) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8", "unicode-1-1-utf-8":
+		return data, true
+	case "windows-1252", "cp1252", "x-cp1252", "iso-8859-1", "iso8859-1", "iso_8859-1", "latin1", "l1",
+		"us-ascii", "ascii":
+		buf := make([]byte, 0, len(data))
+		for _, b := range data {
+			r := rune(b)
+			if 0x80 <= b && b <= 0x9f {
+				r = windows1252[b-0x80]
+			}
+			buf = utf8.AppendRune(buf, r)
+		}
+		return buf, true
+	}
+
+	return nil, false
+}
+
+// splice returns the data with bytes between start and end replaced.
+func splice(data []byte, start, end int, markup []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(data[:start])
+	buf.Write(markup)
+	buf.Write(data[end:])
+
+	return buf.Bytes()
+}
+
+// ----------------------------------------------------------------------------
+// -- markup normalization options
+// ----------------------------------------------------------------------------
+
+// Normalize markup of the generated HTML files: the HTML5 doctype, the root,
+// head and body elements, and the single `<meta charset="UTF-8">` declaration,
+// which is what `pdftohtml` writes.
+//
+// Legacy doctypes, XML declarations and `http-equiv` charset declarations of
+// various versions are replaced. Files declaring Latin-1 or Windows-1252 are
+// transcoded to UTF-8, files declaring other charsets keep theirs, declared
+// with `<meta charset>`.
+func WithMarkupNormalization() option {
+	return func(c *Command) {
+		c.stages = append(c.stages, func(_ context.Context, _, outdir string) error {
+			return rewriteHTML(outdir, func(_ string, data []byte) ([]byte, error) {
+				return normalizeMarkup(data), nil
+			})
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package pdftohtml
+
+import "testing"
+
+func TestNormalizeMarkup(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{
+			name: "fragment",
+			in:   "<p>x</p>",
+			want: "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"UTF-8\">\n</head>\n<body>\n<p>x</p>\n</body>\n</html>\n",
+		},
+		{
+			name: "legacy prolog",
+			in:   "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE html PUBLIC \"-//W3C//DTD XHTML 1.0 Transitional//EN\">\n<html><head><title>t</title></head><body>é</body></html>",
+			want: "<!DOCTYPE html>\n<html><head>\n<meta charset=\"UTF-8\"><title>t</title></head><body>é</body></html>",
+		},
+		{
+			name: "latin-1",
+			in:   "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<html><head><title>t</title></head><body>caf\xe9</body></html>",
+			want: "<!DOCTYPE html>\n<html><head>\n<meta charset=\"UTF-8\"><title>t</title></head><body>café</body></html>",
+		},
+		{
+			name: "windows-1252",
+			in:   "<html><head><meta http-equiv=\"Content-Type\" content=\"text/html; charset=windows-1252\">\n</head><body>\x93\x80\x94</body></html>",
+			want: "<!DOCTYPE html>\n<html><head>\n<meta charset=\"UTF-8\"></head><body>“€”</body></html>",
+		},
+		{
+			name: "other charset kept",
+			in:   "<html><head><meta http-equiv=\"Content-Type\" content=\"text/html; charset=Shift_JIS\">\n</head><body>\x82\xa0</body></html>",
+			want: "<!DOCTYPE html>\n<html><head>\n<meta charset=\"Shift_JIS\"></head><body>\x82\xa0</body></html>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(normalizeMarkup([]byte(tt.in))); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package pdftohtml
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// ----------------------------------------------------------------------------
+// -- object storage
+// ----------------------------------------------------------------------------
+
+// Source opens PDF files by key, e.g. objects in the bucket of the object
+// storage.
+//
+// The source of the Amazon S3 bucket, with the AWS SDK for Go v2, is:
+//
+//	type S3Source struct {
+//		Client *s3.Client
+//		Bucket string
+//	}
+//
+//	func (s S3Source) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+//		out, err := s.Client.GetObject(ctx, &s3.GetObjectInput{Bucket: &s.Bucket, Key: &key})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return out.Body, nil
+//	}
+//
+// and the one of the Google Cloud Storage bucket, with cloud.google.com/go/storage:
+//
+//	type GCSSource struct {
+//		Bucket *storage.BucketHandle
+//	}
+//
+//	func (s GCSSource) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+//		return s.Bucket.Object(key).NewReader(ctx)
+//	}
+type Source interface {
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// SourceFunc adapts the function to `Source`.
+type SourceFunc func(ctx context.Context, key string) (io.ReadCloser, error)
+
+// Open calls the function.
+func (f SourceFunc) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	return f(ctx, key)
+}
+
+// Storage stores output files by name, e.g. as objects in the bucket of the
+// object storage. Names are slash-separated.
+//
+// The storage of the Amazon S3 bucket, with the upload manager of the AWS SDK
+// for Go v2, is:
+//
+//	type S3Storage struct {
+//		Uploader *manager.Uploader
+//		Bucket   string
+//	}
+//
+//	func (s S3Storage) Put(ctx context.Context, name string, r io.Reader) error {
+//		_, err := s.Uploader.Upload(ctx, &s3.PutObjectInput{Bucket: &s.Bucket, Key: &name, Body: r})
+//		return err
+//	}
+//
+// and the one of the Google Cloud Storage bucket:
+//
+//	type GCSStorage struct {
+//		Bucket *storage.BucketHandle
+//	}
+//
+//	func (s GCSStorage) Put(ctx context.Context, name string, r io.Reader) error {
+//		w := s.Bucket.Object(name).NewWriter(ctx)
+//		if _, err := io.Copy(w, r); err != nil {
+//			w.Close()
+//			return err
+//		}
+//		return w.Close()
+//	}
+type Storage interface {
+	Put(ctx context.Context, name string, r io.Reader) error
+}
+
+// StorageFunc adapts the function to `Storage`.
+type StorageFunc func(ctx context.Context, name string, r io.Reader) error
+
+// Put calls the function.
+func (f StorageFunc) Put(ctx context.Context, name string, r io.Reader) error {
+	return f(ctx, name, r)
+}
+
+// RunObject reads the PDF file of the key from the source into the temporary
+// directory, converts it like `Run`, and stores the output files in the storage
+// under the prefix, e.g. "docs/report" for "docs/report/index.html". The
+// temporary directory is removed once done.
+//
+// The PDF file is limited in size like in `RunURL`, see `WithDownloadMaxSize`.
+// Once stored, the `EventUploaded` is emitted with the prefix as the location.
+func (c *Command) RunObject(ctx context.Context, src Source, key string, dst Storage, prefix string) error {
+	tmpdir, err := os.MkdirTemp("", "pdftohtml-object-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpdir)
+
+	inpath, err := c.fetch(ctx, src, key, tmpdir)
+	if err != nil {
+		return err
+	}
+
+	outdir := filepath.Join(tmpdir, "output")
+	if err := c.Run(ctx, inpath, outdir); err != nil {
+		return err
+	}
+
+	if err := putTree(ctx, dst, outdir, prefix); err != nil {
+		return err
+	}
+
+	EmitEvent(ctx, Event{Type: EventUploaded, Input: key, Location: prefix})
+
+	return nil
+}
+
+// fetch writes the PDF file of the key into the file in the directory, and
+// returns its path.
+func (c *Command) fetch(ctx context.Context, src Source, key, dir string) (string, error) {
+	limit := c.downloadPolicy().limit
+
+	r, err := src.Open(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	inpath := filepath.Join(dir, inputName(key))
+	f, err := os.Create(inpath)
+	if err != nil {
+		return "", err
+	}
+
+	n, err := io.Copy(f, io.LimitReader(r, limit+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	if n > limit {
+		return "", fmt.Errorf("%w: over %d bytes", ErrDownloadTooLarge, limit)
+	}
+
+	return inpath, nil
+}
+
+// putTree stores regular files of the directory tree in the storage under the
+// prefix.
+func putTree(ctx context.Context, dst Storage, dir, prefix string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		return dst.Put(ctx, path.Join(prefix, filepath.ToSlash(name)), f)
+	})
+}
@@ -0,0 +1,66 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestRunObject(t *testing.T) {
+	cmd := newStubCommand(t, 1)
+
+	src := SourceFunc(func(_ context.Context, key string) (io.ReadCloser, error) {
+		if key != "in/report.pdf" {
+			return nil, fs.ErrNotExist
+		}
+		return io.NopCloser(strings.NewReader("%PDF-1.4")), nil
+	})
+
+	stored := make(map[string]string)
+	dst := StorageFunc(func(_ context.Context, name string, r io.Reader) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		stored[name] = string(data)
+		return nil
+	})
+
+	if err := cmd.RunObject(context.Background(), src, "in/report.pdf", dst, "out/report"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stored["out/report/page1.html"], "page 1\n"; got != want {
+		t.Errorf("got stored %q, want page1.html of %q", stored, want)
+	}
+
+	err := cmd.RunObject(context.Background(), src, "in/missing.pdf", dst, "out/missing")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing object: got %v, want fs.ErrNotExist", err)
+	}
+	for name := range stored {
+		if strings.HasPrefix(name, "out/missing/") {
+			t.Errorf("missing object: got stored %q", name)
+		}
+	}
+}
+
+func TestRunObjectTooLarge(t *testing.T) {
+	cmd := newStubCommand(t, 1)
+	WithDownloadMaxSize(4)(cmd)
+
+	src := SourceFunc(func(context.Context, string) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("%PDF-1.4")), nil
+	})
+	dst := StorageFunc(func(_ context.Context, name string, _ io.Reader) error {
+		t.Errorf("got stored %q, want nothing stored", name)
+		return nil
+	})
+
+	err := cmd.RunObject(context.Background(), src, "report.pdf", dst, "report")
+	if !errors.Is(err, ErrDownloadTooLarge) {
+		t.Errorf("got %v, want ErrDownloadTooLarge", err)
+	}
+}
@@ -0,0 +1,136 @@
+package pdftohtml
+
+import "strconv"
+
+// ----------------------------------------------------------------------------
+// -- `pdftohtml` option set
+// ----------------------------------------------------------------------------
+
+// OptionSet is a typed view of the options applied to the command. Zero values
+// mean the option is not set and `pdftohtml` uses its default.
+//
+// Passwords are never serialized to JSON, so they don't end up in persisted
+// jobs or configs. Set them again after decoding, e.g. with `WithOwnerPassword`
+// and `WithUserPassword`.
+type OptionSet struct {
+	ConfigFile       string  `json:"xpdfrc,omitempty"`
+	Overwrite        bool    `json:"overwrite,omitempty"`
+	PageFrom         uint64  `json:"first-page,omitempty"`
+	PageTo           uint64  `json:"last-page,omitempty"`
+	Zoom             float64 `json:"zoom,omitempty"`
+	Resolution       uint64  `json:"resolution,omitempty"`
+	VerticalStretch  float64 `json:"vstretch,omitempty"`
+	EmbedBackground  bool    `json:"embed-background,omitempty"`
+	NoFonts          bool    `json:"no-fonts,omitempty"`
+	EmbedFonts       bool    `json:"embed-fonts,omitempty"`
+	NoInvisibleText  bool    `json:"skip-invisible,omitempty"`
+	AllInvisibleText bool    `json:"all-invisible,omitempty"`
+	FormFields       bool    `json:"form-fields,omitempty"`
+	MetaTags         bool    `json:"meta,omitempty"`
+	Table            bool    `json:"table,omitempty"`
+	OwnerPassword    string  `json:"-"`
+	UserPassword     string  `json:"-"`
+}
+
+// args returns `pdftohtml` arguments for the options.
+func (s OptionSet) args() []string {
+	var args []string
+
+	str := func(name, v string) {
+		if v != "" {
+			args = append(args, name, v)
+		}
+	}
+	integer := func(name string, v uint64) {
+		if v != 0 {
+			args = append(args, name, strconv.FormatUint(v, 10))
+		}
+	}
+	fraction := func(name string, v float64) {
+		if v != 0 {
+			args = append(args, name, strconv.FormatFloat(v, 'e', 2, 64))
+		}
+	}
+	toggle := func(name string, v bool) {
+		if v {
+			args = append(args, name)
+		}
+	}
+
+	str("-cfg", s.ConfigFile)
+	toggle("-overwrite", s.Overwrite)
+	integer("-f", s.PageFrom)
+	integer("-l", s.PageTo)
+	fraction("-z", s.Zoom)
+	integer("-r", s.Resolution)
+	fraction("-vstretch", s.VerticalStretch)
+	toggle("-embedbackground", s.EmbedBackground)
+	toggle("-nofonts", s.NoFonts)
+	toggle("-embedfonts", s.EmbedFonts)
+	toggle("-skipinvisible", s.NoInvisibleText)
+	toggle("-allinvisible", s.AllInvisibleText)
+	toggle("-formfields", s.FormFields)
+	toggle("-meta", s.MetaTags)
+	toggle("-table", s.Table)
+	// values are separate arguments, which Xpdf takes as they are even when they
+	// start with a dash, so passwords and the config path need no escaping
+	str("-opw", s.OwnerPassword)
+	str("-upw", s.UserPassword)
+
+	return args
+}
+
+// options returns the options setting all non-zero values of the set.
+func (s OptionSet) options() []option {
+	var opts []option
+
+	str := func(v string, fn func(string) option) {
+		if v != "" {
+			opts = append(opts, fn(v))
+		}
+	}
+	integer := func(v uint64, fn func(uint64) option) {
+		if v != 0 {
+			opts = append(opts, fn(v))
+		}
+	}
+	fraction := func(v float64, fn func(float64) option) {
+		if v != 0 {
+			opts = append(opts, fn(v))
+		}
+	}
+	toggle := func(v bool, fn func() option) {
+		if v {
+			opts = append(opts, fn())
+		}
+	}
+
+	str(s.ConfigFile, WithCustomConfig)
+	toggle(s.Overwrite, WithOutdirOverwrite)
+	integer(s.PageFrom, WithPageFrom)
+	integer(s.PageTo, WithPageTo)
+	fraction(s.Zoom, WithInitialZoom)
+	integer(s.Resolution, WithResolution)
+	fraction(s.VerticalStretch, WithVerticalStretch)
+	toggle(s.EmbedBackground, WithEmbedBackground)
+	toggle(s.NoFonts, WithNoFonts)
+	toggle(s.EmbedFonts, WithEmbedFonts)
+	toggle(s.NoInvisibleText, WithNoInvisibleText)
+	toggle(s.AllInvisibleText, WithAllInvisibleText)
+	toggle(s.FormFields, WithEmbedFormFields)
+	toggle(s.MetaTags, WithEmbedMetaTags)
+	toggle(s.Table, WithModeTable)
+	str(s.OwnerPassword, WithOwnerPassword)
+	str(s.UserPassword, WithUserPassword)
+
+	return opts
+}
+
+// Apply all non-zero options of the set, e.g. one returned by `Command.Options`.
+func WithOptionSet(set OptionSet) option {
+	return func(c *Command) {
+		for _, opt := range set.options() {
+			opt(c)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package pdftohtml
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// pdftohtmlArgs are the options of Xpdf `pdftohtml`, and whether they take the
+// value from the next argument.
+var pdftohtmlArgs = map[string]bool{
+	"-f": true, "-l": true, "-z": true, "-r": true, "-vstretch": true,
+	"-embedbackground": false, "-nofonts": false, "-embedfonts": false,
+	"-skipinvisible": false, "-allinvisible": false, "-formfields": false,
+	"-meta": false, "-table": false, "-overwrite": false,
+	"-opw": true, "-upw": true, "-cfg": true,
+	"-q": false, "-v": false, "-h": false, "-help": false, "--help": false, "-?": false,
+}
+
+// parseXpdfArgs parses the arguments like Xpdf `parseArgs` does: options are
+// recognized anywhere until `--`, and everything else is positional.
+func parseXpdfArgs(argv []string) (opts map[string][]string, positional []string) {
+	opts = make(map[string][]string)
+	for i := 0; i < len(argv); i++ {
+		arg := argv[i]
+		if arg == "--" {
+			return opts, append(positional, argv[i+1:]...)
+		}
+
+		takesValue, ok := pdftohtmlArgs[arg]
+		switch {
+		case !ok:
+			positional = append(positional, arg)
+		case takesValue && i+1 < len(argv):
+			opts[arg] = append(opts[arg], argv[i+1])
+			i++
+		default:
+			opts[arg] = append(opts[arg], "")
+		}
+	}
+
+	return opts, positional
+}
+
+func FuzzArgs(f *testing.F) {
+	f.Add("in.pdf", "out", "", "", "")
+	f.Add("-v", "-h", "-cfg", "-opw", "--")
+	f.Add("--", "-overwrite", "-f", "-upw", "-")
+	f.Add("-in.pdf", "--help", "-q", "", "-l")
+
+	f.Fuzz(func(t *testing.T, inpath, outdir, config, owner, user string) {
+		c := &Command{opts: OptionSet{
+			ConfigFile:    config,
+			Overwrite:     true,
+			PageFrom:      1,
+			OwnerPassword: owner,
+			UserPassword:  user,
+		}}
+
+		opts, positional := parseXpdfArgs(c.argv(c.opts, inpath, outdir))
+
+		if !slices.Equal(positional, []string{inpath, outdir}) {
+			t.Fatalf("positional arguments %q, want %q", positional, []string{inpath, outdir})
+		}
+
+		want := map[string][]string{"-overwrite": {""}, "-f": {"1"}}
+		for name, val := range map[string]string{"-cfg": config, "-opw": owner, "-upw": user} {
+			if val != "" {
+				want[name] = []string{val}
+			}
+		}
+		for name, vals := range opts {
+			if !slices.Equal(vals, want[name]) {
+				t.Fatalf("option %s = %q, want %q", name, vals, want[name])
+			}
+		}
+		if len(opts) != len(want) {
+			t.Fatalf("options %q, want %q", opts, want)
+		}
+	})
+}
+
+func TestOptionSetJSONPasswords(t *testing.T) {
+	set := OptionSet{Zoom: 1.5, OwnerPassword: "owner-secret", UserPassword: "user-secret"}
+
+	data, err := json.Marshal(Config{OptionSet: set})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("passwords serialized: %s", data)
+	}
+
+	var got OptionSet
+	if err := json.Unmarshal([]byte(`{"zoom":1.5,"owner-password":"x","user-password":"y"}`), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != (OptionSet{Zoom: 1.5}) {
+		t.Errorf("got %+v, passwords must not be decoded", got)
+	}
+}
@@ -0,0 +1,327 @@
+package pdftohtml
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- outdir safety
+// ----------------------------------------------------------------------------
+
+// ErrUnsafeOutdir is returned when the outdir is refused as a conversion target.
+var ErrUnsafeOutdir = errors.New("pdftohtml: unsafe outdir")
+
+// checkOutdir refuses outdirs that are dangerous to write into, or out of the
+// configured root.
+func (c *Command) checkOutdir(inpath, outdir string) error {
+	target, err := resolvePath(outdir)
+	if err != nil {
+		return err
+	}
+
+	if c.opts.Overwrite || c.clean || c.incremental {
+		source, err := resolvePath(inpath)
+		if err != nil {
+			return err
+		}
+
+		home, err := os.UserHomeDir()
+		if err == nil {
+			home, err = resolvePath(home)
+		}
+		if err != nil {
+			home = ""
+		}
+
+		switch {
+		case filepath.Dir(target) == target:
+			return fmt.Errorf("%w: %q is a filesystem root", ErrUnsafeOutdir, outdir)
+		case home != "" && isWithin(target, home):
+			return fmt.Errorf("%w: %q contains the home directory", ErrUnsafeOutdir, outdir)
+		case isWithin(target, source):
+			return fmt.Errorf("%w: %q contains the input", ErrUnsafeOutdir, outdir)
+		}
+	}
+
+	if c.root != "" {
+		root, err := resolvePath(c.root)
+		if err != nil {
+			return err
+		}
+
+		if !isWithin(root, target) {
+			return fmt.Errorf("%w: %q is outside of %q", ErrUnsafeOutdir, outdir, c.root)
+		}
+	}
+
+	return nil
+}
+
+// checkTree asserts that no file in the outdir resolves to a location outside
+// of it.
+func checkTree(outdir string) error {
+	root, err := resolvePath(outdir)
+	if err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(outdir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return err
+		}
+
+		target, err := resolvePath(path)
+		if err != nil {
+			return err
+		}
+
+		if !isWithin(root, target) {
+			return fmt.Errorf("%w: %q points outside of the outdir", ErrUnsafeOutdir, path)
+		}
+
+		return nil
+	})
+}
+
+// applyOwnership sets the configured permissions and owner of all files in the
+// outdir, including the outdir itself.
+func (c *Command) applyOwnership(outdir string) error {
+	if c.fileMode == 0 && c.dirMode == 0 && !c.chown {
+		return nil
+	}
+
+	return filepath.WalkDir(outdir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink != 0 {
+			return err
+		}
+
+		mode := c.fileMode
+		if d.IsDir() {
+			mode = c.dirMode
+		}
+		if mode != 0 {
+			if err := os.Chmod(path, mode); err != nil {
+				return err
+			}
+		}
+
+		if c.chown {
+			return os.Lchown(path, c.uid, c.gid)
+		}
+
+		return nil
+	})
+}
+
+// CleanMarkerName is the name of the marker file which allows `WithOutdirClean`
+// to remove everything in the outdir, not only the conversion output.
+const CleanMarkerName = ".pdftohtml-outdir"
+
+// reOutputFile matches names of files generated by the conversion.
+var reOutputFile = regexp.MustCompile(`^(?:index\.html|page\d+(?:@[0-9.]+x|-[a-z][a-z0-9]*)?\.(?:html|png|jpe?g|gif|svg|webp)|ff?\d+\.woff2?)$`)
+
+// cleanOutdir removes output of the previous conversion from the outdir. If the
+// outdir contains the marker file, everything but the marker is removed. If it
+// contains the manifest of the previous conversion, the files it lists are
+// removed. Otherwise files named like the conversion output are removed.
+func cleanOutdir(outdir string) error {
+	entries, err := os.ReadDir(outdir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Lstat(filepath.Join(outdir, CleanMarkerName))
+	marked := err == nil
+
+	if !marked {
+		m, err := ReadManifest(outdir)
+		if err == nil {
+			return cleanManifest(outdir, m)
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(outdir, entry.Name())
+
+		switch name := entry.Name(); {
+		case name == CleanMarkerName:
+			continue
+		case marked:
+			err = os.RemoveAll(path)
+		case entry.Type().IsRegular() && isOutputFile(name):
+			err = os.Remove(path)
+		case entry.IsDir() && (name == AttachmentsDir || name == MediaDir):
+			err = os.RemoveAll(path)
+		default:
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cleanManifest removes the files listed in the manifest of the previous
+// conversion, and the manifest itself, from the outdir. Listed paths out of the
+// outdir and files which are not regular are left alone, and so are directories
+// which still have files in them.
+func cleanManifest(outdir string, m *Manifest) error {
+	root, err := resolvePath(outdir)
+	if err != nil {
+		return err
+	}
+
+	var dirs []string
+	for _, file := range append(m.Files, ManifestFile{Name: ManifestName}) {
+		name := filepath.FromSlash(file.Name)
+		if !filepath.IsLocal(name) || name == CleanMarkerName {
+			continue
+		}
+
+		path := filepath.Join(outdir, name)
+		if target, err := resolvePath(path); err != nil || !isWithin(root, target) {
+			continue
+		}
+		if info, err := os.Lstat(path); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+
+		for dir := filepath.Dir(name); dir != "."; dir = filepath.Dir(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	// deepest directories first, so parents are empty when they are reached
+	sort.SliceStable(dirs, func(i, j int) bool {
+		return len(dirs[i]) > len(dirs[j])
+	})
+	for _, dir := range dirs {
+		os.Remove(filepath.Join(outdir, dir))
+	}
+
+	return nil
+}
+
+// isOutputFile reports whether the file name is one of the names the conversion
+// generates.
+func isOutputFile(name string) bool {
+	switch name {
+	case ManifestName, PreviewImageName, SitemapName, RobotsName, WordsName, StylesheetName, QualityName,
+		ViewerName, ViewerStylesheetName, ViewerScriptName, DestinationsName, FormSchemaName, ActiveContentName,
+		ReportName, PageLabelsName:
+		return true
+	}
+
+	return reOutputFile.MatchString(name)
+}
+
+// resolvePath returns the absolute path with symbolic links evaluated. Parts of
+// the path that do not exist yet are kept as they are.
+func resolvePath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	var rest []string
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return filepath.Join(append([]string{path}, rest...)...), nil
+		}
+
+		rest = append([]string{filepath.Base(path)}, rest...)
+		path = parent
+	}
+}
+
+// isWithin reports whether the path is the root or located under it.
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ----------------------------------------------------------------------------
+// -- outdir options
+// ----------------------------------------------------------------------------
+
+// Refuse outdirs outside of the root directory, including ones reached through
+// symbolic links, and generated files pointing outside of the outdir.
+func WithOutdirRoot(root string) option {
+	return func(c *Command) {
+		c.root = root
+	}
+}
+
+// Empty the existing outdir before the conversion, so no pages of the previous
+// conversion are left behind when the page count shrinks.
+//
+// Only files of the previous conversion are removed: the ones listed in its
+// `manifest.json`, see `WithManifest`, or without the manifest, pages, images
+// and fonts with names the conversion generates. If the outdir contains the
+// `CleanMarkerName` file, everything else is removed. The outdir is refused the
+// same way as with `WithOutdirOverwrite`.
+func WithOutdirClean() option {
+	return func(c *Command) {
+		c.clean = true
+	}
+}
+
+// Create missing parent directories of the outdir with the permissions (before
+// umask) before the conversion.
+//
+// The outdir itself is still created by the executable, which refuses existing
+// outdirs unless `WithOutdirOverwrite` is used.
+func WithOutdirParents(perm os.FileMode) option {
+	return func(c *Command) {
+		c.mkdir, c.mkdirMode = true, perm
+	}
+}
+
+// Set permissions of the generated files and directories, instead of the ones
+// resulting from the umask of the process.
+func WithOutputMode(file, dir os.FileMode) option {
+	return func(c *Command) {
+		c.fileMode, c.dirMode = file, dir
+	}
+}
+
+// Set owner and group of the generated files and directories. Changing the owner
+// usually requires elevated privileges and is not supported on Windows.
+func WithOutputOwner(uid, gid int) option {
+	return func(c *Command) {
+		c.chown, c.uid, c.gid = true, uid, gid
+	}
+}
@@ -0,0 +1,142 @@
+package pdftohtml
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCheckOutdir(t *testing.T) {
+	tmp, err := resolvePath(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	home := filepath.Join(tmp, "home", "user")
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	project := filepath.Join(tmp, "project")
+	inpath := filepath.Join(project, "docs", "in.pdf")
+	if err := os.MkdirAll(filepath.Dir(inpath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(inpath, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		outdir string
+		unsafe bool
+	}{
+		{"sibling", filepath.Join(project, "docs", "out"), false},
+		{"elsewhere", filepath.Join(tmp, "out"), false},
+		{"under home", filepath.Join(home, "out"), false},
+		{"parent of input", filepath.Join(project, "docs"), true},
+		{"grandparent of input", project, true},
+		{"home", home, true},
+		{"parent of home", filepath.Join(tmp, "home"), true},
+		{"root", string(filepath.Separator), true},
+	}
+
+	c := &Command{clean: true}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.checkOutdir(inpath, tt.outdir)
+			if got := errors.Is(err, ErrUnsafeOutdir); got != tt.unsafe {
+				t.Errorf("checkOutdir(%q) = %v, want unsafe %v", tt.outdir, err, tt.unsafe)
+			}
+		})
+	}
+}
+
+func TestCleanOutdir(t *testing.T) {
+	write := func(t *testing.T, dir string, names ...string) {
+		for _, name := range names {
+			path := filepath.Join(dir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name     string
+		manifest []string // nil for no manifest
+		files    []string
+		want     []string
+	}{
+		{
+			name:  "by name",
+			files: []string{"index.html", "page1.html", "page1.png", "page2@2x.png", "f1.woff", "page1.pdf", "page2-notes.txt", "f1.ttf", "notes.md"},
+			want:  []string{"f1.ttf", "notes.md", "page1.pdf", "page2-notes.txt"},
+		},
+		{
+			name:     "by manifest",
+			manifest: []string{"page1.html", "f1.ttf", "attachments/a.txt", "../outside.txt", ".pdftohtml-outdir"},
+			files:    []string{"page1.html", "page2.html", "f1.ttf", "f2.ttf", "attachments/a.txt", "attachments/b.txt"},
+			want:     []string{"attachments", "attachments/b.txt", "f2.ttf", "page2.html"},
+		},
+		{
+			name:     "by manifest, emptied directories",
+			manifest: []string{"page1.html", "media/clips/a.mp4"},
+			files:    []string{"page1.html", "media/clips/a.mp4"},
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			outdir := filepath.Join(root, "out")
+			write(t, root, "outside.txt")
+			write(t, outdir, tt.files...)
+
+			if tt.manifest != nil {
+				m := &Manifest{}
+				for _, name := range tt.manifest {
+					m.Files = append(m.Files, ManifestFile{Name: name})
+				}
+				data, err := json.Marshal(m)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(outdir, ManifestName), data, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := cleanOutdir(outdir); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			err := filepath.WalkDir(outdir, func(path string, d fs.DirEntry, err error) error {
+				if err != nil || path == outdir {
+					return err
+				}
+				name, err := filepath.Rel(outdir, path)
+				got = append(got, filepath.ToSlash(name))
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("left %q, want %q", got, tt.want)
+			}
+
+			if _, err := os.Stat(filepath.Join(root, "outside.txt")); err != nil {
+				t.Errorf("file outside of the outdir removed: %v", err)
+			}
+		})
+	}
+}
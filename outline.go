@@ -0,0 +1,73 @@
+package pdftohtml
+
+// ----------------------------------------------------------------------------
+// -- document outline
+// ----------------------------------------------------------------------------
+
+// OutlineItem is the bookmark of the document outline.
+type OutlineItem struct {
+	Title    string        `json:"title"`
+	Page     int           `json:"page,omitempty"` // 0 if the destination is unknown
+	Top      float64       `json:"top,omitempty"`  // in pixels of the generated HTML
+	Children []OutlineItem `json:"children,omitempty"`
+}
+
+// Outline reads the outline, also known as bookmarks, of the PDF file, with the
+// destinations in the coordinates of the HTML generated by the command.
+//
+// The outline is read by the package itself, which does not read encrypted
+// files, see `ErrUnsupportedPDF`.
+func (c *Command) Outline(inpath string) ([]OutlineItem, error) {
+	r, err := openPDFFile(inpath)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := r.pages()
+	_, sy := c.pixelScale()
+	seen := make(map[pdfRef]bool)
+
+	var items func(first any, depth int) []OutlineItem
+	items = func(first any, depth int) []OutlineItem {
+		var list []OutlineItem
+
+		for next := first; next != nil && depth < pdfMaxDepth; {
+			// damaged outlines may loop
+			if ref, ok := next.(pdfRef); ok {
+				if seen[ref] {
+					break
+				}
+				seen[ref] = true
+			}
+
+			node := r.dict(next)
+			if node == nil {
+				break
+			}
+
+			item := OutlineItem{Title: r.text(node["Title"])}
+
+			dest := node["Dest"]
+			if dest == nil {
+				if action := r.dict(node["A"]); action["S"] == pdfName("GoTo") {
+					dest = action
+				}
+			}
+			if d, ok := r.destination(dest, pages); ok {
+				item.Page = d.page + 1
+				if d.hasTop {
+					item.Top = max(0, (pages[d.page].cropBox[3]-d.top)*sy)
+				}
+			}
+
+			item.Children = items(node["First"], depth+1)
+			list = append(list, item)
+
+			next = node["Next"]
+		}
+
+		return list
+	}
+
+	return items(r.dict(r.catalog()["Outlines"])["First"], 0), nil
+}
@@ -0,0 +1,238 @@
+package pdftohtml
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- page labels
+// ----------------------------------------------------------------------------
+
+// PageLabelsName is the name of the page labels list in the outdir.
+const PageLabelsName = "pagelabels.json"
+
+// pdfMaxLabel is the largest number formatted as roman numerals or letters,
+// larger ones are formatted as decimals.
+const pdfMaxLabel = 10000
+
+// PageLabels reads the page labels of the PDF file, e.g. "i", "ii", "A-1", in
+// page order. Labels are nil if the document does not define them.
+//
+// Labels are read by the package itself, which does not read encrypted files,
+// see `ErrUnsupportedPDF`.
+func (c *Command) PageLabels(inpath string) ([]string, error) {
+	r, err := openPDFFile(inpath)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.pageLabels(len(r.pages())), nil
+}
+
+// pageLabels returns the labels of the pages, from the /PageLabels number tree
+// of the catalog.
+func (r *pdfReader) pageLabels(count int) []string {
+	type labelRange struct {
+		first  int
+		style  pdfName
+		prefix string
+		start  int
+	}
+
+	var ranges []labelRange
+	r.numberTree(r.catalog()["PageLabels"], func(key int, val any) {
+		if key < 0 || key >= count {
+			return
+		}
+
+		dict := r.dict(val)
+		lr := labelRange{first: key, prefix: r.text(dict["P"]), start: 1}
+		lr.style, _ = r.resolve(dict["S"]).(pdfName)
+		if start, ok := r.number(dict["St"]); ok && start >= 1 {
+			lr.start = int(min(start, 1e9))
+		}
+		ranges = append(ranges, lr)
+	})
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].first < ranges[j].first
+	})
+
+	labels := make([]string, count)
+	for i := range labels {
+		// pages before the first range are numbered
+		lr := labelRange{style: "D", start: 1}
+		for _, next := range ranges {
+			if next.first > i {
+				break
+			}
+			lr = next
+		}
+
+		labels[i] = lr.prefix + formatPageLabel(lr.style, lr.start+i-lr.first)
+	}
+
+	return labels
+}
+
+// formatPageLabel formats the number in the numbering style of the page labels.
+// Labels without the style are the prefix alone.
+func formatPageLabel(style pdfName, n int) string {
+	switch style {
+	case "D":
+		return strconv.Itoa(n)
+	case "R", "r":
+		if n > pdfMaxLabel {
+			return strconv.Itoa(n)
+		}
+		roman := romanNumeral(n)
+		if style == "r" {
+			roman = strings.ToLower(roman)
+		}
+		return roman
+	case "A", "a":
+		if n > pdfMaxLabel {
+			return strconv.Itoa(n)
+		}
+		// A to Z, then AA to ZZ, and so on
+		letter := 'A' + rune((n-1)%26)
+		if style == "a" {
+			letter += 'a' - 'A'
+		}
+		return strings.Repeat(string(letter), (n-1)/26+1)
+	}
+
+	return ""
+}
+
+// romanNumeral returns the number in uppercase roman numerals.
+func romanNumeral(n int) string {
+	numerals := []struct {
+		value  int
+		symbol string
+	}{
+		{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"}, {100, "C"}, {90, "XC"},
+		{50, "L"}, {40, "XL"}, {10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+	}
+
+	var b strings.Builder
+	for _, num := range numerals {
+		for n >= num.value {
+			b.WriteString(num.symbol)
+			n -= num.value
+		}
+	}
+
+	return b.String()
+}
+
+// pageLabel returns the label of the page, or its number if it has none.
+func pageLabel(labels []string, n int) string {
+	if n > 0 && n <= len(labels) && labels[n-1] != "" {
+		return labels[n-1]
+	}
+
+	return strconv.Itoa(n)
+}
+
+// ReadPageLabels reads the page labels list written to the outdir.
+func ReadPageLabels(outdir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(outdir, PageLabelsName))
+	if err != nil {
+		return nil, err
+	}
+
+	var labels []string
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, err
+	}
+
+	return labels, nil
+}
+
+// insertPageLabels titles the pages in the outdir with their labels, and writes
+// the list of the labels.
+func insertPageLabels(outdir string, labels []string) error {
+	err := rewriteHTML(outdir, func(name string, data []byte) ([]byte, error) {
+		n := pageNumber(name)
+		if n == 0 || name != fmt.Sprintf("page%d.html", n) {
+			return data, nil
+		}
+
+		doc, xhtml := parseDocument(data)
+
+		title := doc.find("title")
+		if title == nil {
+			head := doc.find("head")
+			if head == nil {
+				return data, nil
+			}
+			title = &htmlNode{kind: elementNode, tag: "title"}
+			head.appendChild(title)
+		}
+
+		text := "Page " + pageLabel(labels, n)
+		if prev := strings.TrimSpace(title.textContent()); prev != "" {
+			text = prev + " - " + text
+		}
+		title.children = nil
+		title.appendChild(&htmlNode{kind: textNode, text: text})
+
+		return renderDocument(doc, xhtml), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(labels, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(outdir, PageLabelsName), data, 0o644)
+}
+
+// ----------------------------------------------------------------------------
+// -- page labels options
+// ----------------------------------------------------------------------------
+
+// Use the page labels of the PDF file, e.g. "i", "ii", "A-1", so converted
+// manuals keep their printed page numbering: pages are titled with their labels,
+// the page list of `WithViewer` and of `ExportEPUB` shows them, and
+// `pagelabels.json` lists the label of every page of the document.
+//
+// Files keep their `page<N>.html` names, which links and other stages rely on.
+// Map labels to files with `ReadPageLabels`, e.g. to route `/page/iv`.
+//
+// Labels are read by the package itself, labels of encrypted files are not
+// used.
+func WithPageLabels() option {
+	return func(c *Command) {
+		c.pageLabels = true
+		c.stages = append(c.stages, func(_ context.Context, inpath, outdir string) error {
+			labels, err := c.PageLabels(inpath)
+			if errors.Is(err, ErrUnsupportedPDF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if labels == nil {
+				return nil
+			}
+
+			return insertPageLabels(outdir, labels)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package pdftohtml
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// labeledPDF returns the PDF file of the pages with the /PageLabels number tree.
+func labeledPDF(pages int, labels string) []byte {
+	kids := make([]string, pages)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R /PageLabels " + labels + " >>",
+		"",
+	}
+	for i := range kids {
+		kids[i] = fmt.Sprintf("%d 0 R", i+3)
+		objects = append(objects, "<< /Type /Page /Parent 2 0 R >>")
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pages)
+
+	return pdfFile(fmt.Sprintf("<< /Size %d /Root 1 0 R >>", len(objects)+1), objects...)
+}
+
+func TestPageLabels(t *testing.T) {
+	tests := []struct {
+		name   string
+		pages  int
+		labels string
+		want   []string
+	}{
+		{"none", 2, "null", nil},
+		{"roman then decimal", 5, "<< /Nums [0 << /S /r >> 3 << /S /D >>] >>", []string{"i", "ii", "iii", "1", "2"}},
+		{"prefix and start", 3, "<< /Nums [0 << /S /D /P (A-) /St 7 >>] >>", []string{"A-7", "A-8", "A-9"}},
+		{"letters", 3, "<< /Nums [0 << /S /A /St 26 >>] >>", []string{"Z", "AA", "BB"}},
+		{"prefix only", 2, "<< /Nums [0 << /P (Cover) >> 1 << /S /R >>] >>", []string{"Cover", "I"}},
+		{"numbered before first range", 3, "<< /Nums [2 << /S /a >>] >>", []string{"1", "2", "a"}},
+		{"kids", 3, "<< /Kids [<< /Nums [1 << /S /D /St 10 >>] >>] >>", []string{"1", "10", "11"}},
+		{"out of range keys", 2, "<< /Nums [-1 << /S /r >> 5 << /S /r >>] >>", nil},
+		{"huge start", 1, "<< /Nums [0 << /S /R /St 1e300 >>] >>", []string{"1000000000"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newPDFReader(labeledPDF(tt.pages, tt.labels))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := r.pageLabels(len(r.pages())); !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRomanNumeral(t *testing.T) {
+	for n, want := range map[int]string{1: "I", 4: "IV", 9: "IX", 14: "XIV", 40: "XL", 1994: "MCMXCIV", 3999: "MMMCMXCIX"} {
+		if got := romanNumeral(n); got != want {
+			t.Errorf("romanNumeral(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestInsertPageLabels(t *testing.T) {
+	outdir := t.TempDir()
+	pages := map[string]string{
+		"page1.html": "<html><head><title>Manual</title></head><body></body></html>",
+		"page2.html": "<html><head></head><body></body></html>",
+	}
+	for name, data := range pages {
+		if err := os.WriteFile(filepath.Join(outdir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := insertPageLabels(outdir, []string{"i", "<ii>"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"page1.html": "<title>Manual - Page i</title>", "page2.html": "<title>Page &lt;ii&gt;</title>"} {
+		data, err := os.ReadFile(filepath.Join(outdir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s: %s does not contain %s", name, data, want)
+		}
+	}
+
+	labels, err := ReadPageLabels(outdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(labels, []string{"i", "<ii>"}) {
+		t.Errorf("got %q", labels)
+	}
+}
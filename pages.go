@@ -0,0 +1,116 @@
+package pdftohtml
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- page callback
+// ----------------------------------------------------------------------------
+
+// pagePollInterval is how often the outdir is checked for converted pages.
+const pagePollInterval = 100 * time.Millisecond
+
+// PageResult describes a single page as soon as it is converted.
+type PageResult struct {
+	Number int    // page number
+	HTML   string // path of the page HTML file
+	Image  string // path of the background image, empty if embedded
+}
+
+// watchPages runs the process and calls the page callback for every page, from
+// the first page converted, once it is converted.
+func (c *Command) watchPages(ctx context.Context, cmd *exec.Cmd, outdir string, first int, cancel func()) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	ticker := time.NewTicker(pagePollInterval)
+	defer ticker.Stop()
+
+	next := first
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				return err
+			}
+
+			// process finished, so did all pages
+			_, err = c.reportPages(ctx, outdir, next, true)
+			return err
+
+		case <-ticker.C:
+			var err error
+			next, err = c.reportPages(ctx, outdir, next, false)
+			if err != nil {
+				cancel()
+				<-done
+				return err
+			}
+		}
+	}
+}
+
+// reportPages calls the page callback for finished pages starting with the next
+// one, and returns the number of the first page not reported yet.
+func (c *Command) reportPages(ctx context.Context, outdir string, next int, final bool) (int, error) {
+	for ; ; next++ {
+		page := pageResult(outdir, next)
+		if !exists(page.HTML) {
+			return next, nil
+		}
+
+		// pages are converted in order, so a page is finished once any file of
+		// the following page appears
+		following := pageResult(outdir, next+1)
+		if !final && !exists(following.HTML) && !exists(following.Image) {
+			return next, nil
+		}
+
+		if !exists(page.Image) {
+			page.Image = ""
+		}
+
+		if err := c.onPage(ctx, page); err != nil {
+			return next, err
+		}
+	}
+}
+
+// pageResult returns paths of files generated for the page.
+func pageResult(outdir string, n int) PageResult {
+	return PageResult{
+		Number: n,
+		HTML:   filepath.Join(outdir, fmt.Sprintf("page%d.html", n)),
+		Image:  filepath.Join(outdir, fmt.Sprintf("page%d.png", n)),
+	}
+}
+
+// exists reports whether the file exists.
+func exists(path string) bool {
+	_, err := os.Stat(path)
+
+	return err == nil
+}
+
+// Call the callback for every page as soon as its files are in the outdir,
+// before post-processing stages are applied.
+//
+// Returning an error from the callback stops the conversion and fails the run
+// with that error.
+func WithPageCallback(fn func(ctx context.Context, page PageResult) error) option {
+	return func(c *Command) {
+		c.onPage = fn
+	}
+}
@@ -0,0 +1,279 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- page selection
+// ----------------------------------------------------------------------------
+
+// pageRange is the contiguous range of pages, up to the last page if to is 0.
+// Ranges of single runs of the executable may have own zoom.
+type pageRange struct {
+	from, to uint64
+	zoom     float64
+}
+
+// pageSelection is the list of disjoint ranges of pages in order. The empty
+// selection contains all pages.
+type pageSelection []pageRange
+
+// parsePages parses the page-list expression, e.g. "1,3,5-9,12-", into the
+// selection with overlapping and adjacent ranges merged.
+func parsePages(expr string) (pageSelection, error) {
+	invalid := fmt.Errorf("pdftohtml: invalid page selection %q", expr)
+
+	var ranges pageSelection
+	for _, item := range strings.Split(expr, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			return nil, invalid
+		}
+
+		from, to, isRange := strings.Cut(item, "-")
+		r := pageRange{from: 1}
+
+		var err error
+		if from = strings.TrimSpace(from); from != "" {
+			if r.from, err = strconv.ParseUint(from, 10, 64); err != nil || r.from == 0 {
+				return nil, invalid
+			}
+		}
+		switch to = strings.TrimSpace(to); {
+		case !isRange:
+			r.to = r.from
+		case to != "":
+			if r.to, err = strconv.ParseUint(to, 10, 64); err != nil || r.to < r.from {
+				return nil, invalid
+			}
+		case from == "":
+			return nil, invalid // lone "-"
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].from < ranges[j].from
+	})
+
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		switch {
+		case last.to == 0:
+			// the last range goes to the end already
+		case r.from <= last.to+1:
+			if r.to == 0 || r.to > last.to {
+				last.to = r.to
+			}
+		default:
+			merged = append(merged, r)
+		}
+	}
+
+	return merged, nil
+}
+
+// contains reports whether the page is selected.
+func (s pageSelection) contains(n uint64) bool {
+	if len(s) == 0 {
+		return true
+	}
+
+	for _, r := range s {
+		if n >= r.from && (r.to == 0 || n <= r.to) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reFontFile matches names of the extracted font files.
+var reFontFile = regexp.MustCompile(`^(ff?)(\d+)(\.(?:ttf|otf|woff2?))$`)
+
+// reIndexBody matches the content of the body of the index.
+var reIndexBody = regexp.MustCompile(`(?is)<body\b[^>]*>(.*)</body\s*>`)
+
+// convert runs the executable for every range of the selected pages, or every
+// chunk of them, or pages of the same zoom, and merges their output in the
+// outdir. Without the selection, chunks and the target width it is run once.
+// Pages changed since the last run of `WithIncremental` replace those in the
+// outdir instead.
+func (c *Command) convert(ctx context.Context, opts OptionSet, inpath, outdir string) error {
+	if record := recordFrom(ctx); record != nil && record.reconvert != nil {
+		return c.reconvert(ctx, opts, inpath, outdir, record.reconvert)
+	}
+
+	ranges := c.pages
+	if c.chunk > 0 || c.width > 0 {
+		var err error
+		if ranges, err = c.runRanges(ctx, opts, inpath); err != nil {
+			return err
+		}
+	}
+	if len(ranges) == 0 {
+		return c.exec(ctx, opts, inpath, outdir)
+	}
+
+	opts.PageFrom, opts.PageTo = ranges[0].from, ranges[0].to
+	if ranges[0].zoom > 0 {
+		opts.Zoom = ranges[0].zoom
+	}
+	if err := c.exec(ctx, opts, inpath, outdir); err != nil {
+		return err
+	}
+
+	tmpdir, err := os.MkdirTemp(filepath.Dir(outdir), ".pdftohtml-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// pages of the extra runs are reported once merged
+	aux := *c
+	aux.onPage = nil
+
+	for i, r := range ranges[1:] {
+		opts.PageFrom, opts.PageTo = r.from, r.to
+		if r.zoom > 0 {
+			opts.Zoom = r.zoom
+		}
+
+		rundir := filepath.Join(tmpdir, strconv.Itoa(i))
+		if err := aux.exec(ctx, opts, inpath, rundir); err != nil {
+			return err
+		}
+
+		if err := mergeOutput(rundir, outdir); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(rundir); err != nil {
+			return err
+		}
+
+		if c.onPage != nil {
+			if _, err := c.reportPages(ctx, outdir, int(r.from), true); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// mergeOutput moves the output of the extra run into the outdir. Font files are
+// renumbered to not collide with fonts of other runs, and the pages are appended
+// to the index.
+func mergeOutput(rundir, outdir string) error {
+	entries, err := os.ReadDir(outdir)
+	if err != nil {
+		return err
+	}
+	used := make(map[string]bool, len(entries))
+	next := 0
+	for _, entry := range entries {
+		used[entry.Name()] = true
+		if match := reFontFile.FindStringSubmatch(entry.Name()); match != nil {
+			n, _ := strconv.Atoi(match[2])
+			next = max(next, n+1)
+		}
+	}
+
+	fonts, err := os.ReadDir(rundir)
+	if err != nil {
+		return err
+	}
+	replace := make(map[string]string)
+	for _, entry := range fonts {
+		match := reFontFile.FindStringSubmatch(entry.Name())
+		if match == nil || !used[entry.Name()] {
+			continue
+		}
+
+		name := fmt.Sprintf("%s%d%s", match[1], next, match[3])
+		next++
+
+		if err := os.Rename(filepath.Join(rundir, entry.Name()), filepath.Join(rundir, name)); err != nil {
+			return err
+		}
+		replace[entry.Name()] = name
+	}
+	if len(replace) > 0 {
+		if err := rewriteReferences(rundir, replace); err != nil {
+			return err
+		}
+	}
+
+	index, err := os.ReadFile(filepath.Join(rundir, "index.html"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	files, err := os.ReadDir(rundir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range files {
+		if entry.Name() == "index.html" {
+			continue
+		}
+
+		if err := os.Rename(filepath.Join(rundir, entry.Name()), filepath.Join(outdir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	match := reIndexBody.FindSubmatch(index)
+	if match == nil {
+		return nil
+	}
+
+	path := filepath.Join(outdir, "index.html")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, insertBodyEnd(data, match[1]), 0o644)
+}
+
+// ----------------------------------------------------------------------------
+// -- page selection options
+// ----------------------------------------------------------------------------
+
+// Specifies the pages to convert with the page-list expression: page numbers
+// and ranges separated by commas, e.g. "1,3,5-9,12-", where "12-" is page 12 to
+// the last one and "-4" the first four pages.
+//
+// `pdftohtml` converts the contiguous range only, so it is run for every range
+// and the outputs are merged: pages keep their numbers, and the index lists all
+// of them. This overrides `WithPageRange`.
+func WithPages(expr string) option {
+	return func(c *Command) {
+		pages, err := parsePages(expr)
+		if err != nil {
+			c.errs = append(c.errs, err)
+			return
+		}
+
+		// the single range is supported by the executable itself
+		c.opts.PageFrom, c.opts.PageTo = pages[0].from, pages[0].to
+		c.pages = nil
+		if len(pages) > 1 {
+			c.opts.PageFrom, c.opts.PageTo = 0, 0
+			c.pages = pages
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package pdftohtml
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// ----------------------------------------------------------------------------
+// -- partial output
+// ----------------------------------------------------------------------------
+
+// PartialError is returned when the conversion was interrupted, but the pages
+// converted so far were kept in the outdir.
+type PartialError struct {
+	Pages []int // numbers of fully converted pages
+	Err   error // reason of the interruption
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("pdftohtml: partial output with %d pages: %v", len(e.Pages), e.Err)
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+// rePageFile matches names of all files generated for a page, e.g. `page1.png`.
+var rePageFile = regexp.MustCompile(`^page(\d+)\.[a-z]+$`)
+
+// salvage removes pages the conversion did not finish and marks the rest of the
+// output as partial.
+func (c *Command) salvage(inpath, outdir string, cause error) error {
+	entries, err := os.ReadDir(outdir)
+	if errors.Is(err, os.ErrNotExist) {
+		return cause
+	}
+	if err != nil {
+		return err
+	}
+
+	// pages are converted in order, so a page is finished once any file of the
+	// following page appears
+	files := make(map[int][]string)
+	last := 0
+	for _, entry := range entries {
+		match := rePageFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		n, _ := strconv.Atoi(match[1])
+		files[n] = append(files[n], entry.Name())
+		last = max(last, n)
+	}
+
+	var pages []int
+	for n := 1; n <= last; n++ {
+		_, html := os.Stat(filepath.Join(outdir, fmt.Sprintf("page%d.html", n)))
+		if n < last && html == nil {
+			pages = append(pages, n)
+			continue
+		}
+
+		for _, name := range files[n] {
+			if err := os.Remove(filepath.Join(outdir, name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	m, err := newManifest(inpath, outdir)
+	if err != nil {
+		return err
+	}
+	m.Partial = true
+
+	if err := m.write(outdir); err != nil {
+		return err
+	}
+
+	if err := c.applyOwnership(outdir); err != nil {
+		return err
+	}
+
+	return &PartialError{Pages: pages, Err: cause}
+}
+
+// Keep pages converted so far when the conversion is cancelled or times out.
+//
+// Unfinished pages are removed, and the output is described by a manifest marked
+// as partial. Post-processing stages are not applied to partial output. The run
+// fails with `PartialError` listing the kept pages.
+func WithPartialOutput() option {
+	return func(c *Command) {
+		c.partial = true
+	}
+}
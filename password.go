@@ -0,0 +1,60 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"context"
+	"errors"
+)
+
+// ----------------------------------------------------------------------------
+// -- password candidates
+// ----------------------------------------------------------------------------
+
+// ErrIncorrectPassword is returned when the PDF file cannot be opened with the
+// given password, or any of the password candidates.
+var ErrIncorrectPassword = errors.New("pdftohtml: incorrect password")
+
+// isIncorrectPassword reports whether the error output of the executable is the
+// one of the wrong password failure.
+func isIncorrectPassword(stderr []byte) bool {
+	return bytes.Contains(bytes.ToLower(stderr), []byte("incorrect password"))
+}
+
+// unlock runs the executable with the options and the password candidates in
+// order, until one of them opens the PDF file, and returns it. Without candidates
+// the executable is run once with the passwords of the options.
+func (c *Command) unlock(ctx context.Context, opts OptionSet, inpath, outdir string) (string, error) {
+	if len(c.passwords) == 0 {
+		return "", c.convert(ctx, opts, inpath, outdir)
+	}
+
+	for _, password := range c.passwords {
+		// the PDF file is opened with either of the passwords
+		try := opts
+		try.OwnerPassword, try.UserPassword = password, password
+
+		err := c.convert(ctx, try, inpath, outdir)
+		if !errors.Is(err, ErrIncorrectPassword) {
+			return password, err
+		}
+	}
+
+	return "", ErrIncorrectPassword
+}
+
+// TryPasswords executes the command like `Run`, and returns the password candidate
+// that opened the PDF file. The password is empty without candidates.
+func (c *Command) TryPasswords(ctx context.Context, inpath, outdir string) (string, error) {
+	return c.run(ctx, inpath, outdir)
+}
+
+// Try the passwords in order, until one of them opens the PDF file. Each one is
+// tried both as the owner and the user password, and overrides the passwords set
+// with `WithOwnerPassword` and `WithUserPassword`.
+//
+// Use `Command.TryPasswords` to find out which one succeeded.
+func WithPasswordCandidates(passwords ...string) option {
+	return func(c *Command) {
+		c.passwords = append(c.passwords, passwords...)
+	}
+}
@@ -0,0 +1,45 @@
+package pdftohtml
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- path validation
+// ----------------------------------------------------------------------------
+
+// ErrInvalidPath is returned for input or output paths that cannot be used on
+// the current platform.
+var ErrInvalidPath = errors.New("pdftohtml: invalid path")
+
+// checkPath asserts that the path can be passed to the executable and used on
+// the current platform.
+func checkPath(path string) error {
+	if path == "" {
+		return fmt.Errorf("%w: empty path", ErrInvalidPath)
+	}
+	if strings.ContainsRune(path, 0) {
+		return fmt.Errorf("%w: %q contains NUL character", ErrInvalidPath, path)
+	}
+
+	rest := strings.TrimPrefix(path, filepath.VolumeName(path))
+	for _, elem := range strings.FieldsFunc(rest, isSeparator) {
+		if elem == "." || elem == ".." {
+			continue
+		}
+
+		if err := checkPathElem(elem); err != nil {
+			return fmt.Errorf("%w: %q: %w", ErrInvalidPath, path, err)
+		}
+	}
+
+	return nil
+}
+
+// isSeparator reports whether the rune separates path elements.
+func isSeparator(r rune) bool {
+	return r == '/' || r == filepath.Separator
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package pdftohtml
+
+// checkPathElem asserts that the single path element is a valid name. Any name
+// without a separator is valid outside of Windows.
+func checkPathElem(elem string) error {
+	return nil
+}
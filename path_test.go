@@ -0,0 +1,34 @@
+package pdftohtml
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckPath(t *testing.T) {
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"", false},
+		{"a\x00b.pdf", false},
+		{"docs/a.pdf", true},
+		{"./docs/../a.pdf", true},
+		{"/tmp/out dir/a b.pdf", true},
+		{"dokumenty/żółć.pdf", true},
+		{"文書/報告.pdf", true},
+		{"-v.pdf", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			err := checkPath(tt.path)
+			if tt.ok && err != nil {
+				t.Errorf("checkPath(%q) = %v, want nil", tt.path, err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidPath) {
+				t.Errorf("checkPath(%q) = %v, want ErrInvalidPath", tt.path, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+//go:build windows
+
+package pdftohtml
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// reservedNames are device names Windows refuses as file names, with or
+// without an extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// checkPathElem asserts that the single path element is a valid Windows name.
+func checkPathElem(elem string) error {
+	if !utf8.ValidString(elem) {
+		return errors.New("names must be valid UTF-8")
+	}
+
+	base, _, _ := strings.Cut(elem, ".")
+	if reservedNames[strings.ToUpper(strings.TrimRight(base, " "))] {
+		return fmt.Errorf("%q is a reserved name", elem)
+	}
+
+	if strings.HasSuffix(elem, ".") || strings.HasSuffix(elem, " ") {
+		return errors.New("names cannot end with a dot or a space")
+	}
+
+	if i := strings.IndexAny(elem, `<>:"|?*`); i >= 0 {
+		return fmt.Errorf("names cannot contain %q", elem[i])
+	}
+
+	for _, r := range elem {
+		if r < 32 {
+			return errors.New("names cannot contain control characters")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+//go:build windows
+
+package pdftohtml
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckPathWindows(t *testing.T) {
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{`C:\docs\a.pdf`, true},
+		{`\\server\share\a.pdf`, true},
+		{`C:\out dir\żółć.pdf`, true},
+		{`docs\CON`, false},
+		{`docs\con.pdf`, false},
+		{`docs\LPT1.txt`, false},
+		{`docs\COM9`, false},
+		{`docs\CON \a.pdf`, false},
+		{`docs\CONSOLE.pdf`, true},
+		{`docs\a.`, false},
+		{`docs\a `, false},
+		{`docs.\a.pdf`, false},
+		{`docs\a<b.pdf`, false},
+		{`docs\a:b.pdf`, false},
+		{`docs\a?.pdf`, false},
+		{"docs\\a\x01.pdf", false},
+		{"docs\\a\xff.pdf", false},
+		{"docs\\a\x00.pdf", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			err := checkPath(tt.path)
+			if tt.ok && err != nil {
+				t.Errorf("checkPath(%q) = %v, want nil", tt.path, err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidPath) {
+				t.Errorf("checkPath(%q) = %v, want ErrInvalidPath", tt.path, err)
+			}
+		})
+	}
+}
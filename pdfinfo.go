@@ -0,0 +1,77 @@
+package pdftohtml
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// ----------------------------------------------------------------------------
+// -- `pdfinfo`
+// ----------------------------------------------------------------------------
+
+// Info describes the PDF file as reported by Xpdf `pdfinfo`.
+type Info struct {
+	Pages  int     // number of pages
+	Width  float64 // width of the first page, in points
+	Height float64 // height of the first page, in points
+}
+
+var (
+	reInfoPages = regexp.MustCompile(`(?m)^Pages:\s+(\d+)`)
+	reInfoSize  = regexp.MustCompile(`(?m)^Page size:\s+([\d.]+) x ([\d.]+) pts`)
+)
+
+// Info reads information about the PDF file with Xpdf `pdfinfo` executable, found
+// next to the `pdftohtml` executable or in the PATH. Passwords of the command
+// are passed on.
+func (c *Command) Info(ctx context.Context, inpath string) (*Info, error) {
+	path, err := c.toolPath("pdfinfo")
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	if c.opts.OwnerPassword != "" {
+		args = append(args, "-opw", c.opts.OwnerPassword)
+	}
+	if c.opts.UserPassword != "" {
+		args = append(args, "-upw", c.opts.UserPassword)
+	}
+
+	cmd := exec.CommandContext(ctx, path, append(args, "--", inpath)...)
+	cmd.Env = c.environ()
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	match := reInfoPages.FindSubmatch(out)
+	if match == nil {
+		return nil, fmt.Errorf("pdftohtml: cannot read page count of %q", inpath)
+	}
+
+	info := &Info{}
+	info.Pages, _ = strconv.Atoi(string(match[1]))
+
+	if match := reInfoSize.FindSubmatch(out); match != nil {
+		info.Width, _ = strconv.ParseFloat(string(match[1]), 64)
+		info.Height, _ = strconv.ParseFloat(string(match[2]), 64)
+	}
+
+	return info, nil
+}
+
+// toolPath looks up other Xpdf executable, preferring the one installed next to
+// the `pdftohtml` executable.
+func (c *Command) toolPath(name string) (string, error) {
+	if path, err := exec.LookPath(filepath.Join(filepath.Dir(c.path), name)); err == nil {
+		return path, nil
+	}
+
+	return exec.LookPath(name)
+}
@@ -0,0 +1,1241 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"strconv"
+	"unicode/utf16"
+)
+
+// ----------------------------------------------------------------------------
+// -- pdf reading
+// ----------------------------------------------------------------------------
+
+// The reader below is deliberately small: it reads the object structure of the
+// PDF file for pre-flight checks and metadata the Xpdf tools do not report. It
+// reads cross-reference tables and streams, object streams and the common
+// filters, and rebuilds the cross-reference of damaged files, but it does not
+// decrypt anything, nor interpret content streams.
+
+// ErrUnsupportedPDF is returned when the PDF file can't be read by the package
+// itself, e.g. it is encrypted.
+var ErrUnsupportedPDF = errors.New("pdftohtml: unsupported PDF file")
+
+type (
+	pdfName   string
+	pdfString string
+	pdfArray  []any
+	pdfDict   map[pdfName]any
+)
+
+// pdfRef is the reference to the indirect object.
+type pdfRef struct {
+	num, gen int
+}
+
+// pdfStream is the stream object with the undecoded data.
+type pdfStream struct {
+	dict pdfDict
+	raw  []byte
+}
+
+// pdfXref is the location of the indirect object: the offset in the file, or
+// the object stream and the index in it.
+type pdfXref struct {
+	offset     int64
+	stream     int
+	index      int
+	compressed bool
+}
+
+// pdfReader reads objects of the PDF file held in memory.
+type pdfReader struct {
+	data    []byte
+	xref    map[int]pdfXref
+	trailer pdfDict
+	objects map[int]any
+	depth   int // nesting of resolved objects, to break reference cycles
+}
+
+// pdfMaxDepth bounds the nesting of objects resolved while reading one.
+const pdfMaxDepth = 64
+
+// pdfMaxDecoded bounds the size of the decoded stream, so small compressed
+// streams can't exhaust the memory.
+const pdfMaxDecoded = 256 << 20
+
+// openPDFFile reads the PDF file.
+func openPDFFile(path string) (*pdfReader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPDFReader(data)
+}
+
+// newPDFReader reads the cross-reference of the PDF file, rebuilding it from the
+// objects in the file if it is damaged.
+func newPDFReader(data []byte) (*pdfReader, error) {
+	if !bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-")) {
+		return nil, fmt.Errorf("%w: missing header", ErrUnsupportedPDF)
+	}
+
+	r := &pdfReader{data: data, xref: make(map[int]pdfXref), objects: make(map[int]any)}
+	if err := r.readXrefs(); err != nil || r.trailer["Root"] == nil {
+		r.xref, r.trailer = make(map[int]pdfXref), nil
+		r.rebuildXref()
+	}
+
+	if r.trailer["Root"] == nil {
+		return nil, fmt.Errorf("%w: missing catalog", ErrUnsupportedPDF)
+	}
+	if r.trailer["Encrypt"] != nil {
+		return nil, fmt.Errorf("%w: encrypted", ErrUnsupportedPDF)
+	}
+
+	return r, nil
+}
+
+// reStartXref matches the offset of the last cross-reference section.
+var reStartXref = regexp.MustCompile(`startxref\s+(\d+)`)
+
+// readXrefs reads the chain of cross-reference sections from the last one.
+func (r *pdfReader) readXrefs() error {
+	tail := r.data[max(0, len(r.data)-2048):]
+
+	matches := reStartXref.FindAllSubmatch(tail, -1)
+	if matches == nil {
+		return errors.New("missing startxref")
+	}
+	offset, _ := strconv.ParseInt(string(matches[len(matches)-1][1]), 10, 64)
+
+	seen := make(map[int64]bool)
+	for offset > 0 && !seen[offset] {
+		seen[offset] = true
+
+		trailer, err := r.readXref(offset)
+		if err != nil {
+			return err
+		}
+
+		if r.trailer == nil {
+			r.trailer = trailer
+		}
+
+		// hybrid files have the stream next to the table
+		if stm, ok := trailer["XRefStm"].(int64); ok && !seen[stm] {
+			seen[stm] = true
+			if _, err := r.readXref(stm); err != nil {
+				return err
+			}
+		}
+
+		prev, _ := trailer["Prev"].(int64)
+		offset = prev
+	}
+
+	return nil
+}
+
+// readXref reads the cross-reference table or stream at the offset, keeping
+// entries read from newer sections, and returns its trailer.
+func (r *pdfReader) readXref(offset int64) (pdfDict, error) {
+	if offset < 0 || offset >= int64(len(r.data)) {
+		return nil, errors.New("xref offset out of range")
+	}
+
+	lex := &pdfLexer{data: r.data, pos: int(offset)}
+	lex.skipSpace()
+	if !bytes.HasPrefix(r.data[lex.pos:], []byte("xref")) {
+		return r.readXrefStream(lex)
+	}
+	lex.pos += len("xref")
+
+	for {
+		lex.skipSpace()
+		if bytes.HasPrefix(r.data[lex.pos:], []byte("trailer")) {
+			lex.pos += len("trailer")
+			break
+		}
+
+		first, err1 := lex.readInt()
+		count, err2 := lex.readInt()
+		if err1 != nil || err2 != nil {
+			return nil, errors.New("malformed xref subsection")
+		}
+
+		for i := range count {
+			off, err1 := lex.readInt()
+			_, err2 := lex.readInt()
+			kind, err3 := lex.readKeyword()
+			if err1 != nil || err2 != nil || err3 != nil {
+				return nil, errors.New("malformed xref entry")
+			}
+
+			if _, ok := r.xref[first+i]; !ok && kind == "n" {
+				r.xref[first+i] = pdfXref{offset: int64(off)}
+			}
+		}
+	}
+
+	obj, err := lex.readObject()
+	if err != nil {
+		return nil, err
+	}
+	trailer, ok := obj.(pdfDict)
+	if !ok {
+		return nil, errors.New("malformed trailer")
+	}
+
+	return trailer, nil
+}
+
+// readXrefStream reads the cross-reference stream at the position of the lexer.
+func (r *pdfReader) readXrefStream(lex *pdfLexer) (pdfDict, error) {
+	_, obj, err := r.readIndirect(lex)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, ok := obj.(*pdfStream)
+	if !ok || stream.dict["Type"] != pdfName("XRef") {
+		return nil, errors.New("malformed xref stream")
+	}
+
+	data, err := r.decode(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	// fields are at most 8 bytes wide, so they fit int64
+	var widths []int
+	for _, w := range r.array(stream.dict["W"]) {
+		n, _ := r.resolve(w).(int64)
+		if n < 0 || n > 8 {
+			return nil, errors.New("malformed xref stream widths")
+		}
+		widths = append(widths, int(n))
+	}
+	if len(widths) != 3 {
+		return nil, errors.New("malformed xref stream widths")
+	}
+
+	size, _ := r.resolve(stream.dict["Size"]).(int64)
+	index := []int64{0, size}
+	if arr := r.array(stream.dict["Index"]); len(arr) > 0 {
+		index = index[:0]
+		for _, v := range arr {
+			n, _ := r.resolve(v).(int64)
+			index = append(index, n)
+		}
+	}
+
+	field := func(b []byte, def int64) int64 {
+		if len(b) == 0 {
+			return def
+		}
+
+		var v int64
+		for _, c := range b {
+			v = v<<8 | int64(c)
+		}
+
+		return v
+	}
+
+	entry := widths[0] + widths[1] + widths[2]
+	pos := 0
+	for i := 0; i+1 < len(index); i += 2 {
+		for num := index[i]; num < index[i]+index[i+1]; num++ {
+			if entry == 0 || pos+entry > len(data) {
+				break
+			}
+			b := data[pos : pos+entry]
+			pos += entry
+
+			kind := field(b[:widths[0]], 1)
+			f2 := field(b[widths[0]:widths[0]+widths[1]], 0)
+			f3 := field(b[widths[0]+widths[1]:], 0)
+
+			if _, ok := r.xref[int(num)]; ok {
+				continue
+			}
+			switch kind {
+			case 1:
+				r.xref[int(num)] = pdfXref{offset: f2}
+			case 2:
+				r.xref[int(num)] = pdfXref{stream: int(f2), index: int(f3), compressed: true}
+			}
+		}
+	}
+
+	return stream.dict, nil
+}
+
+// reObjectHeader matches the beginning of the indirect object.
+var reObjectHeader = regexp.MustCompile(`(?m)(?:^|[\r\n\s])(\d+)\s+(\d+)\s+obj\b`)
+
+// reTrailer matches the trailer dictionary of the cross-reference table.
+var reTrailer = regexp.MustCompile(`trailer\s*<<`)
+
+// rebuildXref finds all objects in the file, the last definition of an object
+// wins, and reconstructs the trailer.
+func (r *pdfReader) rebuildXref() {
+	for _, loc := range reObjectHeader.FindAllSubmatchIndex(r.data, -1) {
+		num, _ := strconv.Atoi(string(r.data[loc[2]:loc[3]]))
+		r.xref[num] = pdfXref{offset: int64(loc[2])}
+	}
+
+	// objects of object streams are found in their headers
+	for num := range maps.Clone(r.xref) {
+		stream, ok := r.object(num).(*pdfStream)
+		if !ok || stream.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+
+		data, err := r.decode(stream)
+		if err != nil {
+			continue
+		}
+
+		n, _ := r.resolve(stream.dict["N"]).(int64)
+		lex := &pdfLexer{data: data}
+		for i := range int(n) {
+			obj, err1 := lex.readInt()
+			_, err2 := lex.readInt()
+			if err1 != nil || err2 != nil {
+				break
+			}
+			if _, ok := r.xref[obj]; !ok {
+				r.xref[obj] = pdfXref{stream: num, index: i, compressed: true}
+			}
+		}
+	}
+
+	for _, loc := range reTrailer.FindAllIndex(r.data, -1) {
+		lex := &pdfLexer{data: r.data, pos: loc[1] - 2}
+		if obj, err := lex.readObject(); err == nil {
+			if dict, ok := obj.(pdfDict); ok && dict["Root"] != nil {
+				r.trailer = dict
+			}
+		}
+	}
+	if r.trailer != nil {
+		return
+	}
+
+	// files with cross-reference streams only have the catalog to find
+	r.trailer = pdfDict{}
+	for num := range r.xref {
+		obj := r.object(num)
+		if dict := r.dict(obj); dict["Type"] == pdfName("Catalog") {
+			r.trailer["Root"] = pdfRef{num: num}
+		}
+		if stream, ok := obj.(*pdfStream); ok && stream.dict["Type"] == pdfName("XRef") {
+			for _, key := range []pdfName{"Root", "Info", "Encrypt"} {
+				if v, ok := stream.dict[key]; ok {
+					r.trailer[key] = v
+				}
+			}
+		}
+	}
+}
+
+// readIndirect reads the indirect object at the position of the lexer.
+func (r *pdfReader) readIndirect(lex *pdfLexer) (int, any, error) {
+	num, err1 := lex.readInt()
+	_, err2 := lex.readInt()
+	kw, err3 := lex.readKeyword()
+	if err1 != nil || err2 != nil || err3 != nil || kw != "obj" {
+		return 0, nil, errors.New("malformed indirect object")
+	}
+
+	obj, err := lex.readObject()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	dict, ok := obj.(pdfDict)
+	if !ok {
+		return num, obj, nil
+	}
+
+	lex.skipSpace()
+	if !bytes.HasPrefix(r.data[lex.pos:], []byte("stream")) {
+		return num, obj, nil
+	}
+	lex.pos += len("stream")
+
+	// the data starts after the end of line
+	if bytes.HasPrefix(r.data[lex.pos:], []byte("\r\n")) {
+		lex.pos += 2
+	} else if lex.pos < len(r.data) && (r.data[lex.pos] == '\n' || r.data[lex.pos] == '\r') {
+		lex.pos++
+	}
+	start := lex.pos
+
+	length := int64(-1)
+	switch v := dict["Length"].(type) {
+	case int64:
+		length = v
+	case pdfRef:
+		// the length object is read later, after this one is finished
+		if n, ok := r.resolve(v).(int64); ok {
+			length = n
+		}
+	}
+
+	// length is compared before the addition, so it can't overflow
+	end := -1
+	if length >= 0 && length <= int64(len(r.data)-start) {
+		end = start + int(length)
+	}
+	if end < 0 || !bytes.Contains(r.data[end:min(len(r.data), end+32)], []byte("endstream")) {
+		i := bytes.Index(r.data[start:], []byte("endstream"))
+		if i < 0 {
+			return 0, nil, errors.New("unterminated stream")
+		}
+		end = start + i
+	}
+
+	return num, &pdfStream{dict: dict, raw: r.data[start:end]}, nil
+}
+
+// object returns the indirect object, or nil if it does not exist.
+func (r *pdfReader) object(num int) any {
+	if obj, ok := r.objects[num]; ok {
+		return obj
+	}
+
+	if r.depth > pdfMaxDepth {
+		return nil
+	}
+	r.depth++
+	defer func() { r.depth-- }()
+
+	// objects being read are nil for references back to them
+	r.objects[num] = nil
+
+	var obj any
+	if x, ok := r.xref[num]; ok {
+		if x.compressed {
+			obj = r.compressedObject(x.stream, x.index)
+		} else if x.offset >= 0 && x.offset < int64(len(r.data)) {
+			if n, o, err := r.readIndirect(&pdfLexer{data: r.data, pos: int(x.offset)}); err == nil && n == num {
+				obj = o
+			}
+		}
+	}
+	r.objects[num] = obj
+
+	return obj
+}
+
+// compressedObject returns the object at the index in the object stream.
+func (r *pdfReader) compressedObject(num, index int) any {
+	stream, ok := r.object(num).(*pdfStream)
+	if !ok {
+		return nil
+	}
+
+	data, err := r.decode(stream)
+	if err != nil {
+		return nil
+	}
+
+	n, _ := r.resolve(stream.dict["N"]).(int64)
+	first, _ := r.resolve(stream.dict["First"]).(int64)
+	if index < 0 || index >= int(n) || first < 0 || first > int64(len(data)) {
+		return nil
+	}
+
+	// the header lists pairs of object numbers and offsets
+	lex := &pdfLexer{data: data}
+	var offset int
+	for i := 0; i <= index; i++ {
+		_, err1 := lex.readInt()
+		off, err2 := lex.readInt()
+		if err1 != nil || err2 != nil {
+			return nil
+		}
+		offset = off
+	}
+
+	// offset is compared before the addition, so it can't overflow
+	if offset < 0 || offset > len(data)-int(first) {
+		return nil
+	}
+	lex = &pdfLexer{data: data, pos: int(first) + offset}
+	obj, err := lex.readObject()
+	if err != nil {
+		return nil
+	}
+
+	return obj
+}
+
+// resolve returns the object the reference points to, or the object itself.
+func (r *pdfReader) resolve(obj any) any {
+	for range pdfMaxDepth {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = r.object(ref.num)
+	}
+
+	return nil
+}
+
+// dict returns the resolved dictionary, or the dictionary of the stream.
+func (r *pdfReader) dict(obj any) pdfDict {
+	switch v := r.resolve(obj).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+
+	return nil
+}
+
+// array returns the resolved array.
+func (r *pdfReader) array(obj any) pdfArray {
+	arr, _ := r.resolve(obj).(pdfArray)
+
+	return arr
+}
+
+// number returns the resolved integer or real number.
+func (r *pdfReader) number(obj any) (float64, bool) {
+	switch v := r.resolve(obj).(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+
+	return 0, false
+}
+
+// rect returns the resolved rectangle, normalized so the first corner is the
+// lower left one.
+func (r *pdfReader) rect(obj any) ([4]float64, bool) {
+	arr := r.array(obj)
+	if len(arr) != 4 {
+		return [4]float64{}, false
+	}
+
+	var rect [4]float64
+	for i, v := range arr {
+		n, ok := r.number(v)
+		if !ok {
+			return [4]float64{}, false
+		}
+		rect[i] = n
+	}
+
+	if rect[0] > rect[2] {
+		rect[0], rect[2] = rect[2], rect[0]
+	}
+	if rect[1] > rect[3] {
+		rect[1], rect[3] = rect[3], rect[1]
+	}
+
+	return rect, true
+}
+
+// text returns the resolved text string, decoded from UTF-16 or PDFDocEncoding.
+func (r *pdfReader) text(obj any) string {
+	s, ok := r.resolve(obj).(pdfString)
+	if !ok {
+		return ""
+	}
+
+	b := []byte(s)
+	if len(b) >= 2 && b[0] == 0xfe && b[1] == 0xff {
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+
+		return string(utf16.Decode(units))
+	}
+	if len(b) >= 3 && b[0] == 0xef && b[1] == 0xbb && b[2] == 0xbf {
+		return string(b[3:])
+	}
+
+	// PDFDocEncoding matches Latin-1 for printable characters
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+
+	return string(runes)
+}
+
+// nameTree calls fn for every entry of the name tree, in order.
+func (r *pdfReader) nameTree(root any, fn func(name string, val any)) {
+	var walk func(node pdfDict, depth int)
+	walk = func(node pdfDict, depth int) {
+		if node == nil || depth > pdfMaxDepth {
+			return
+		}
+
+		names := r.array(node["Names"])
+		for i := 0; i+1 < len(names); i += 2 {
+			fn(r.text(names[i]), names[i+1])
+		}
+
+		for _, kid := range r.array(node["Kids"]) {
+			walk(r.dict(kid), depth+1)
+		}
+	}
+
+	walk(r.dict(root), 0)
+}
+
+// numberTree calls fn for every entry of the number tree, in order.
+func (r *pdfReader) numberTree(root any, fn func(key int, val any)) {
+	var walk func(node pdfDict, depth int)
+	walk = func(node pdfDict, depth int) {
+		if node == nil || depth > pdfMaxDepth {
+			return
+		}
+
+		nums := r.array(node["Nums"])
+		for i := 0; i+1 < len(nums); i += 2 {
+			if key, ok := r.resolve(nums[i]).(int64); ok {
+				fn(int(key), nums[i+1])
+			}
+		}
+
+		for _, kid := range r.array(node["Kids"]) {
+			walk(r.dict(kid), depth+1)
+		}
+	}
+
+	walk(r.dict(root), 0)
+}
+
+// catalog returns the document catalog.
+func (r *pdfReader) catalog() pdfDict {
+	return r.dict(r.trailer["Root"])
+}
+
+// pdfPage is the page with the inherited attributes applied.
+type pdfPage struct {
+	ref      pdfRef // zero for direct page objects
+	dict     pdfDict
+	mediaBox [4]float64
+	cropBox  [4]float64
+	rotate   int
+}
+
+// pages returns the pages in order.
+func (r *pdfReader) pages() []pdfPage {
+	var pages []pdfPage
+
+	seen := make(map[pdfRef]bool)
+
+	var walk func(ref pdfRef, node pdfDict, inherited pdfPage, depth int)
+	walk = func(ref pdfRef, node pdfDict, inherited pdfPage, depth int) {
+		if node == nil || depth > pdfMaxDepth {
+			return
+		}
+
+		if box, ok := r.rect(node["MediaBox"]); ok {
+			inherited.mediaBox = box
+		}
+		if box, ok := r.rect(node["CropBox"]); ok {
+			inherited.cropBox = box
+		}
+		if rotate, ok := r.number(node["Rotate"]); ok {
+			inherited.rotate = ((int(rotate) % 360) + 360) % 360
+		}
+
+		kids, ok := node["Kids"]
+		if !ok || node["Type"] == pdfName("Page") {
+			page := inherited
+			page.ref, page.dict = ref, node
+			if page.cropBox == [4]float64{} {
+				page.cropBox = page.mediaBox
+			}
+			pages = append(pages, page)
+			return
+		}
+
+		for _, kid := range r.array(kids) {
+			// damaged page trees may list the same page twice
+			ref, _ := kid.(pdfRef)
+			if ref != (pdfRef{}) {
+				if seen[ref] {
+					continue
+				}
+				seen[ref] = true
+			}
+
+			walk(ref, r.dict(kid), inherited, depth+1)
+		}
+	}
+
+	// letter size is the default of the Xpdf tools
+	walk(pdfRef{}, r.dict(r.catalog()["Pages"]), pdfPage{mediaBox: [4]float64{0, 0, 612, 792}}, 0)
+
+	return pages
+}
+
+// pdfDest is the resolved destination: the page index and the top of the view
+// in points, if given.
+type pdfDest struct {
+	page   int
+	top    float64
+	hasTop bool
+}
+
+// destination resolves the explicit or named destination, or the go-to action,
+// against the pages.
+func (r *pdfReader) destination(dest any, pages []pdfPage) (pdfDest, bool) {
+	for range pdfMaxDepth {
+		switch v := r.resolve(dest).(type) {
+		case pdfName, pdfString:
+			var ok bool
+			if dest, ok = r.namedDest(v); !ok {
+				return pdfDest{}, false
+			}
+			continue
+
+		case pdfDict:
+			// go-to action, or the named destination wrapped in a dictionary
+			if d, ok := v["D"]; ok {
+				dest = d
+				continue
+			}
+			return pdfDest{}, false
+
+		case pdfArray:
+			if len(v) == 0 {
+				return pdfDest{}, false
+			}
+
+			d := pdfDest{page: -1}
+			switch p := v[0].(type) {
+			case pdfRef:
+				for i, page := range pages {
+					if page.ref == p {
+						d.page = i
+						break
+					}
+				}
+			case int64:
+				// remote destinations count the pages
+				d.page = int(p)
+			}
+			if d.page < 0 || d.page >= len(pages) {
+				return pdfDest{}, false
+			}
+
+			// top of the view is the third element of /XYZ and second of /FitH
+			top := -1
+			if len(v) > 1 {
+				switch r.resolve(v[1]) {
+				case pdfName("XYZ"):
+					top = 3
+				case pdfName("FitH"), pdfName("FitBH"):
+					top = 2
+				}
+			}
+			if top > 0 && top < len(v) {
+				d.top, d.hasTop = r.number(v[top])
+			}
+
+			return d, true
+
+		default:
+			return pdfDest{}, false
+		}
+	}
+
+	return pdfDest{}, false
+}
+
+// namedDest looks up the named destination in the catalog.
+func (r *pdfReader) namedDest(name any) (any, bool) {
+	catalog := r.catalog()
+
+	if n, ok := name.(pdfName); ok {
+		if dest, ok := r.dict(catalog["Dests"])[n]; ok {
+			return dest, true
+		}
+		name = pdfString(n)
+	}
+
+	key := r.text(name)
+
+	var found any
+	r.nameTree(r.dict(catalog["Names"])["Dests"], func(name string, val any) {
+		if found == nil && name == key {
+			found = val
+		}
+	})
+
+	return found, found != nil
+}
+
+// namedDests calls fn for every named destination of the document, both of the
+// catalog dictionary and of the name tree.
+func (r *pdfReader) namedDests(fn func(name string, dest any)) {
+	catalog := r.catalog()
+
+	for name, dest := range r.dict(catalog["Dests"]) {
+		fn(string(name), dest)
+	}
+	r.nameTree(r.dict(catalog["Names"])["Dests"], fn)
+}
+
+// decode returns the decoded data of the stream.
+func (r *pdfReader) decode(stream *pdfStream) ([]byte, error) {
+	data := stream.raw
+
+	filters := []any{r.resolve(stream.dict["Filter"])}
+	if arr, ok := filters[0].(pdfArray); ok {
+		filters = arr
+	}
+	params := []any{r.resolve(stream.dict["DecodeParms"])}
+	if arr, ok := params[0].(pdfArray); ok {
+		params = arr
+	}
+
+	for i, filter := range filters {
+		var parms pdfDict
+		if i < len(params) {
+			parms = r.dict(params[i])
+		}
+
+		var err error
+		switch r.resolve(filter) {
+		case nil:
+			continue
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			if data, err = inflate(data); err != nil {
+				return nil, err
+			}
+			if data, err = r.unpredict(data, parms); err != nil {
+				return nil, err
+			}
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			if data, err = decodeASCIIHex(data); err != nil {
+				return nil, err
+			}
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			if data, err = decodeASCII85(data); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("%w: filter %v", ErrUnsupportedPDF, filter)
+		}
+	}
+
+	return data, nil
+}
+
+// inflate decompresses the zlib data, keeping whatever was decompressed before
+// the data got corrupted.
+func inflate(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	out, err := io.ReadAll(io.LimitReader(zr, pdfMaxDecoded+1))
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	if len(out) > pdfMaxDecoded {
+		return nil, fmt.Errorf("%w: stream over %d bytes", ErrUnsupportedPDF, pdfMaxDecoded)
+	}
+
+	return out, nil
+}
+
+// unpredict reverses the PNG predictors of the decoded data.
+func (r *pdfReader) unpredict(data []byte, parms pdfDict) ([]byte, error) {
+	predictor, _ := r.number(parms["Predictor"])
+	if predictor < 10 {
+		if predictor == 2 {
+			return nil, fmt.Errorf("%w: TIFF predictor", ErrUnsupportedPDF)
+		}
+		return data, nil
+	}
+
+	columns, colors, bpc := 1.0, 1.0, 8.0
+	if v, ok := r.number(parms["Columns"]); ok {
+		columns = v
+	}
+	if v, ok := r.number(parms["Colors"]); ok {
+		colors = v
+	}
+	if v, ok := r.number(parms["BitsPerComponent"]); ok {
+		bpc = v
+	}
+
+	// rows longer than the data are never complete, and their size may not fit
+	// int at all
+	bits := columns * colors * bpc
+	if !(bits > 0) || !(colors*bpc > 0) || colors*bpc > 8*float64(len(data)) || bits > 8*float64(len(data)) {
+		return nil, errors.New("malformed predictor parameters")
+	}
+
+	bpp := max(1, int(colors*bpc+7)/8)
+	row := int(bits+7) / 8
+
+	var out []byte
+	prev := make([]byte, row)
+	for pos := 0; pos+row+1 <= len(data); pos += row + 1 {
+		kind := data[pos]
+		cur := append([]byte(nil), data[pos+1:pos+1+row]...)
+
+		for i := range cur {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = cur[i-bpp], prev[i-bpp]
+			}
+			up := prev[i]
+
+			switch kind {
+			case 1:
+				cur[i] += left
+			case 2:
+				cur[i] += up
+			case 3:
+				cur[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				cur[i] += paeth(left, up, upLeft)
+			}
+		}
+
+		out = append(out, cur...)
+		prev = cur
+	}
+
+	return out, nil
+}
+
+// paeth is the Paeth predictor of PNG.
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	default:
+		return c
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
+
+// decodeASCIIHex decodes the ASCIIHexDecode data.
+func decodeASCIIHex(data []byte) ([]byte, error) {
+	var digits []byte
+	for _, c := range data {
+		if c == '>' {
+			break
+		}
+		if !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	return hex.DecodeString(string(digits))
+}
+
+// decodeASCII85 decodes the ASCII85Decode data.
+func decodeASCII85(data []byte) ([]byte, error) {
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+
+	// "z" decodes to 4 bytes, the output may be longer than the input
+	return io.ReadAll(ascii85.NewDecoder(bytes.NewReader(data)))
+}
+
+// ----------------------------------------------------------------------------
+// -- pdf lexing
+// ----------------------------------------------------------------------------
+
+// pdfLexer reads objects from the data starting at the position.
+type pdfLexer struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+// isPDFSpace reports whether the byte is the white-space character.
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == 0
+}
+
+// isPDFDelimiter reports whether the byte is the delimiter character.
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+
+	return false
+}
+
+// skipSpace skips white-space and comments.
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		switch c := l.data[l.pos]; {
+		case isPDFSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// readToken reads the regular characters at the position.
+func (l *pdfLexer) readToken() string {
+	l.skipSpace()
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+
+	return string(l.data[start:l.pos])
+}
+
+// readInt reads the integer.
+func (l *pdfLexer) readInt() (int, error) {
+	return strconv.Atoi(l.readToken())
+}
+
+// readKeyword reads the keyword.
+func (l *pdfLexer) readKeyword() (string, error) {
+	tok := l.readToken()
+	if tok == "" {
+		return "", errors.New("missing keyword")
+	}
+
+	return tok, nil
+}
+
+// readObject reads the direct object, with references to indirect objects.
+func (l *pdfLexer) readObject() (any, error) {
+	if l.depth > pdfMaxDepth {
+		return nil, errors.New("objects nested too deep")
+	}
+	l.depth++
+	defer func() { l.depth-- }()
+
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	switch c := l.data[l.pos]; {
+	case c == '/':
+		return l.readName(), nil
+
+	case c == '(':
+		return l.readLiteral()
+
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		dict := make(pdfDict)
+		for {
+			l.skipSpace()
+			if bytes.HasPrefix(l.data[l.pos:], []byte(">>")) {
+				l.pos += 2
+				return dict, nil
+			}
+			if l.pos >= len(l.data) || l.data[l.pos] != '/' {
+				return nil, errors.New("malformed dictionary")
+			}
+
+			key := l.readName()
+			val, err := l.readObject()
+			if err != nil {
+				return nil, err
+			}
+			dict[key] = val
+		}
+
+	case c == '<':
+		l.pos++
+		end := bytes.IndexByte(l.data[l.pos:], '>')
+		if end < 0 {
+			return nil, errors.New("unterminated hex string")
+		}
+		data, err := decodeASCIIHex(l.data[l.pos : l.pos+end])
+		l.pos += end + 1
+
+		return pdfString(data), err
+
+	case c == '[':
+		l.pos++
+		var arr pdfArray
+		for {
+			l.skipSpace()
+			if l.pos < len(l.data) && l.data[l.pos] == ']' {
+				l.pos++
+				return arr, nil
+			}
+
+			val, err := l.readObject()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+
+	case isPDFDelimiter(c):
+		return nil, fmt.Errorf("unexpected %q", c)
+	}
+
+	tok := l.readToken()
+	switch tok {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+
+	n, err := strconv.ParseInt(tok, 10, 64)
+	if err != nil {
+		f, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected %q", tok)
+		}
+		return f, nil
+	}
+
+	// integers followed by the generation and `R` are references
+	save := l.pos
+	gen, err := strconv.Atoi(l.readToken())
+	if err == nil && l.readToken() == "R" {
+		return pdfRef{num: int(n), gen: gen}, nil
+	}
+	l.pos = save
+
+	return n, nil
+}
+
+// readName reads the name, with the `#xx` escapes decoded.
+func (l *pdfLexer) readName() pdfName {
+	l.pos++ // slash
+
+	var b []byte
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		b = append(b, c)
+		l.pos++
+	}
+
+	return pdfName(b)
+}
+
+// readLiteral reads the literal string, with escapes decoded.
+func (l *pdfLexer) readLiteral() (pdfString, error) {
+	l.pos++ // parenthesis
+
+	var b []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return pdfString(b), nil
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				break
+			}
+			e := l.data[l.pos]
+			l.pos++
+
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e < '0' || e > '7' {
+					c = e
+					break
+				}
+
+				// up to three octal digits
+				v := int(e - '0')
+				for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+					v = v*8 + int(l.data[l.pos]-'0')
+					l.pos++
+				}
+				c = byte(v)
+			}
+		}
+
+		b = append(b, c)
+	}
+
+	return "", errors.New("unterminated string")
+}
@@ -0,0 +1,131 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// pdfFile returns the PDF file with the objects, numbered from 1, and the
+// cross-reference table, or without it if the trailer is nil.
+func pdfFile(trailer string, objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	if trailer == "" {
+		return buf.Bytes()
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n%s\nstartxref\n%d\n%%%%EOF\n", trailer, xref)
+
+	return buf.Bytes()
+}
+
+// readAll reads everything the package reads from the PDF file.
+func readAll(r *pdfReader) {
+	for num := range r.xref {
+		if stream, ok := r.object(num).(*pdfStream); ok {
+			_, _ = r.decode(stream)
+		}
+	}
+
+	pages := r.pages()
+	r.namedDests(func(_ string, dest any) {
+		r.destination(dest, pages)
+	})
+	r.pageLabels(len(pages))
+	r.pageHashes()
+}
+
+var pdfSeeds = map[string][]byte{
+	"minimal": pdfFile("<< /Size 4 /Root 1 0 R >>",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>",
+	),
+	"negative xref width": []byte("%PDF-1.5\n1 0 obj\n<< /Type /XRef /W [1 -5 1] /Size 2 /Length 6 >>\nstream\nabcdef\nendstream\nendobj\nstartxref\n9\n%%EOF\n"),
+	"huge length": pdfFile("",
+		"<< /Type /Catalog >>",
+		"<< /Length 9223372036854775807 >>\nstream\nabc\nendstream",
+	),
+	"negative object stream offset": pdfFile("",
+		"<< /Type /ObjStm /N 1 /First 0 /Length 7 >>\nstream\n2 -100\nendstream",
+	),
+	"huge predictor row": pdfFile("",
+		"<< /Type /Catalog >>",
+		"<< /Filter /FlateDecode /DecodeParms << /Predictor 12 /Columns 1000000000 >> /Length 11 >>\nstream\nx\x9c\x03\x00\x00\x00\x00\x01\x00\x00\x00\nendstream",
+	),
+}
+
+func TestNewPDFReaderMalformed(t *testing.T) {
+	for name, data := range pdfSeeds {
+		t.Run(name, func(t *testing.T) {
+			r, err := newPDFReader(data)
+			if err != nil {
+				return
+			}
+			readAll(r)
+		})
+	}
+}
+
+func TestNewPDFReaderPages(t *testing.T) {
+	r, err := newPDFReader(pdfSeeds["minimal"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pages := r.pages()
+	if len(pages) != 1 {
+		t.Fatalf("got %d pages, want 1", len(pages))
+	}
+}
+
+func TestDecodeASCII85(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain", "<~BOu!rDZ~>", "hello"},
+		{"z runs", "zzz", strings.Repeat("\x00", 12)},
+		{"z runs and text", "zzzBOu!rDZ~>", strings.Repeat("\x00", 12) + "hello"},
+		{"white space", "z z\nBOu!r DZ ~>", strings.Repeat("\x00", 8) + "hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeASCII85([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzNewPDFReader(f *testing.F) {
+	for _, data := range pdfSeeds {
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r, err := newPDFReader(data)
+		if err != nil {
+			return
+		}
+		readAll(r)
+	})
+}
@@ -8,18 +8,63 @@
 package pdftohtml
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
 	"os/exec"
-	"strconv"
+	"path/filepath"
+	"time"
 )
 
 // ----------------------------------------------------------------------------
 // -- `pdftohtml`
 // ----------------------------------------------------------------------------
 
+// Runner converts PDF file into HTML files in the outdir.
+type Runner interface {
+	Run(ctx context.Context, inpath, outdir string) error
+}
+
 type Command struct {
-	path string
-	args []string
+	path        string
+	root        string
+	clean       bool
+	mkdir       bool
+	mkdirMode   os.FileMode
+	fileMode    os.FileMode
+	dirMode     os.FileMode
+	chown       bool
+	uid, gid    int
+	manifest    bool
+	report      bool
+	partial     bool
+	onPage      func(context.Context, PageResult) error
+	blank       BlankPagePolicy
+	xfa         XFAPolicy
+	densities   []float64
+	variants    []BackgroundVariant
+	pages       pageSelection
+	chunk       int
+	width       float64
+	budget      int64
+	downloads   downloadPolicy
+	passwords   []string
+	incremental bool
+	pageLabels  bool
+	quarantine  string
+	customizers []func(*exec.Cmd)
+	env         map[string]string
+	stderr      bool
+	opts        OptionSet
+	checks      []stage
+	stages      []stage
+	errs        []error
+	warnings    []string
 }
 
 // NewCommand creates new `pdftohtml` command.
@@ -29,7 +74,11 @@ func NewCommand(opts ...option) (*Command, error) {
 		opt(cmd)
 	}
 
-	var err error
+	// assert that all options were applied successfully
+	err := errors.Join(cmd.errs...)
+	if err != nil {
+		return nil, err
+	}
 
 	// assert that executable exists and get absolute path
 	cmd.path, err = exec.LookPath(cmd.path)
@@ -42,14 +91,350 @@ func NewCommand(opts ...option) (*Command, error) {
 
 // Run executes prepared `pdftohtml` command.
 func (c *Command) Run(ctx context.Context, inpath, outdir string) error {
-	cmd := exec.CommandContext(ctx, c.path, append(c.args, inpath, outdir)...)
+	_, err := c.run(ctx, inpath, outdir)
+
+	return err
+}
+
+// run executes prepared `pdftohtml` command, and returns the password candidate
+// that opened the PDF file, if any. Lifecycle of the run is reported to the
+// event callback of the context.
+func (c *Command) run(ctx context.Context, inpath, outdir string) (string, error) {
+	events := eventsFrom(ctx)
+	if events == nil {
+		return c.execute(ctx, inpath, outdir)
+	}
+
+	// pages are reported without changing the shared command
+	observed := *c
+	observed.onPage = func(ctx context.Context, page PageResult) error {
+		events(Event{Type: EventPage, Time: time.Now(), Input: inpath, Page: page.Number})
+		if c.onPage != nil {
+			return c.onPage(ctx, page)
+		}
+		return nil
+	}
+
+	start := time.Now()
+	events(Event{Type: EventStarted, Time: start, Input: inpath})
+
+	password, err := observed.execute(ctx, inpath, outdir)
+	if err != nil {
+		events(Event{Type: EventFailed, Time: time.Now(), Input: inpath, Duration: time.Since(start), Error: err.Error()})
+	} else {
+		events(Event{Type: EventFinished, Time: time.Now(), Input: inpath, Duration: time.Since(start)})
+	}
+
+	return password, err
+}
+
+// execute executes prepared `pdftohtml` command, and returns the password
+// candidate that opened the PDF file, if any.
+func (c *Command) execute(ctx context.Context, inpath, outdir string) (string, error) {
+	for _, path := range []string{inpath, outdir} {
+		if err := checkPath(path); err != nil {
+			return "", err
+		}
+	}
+
+	inpath, err := checkInput(inpath)
+	if err != nil {
+		return "", err
+	}
+
+	// absolute outdir can't be mistaken for an option either
+	outdir, err = filepath.Abs(outdir)
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.checkOutdir(inpath, outdir); err != nil {
+		return "", err
+	}
+
+	record := &runRecord{rotations: make(map[int]int)}
+	ctx = context.WithValue(ctx, recordKey{}, record)
+
+	// checks of the input go before the outdir is touched
+	for _, check := range c.checks {
+		if err := check(ctx, inpath, outdir); err != nil {
+			return "", err
+		}
+	}
+
+	var plan incrementalPlan
+	if c.incremental {
+		if plan, err = c.planIncremental(inpath, outdir); err != nil || plan.current {
+			return "", err
+		}
+		record.reconvert = plan.changed
+	}
+
+	if c.clean || (c.incremental && plan.changed == nil) {
+		if err := cleanOutdir(outdir); err != nil {
+			return "", err
+		}
+	}
+
+	if c.mkdir {
+		if err := os.MkdirAll(filepath.Dir(outdir), c.mkdirMode); err != nil {
+			return "", err
+		}
+	}
+
+	if c.stderr {
+		ctx = withStderr(ctx)
+	}
+
+	opts, warnings, err := c.handleXFA(ctx, c.opts, inpath)
+	if err != nil {
+		return "", err
+	}
+
+	opts, budgetWarnings, err := c.fitResolution(ctx, opts, inpath)
+	if err != nil {
+		return "", err
+	}
+	warnings = append(warnings, budgetWarnings...)
+
+	start := time.Now()
+	password, err := c.unlock(ctx, opts, inpath, outdir)
+	record.timings.Exec = time.Since(start)
+	if err != nil {
+		if c.partial && ctx.Err() != nil {
+			return "", c.salvage(inpath, outdir, ctx.Err())
+		}
+
+		return "", err
+	}
+
+	start = time.Now()
+	if c.root != "" {
+		if err := checkTree(outdir); err != nil {
+			return "", err
+		}
+	}
+
+	blank, err := c.handleBlankPages(outdir)
+	if err != nil {
+		return "", err
+	}
+
+	// extra renditions of backgrounds and stages open the PDF file the same way
+	if password != "" {
+		opts.OwnerPassword, opts.UserPassword = password, password
+	}
+	ctx = context.WithValue(ctx, optionsKey{}, opts)
+
+	if len(c.densities) > 0 {
+		if err := c.renderDensities(ctx, opts, inpath, outdir); err != nil {
+			return "", err
+		}
+	}
+
+	if len(c.variants) > 0 {
+		if err := c.renderVariants(ctx, opts, inpath, outdir); err != nil {
+			return "", err
+		}
+	}
+
+	// apply post-processing stages in the order options were given
+	for _, s := range c.stages {
+		stageStart := time.Now()
+		if err := s(ctx, inpath, outdir); err != nil {
+			return "", err
+		}
+
+		if events := eventsFrom(ctx); events != nil {
+			events(Event{Type: EventStage, Time: time.Now(), Input: inpath, Stage: stageName(s), Duration: time.Since(stageStart)})
+		}
+	}
+
+	record.timings.PostProcess = time.Since(start)
+
+	start = time.Now()
+	if c.manifest || c.blank == BlankPagesMark || c.incremental {
+		m, err := newManifest(inpath, outdir)
+		if err != nil {
+			return "", err
+		}
+		m.markBlank(blank)
+		m.markRotated(record.rotations)
+		m.Warnings = warnings
+		m.Signatures = record.signatures
+		m.Conformance = record.conformance
+
+		m.PageHashes = plan.pages
+		if m.Hash = plan.hash; plan.hash == "" {
+			if m.Hash, err = hashFile(inpath); err != nil {
+				return "", err
+			}
+		}
+		m.Options = c.optionsHash()
+
+		if err := m.write(outdir); err != nil {
+			return "", err
+		}
+	}
+	record.timings.Packaging = time.Since(start)
+
+	if c.report {
+		r, err := c.collectReport(ctx, inpath, outdir, record, warnings)
+		if err != nil {
+			return "", err
+		}
+
+		if err := writeReport(outdir, r); err != nil {
+			return "", err
+		}
+	}
 
-	return cmd.Run()
+	return password, c.applyOwnership(outdir)
+}
+
+// exec runs the executable with the options, watching for converted pages if
+// needed.
+func (c *Command) exec(ctx context.Context, opts OptionSet, inpath, outdir string) error {
+	// process is killed when the page callback fails
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(runCtx, c.path, c.argv(opts, inpath, outdir)...)
+	cmd.Stderr = &stderr
+	if buf := stderrFrom(ctx); buf != nil {
+		cmd.Stderr = io.MultiWriter(&stderr, buf)
+	}
+	cmd.Env = c.environ()
+	for _, customize := range c.customizers {
+		customize(cmd)
+	}
+
+	var err error
+	if c.onPage == nil {
+		err = cmd.Run()
+	} else {
+		err = c.watchPages(ctx, cmd, outdir, int(max(opts.PageFrom, 1)), cancel)
+	}
+
+	if err != nil && isIncorrectPassword(stderr.Bytes()) {
+		return fmt.Errorf("%w: %w", ErrIncorrectPassword, err)
+	}
+	if sig, ok := crashSignal(err); ok {
+		return c.crash(inpath, sig, err, stderr.Bytes())
+	}
+
+	return err
 }
 
 // String returns a human-readable description of the command.
 func (c *Command) String() string {
-	return exec.Command(c.path, append(c.args, "<inpath>", "<outdir>")...).String()
+	return exec.Command(c.path, c.argv(c.opts, "<inpath>", "<outdir>")...).String()
+}
+
+// Path returns the absolute path of the `pdftohtml` executable.
+func (c *Command) Path() string {
+	return c.path
+}
+
+// Args returns the arguments the executable is run with, without the input
+// path and the outdir.
+func (c *Command) Args() []string {
+	return c.args(c.opts)
+}
+
+// argv returns the arguments the executable is run with for the options, the
+// input path and the outdir. Xpdf tools take everything after `--` as it is, so
+// paths starting with a dash are never read as options.
+func (c *Command) argv(opts OptionSet, inpath, outdir string) []string {
+	return append(c.args(opts), "--", inpath, outdir)
+}
+
+// args returns the arguments the executable is run with for the options.
+func (c *Command) args(opts OptionSet) []string {
+	// cleaned outdir still exists, so it has to be overwritten
+	opts.Overwrite = opts.Overwrite || c.clean || c.incremental
+
+	return opts.args()
+}
+
+// Options returns the options applied to the command.
+func (c *Command) Options() OptionSet {
+	return c.opts
+}
+
+// commandJSON is the serialized form of the command.
+type commandJSON struct {
+	Path    string    `json:"path"`
+	Options OptionSet `json:"options"`
+}
+
+// MarshalJSON returns the executable path and the options of the command, so the
+// same command can be recreated later with `UnmarshalJSON`.
+//
+// Post-processing stages are not serialized, use `Config` to persist them.
+// Passwords are left out too, pass them to `WithOwnerPassword` and
+// `WithUserPassword` again when the command is recreated.
+func (c *Command) MarshalJSON() ([]byte, error) {
+	return json.Marshal(commandJSON{Path: c.path, Options: c.opts})
+}
+
+// UnmarshalJSON recreates the command serialized with `MarshalJSON`, validating
+// it like `NewCommand` does. The recreated command has no passwords, recreate
+// it with them when needed:
+//
+//	cmd, err = NewCommand(WithCustomPath(cmd.Path()), WithOptionSet(cmd.Options()), WithUserPassword(password))
+func (c *Command) UnmarshalJSON(data []byte) error {
+	var dto commandJSON
+	if err := json.Unmarshal(data, &dto); err != nil {
+		return err
+	}
+
+	cmd, err := NewCommand(WithCustomPath(dto.Path), WithOptionSet(dto.Options))
+	if err != nil {
+		return err
+	}
+	*c = *cmd
+
+	return nil
+}
+
+// Warnings returns warnings about suspicious, but allowed, option values.
+func (c *Command) Warnings() []string {
+	return c.warnings
+}
+
+// RangeError is returned when the value of a numeric option is out of range.
+type RangeError struct {
+	Option    string
+	Value     float64
+	Min       float64
+	Max       float64
+	Exclusive bool // Min itself is out of range
+}
+
+func (e *RangeError) Error() string {
+	open := "["
+	if e.Exclusive {
+		open = "("
+	}
+
+	return fmt.Sprintf("pdftohtml: %s %g out of range %s%g, %g]", e.Option, e.Value, open, e.Min, e.Max)
+}
+
+// checkRange records `RangeError` on the command if the value is out of range.
+func (c *Command) checkRange(err RangeError) bool {
+	ok := err.Value >= err.Min && err.Value <= err.Max
+	if err.Exclusive {
+		ok = ok && err.Value != err.Min
+	}
+
+	if !ok {
+		c.errs = append(c.errs, &err)
+	}
+
+	return ok
 }
 
 // ----------------------------------------------------------------------------
@@ -68,7 +453,7 @@ func WithCustomPath(path string) option {
 // Read config-file in place of ~/.xpdfrc or the system-wide config file.
 func WithCustomConfig(path string) option {
 	return func(c *Command) {
-		c.args = append(c.args, "-cfg", path)
+		c.opts.ConfigFile = path
 	}
 }
 
@@ -76,31 +461,34 @@ func WithCustomConfig(path string) option {
 //
 // By default pdftohtml will not overwrite the output directory. If the directory already
 // exists, pdftohtml will exit with an error.
+//
+// Outdirs containing the input or the home directory, e.g. any of their parents,
+// and filesystem roots are refused with `ErrUnsafeOutdir`.
 func WithOutdirOverwrite() option {
 	return func(c *Command) {
-		c.args = append(c.args, "-overwrite")
+		c.opts.Overwrite = true
 	}
 }
 
 // Specifies the first page to convert.
 func WithPageFrom(page uint64) option {
 	return func(c *Command) {
-		c.args = append(c.args, "-f", strconv.FormatUint(page, 10))
+		c.opts.PageFrom = page
 	}
 }
 
 // Specifies the last page to convert.
 func WithPageTo(page uint64) option {
 	return func(c *Command) {
-		c.args = append(c.args, "-l", strconv.FormatUint(page, 10))
+		c.opts.PageTo = page
 	}
 }
 
 // Specifies the range of pages to convert.
 func WithPageRange(from, to uint64) option {
 	return func(c *Command) {
-		WithPageFrom(from)
-		WithPageTo(to)
+		c.opts.PageFrom = from
+		c.opts.PageTo = to
 	}
 }
 
@@ -110,9 +498,18 @@ func WithPageRange(from, to uint64) option {
 // be 1 pixel in the HTML.
 //
 // Using ´-z 1.5’, for example, will make the initial view 50% larger.
+//
+// The zoom must be greater than 0, zoom greater than 10 is reported as a warning.
 func WithInitialZoom(zoom float64) option {
 	return func(c *Command) {
-		c.args = append(c.args, "-z", strconv.FormatFloat(zoom, 'e', 2, 64))
+		if !c.checkRange(RangeError{Option: "zoom", Value: zoom, Min: 0, Max: math.Inf(+1), Exclusive: true}) {
+			return
+		}
+		if zoom > 10 {
+			c.warnings = append(c.warnings, fmt.Sprintf("zoom %g is extremely large", zoom))
+		}
+
+		c.opts.Zoom = zoom
 	}
 }
 
@@ -122,9 +519,15 @@ func WithInitialZoom(zoom float64) option {
 // The initial zoom level is set by the `WithInitialZoom` option. Specifying
 // a larger zoom value will allow the viewer to zoom in farther without upscaling
 // artifacts in the background.
+//
+// The resolution must be between 8 and 2400 DPI.
 func WithResolution(dpi uint64) option {
 	return func(c *Command) {
-		c.args = append(c.args, "-r", strconv.FormatUint(dpi, 10))
+		if !c.checkRange(RangeError{Option: "resolution", Value: float64(dpi), Min: 8, Max: 2400}) {
+			return
+		}
+
+		c.opts.Resolution = dpi
 	}
 }
 
@@ -132,9 +535,15 @@ func WithResolution(dpi uint64) option {
 //
 // Setting this to a value greater than 1.0 will stretch each page vertically,
 // spreading out the lines. This also stretches the background image to match.
+//
+// The factor must be at least 1.0.
 func WithVerticalStretch(factor float64) option {
 	return func(c *Command) {
-		c.args = append(c.args, "-vstretch", strconv.FormatFloat(factor, 'e', 2, 64))
+		if !c.checkRange(RangeError{Option: "vertical stretch", Value: factor, Min: 1, Max: math.Inf(+1)}) {
+			return
+		}
+
+		c.opts.VerticalStretch = factor
 	}
 }
 
@@ -142,7 +551,7 @@ func WithVerticalStretch(factor float64) option {
 // rather than storing it as a separate file.
 func WithEmbedBackground() option {
 	return func(c *Command) {
-		c.args = append(c.args, "-embedbackground")
+		c.opts.EmbedBackground = true
 	}
 }
 
@@ -152,7 +561,7 @@ func WithEmbedBackground() option {
 // can work around problems with buggy fonts.
 func WithNoFonts() option {
 	return func(c *Command) {
-		c.args = append(c.args, "-nofonts")
+		c.opts.NoFonts = true
 	}
 }
 
@@ -160,7 +569,7 @@ func WithNoFonts() option {
 // than storing them as separate files.
 func WithEmbedFonts() option {
 	return func(c *Command) {
-		c.args = append(c.args, "-embedfonts")
+		c.opts.EmbedFonts = true
 	}
 }
 
@@ -170,7 +579,7 @@ func WithEmbedFonts() option {
 // (alpha=0) HTML text. This option tells pdftohtml to discard invisible text entirely.
 func WithNoInvisibleText() option {
 	return func(c *Command) {
-		c.args = append(c.args, "-skipinvisible")
+		c.opts.NoInvisibleText = true
 	}
 }
 
@@ -181,7 +590,42 @@ func WithNoInvisibleText() option {
 // regular text in the background image, and then draw it as transparent (alpha=0) HTML text.
 func WithAllInvisibleText() option {
 	return func(c *Command) {
-		c.args = append(c.args, "-allinvisible")
+		c.opts.AllInvisibleText = true
+	}
+}
+
+// InvisibleTextPolicy determines how invisible text, commonly the OCR text layer
+// of scanned PDF files, and regular text are drawn.
+type InvisibleTextPolicy int
+
+const (
+	// InvisibleTextTransparent draws invisible text as transparent HTML text,
+	// over the background, and regular text as visible HTML text. This is the
+	// default of Xpdf, and what Poppler `-hidden` enables.
+	InvisibleTextTransparent InvisibleTextPolicy = iota + 1
+	// InvisibleTextSkip discards invisible text entirely, see
+	// `WithNoInvisibleText`.
+	InvisibleTextSkip
+	// InvisibleTextAll draws regular text in the background as well, and all
+	// text as transparent HTML text, see `WithAllInvisibleText`.
+	InvisibleTextAll
+)
+
+// Specifies how invisible text is drawn, by the policy, instead of the flags of
+// the `pdftohtml` executable, see `InvisibleTextPolicy`. It replaces the choice
+// of `WithNoInvisibleText` and `WithAllInvisibleText`.
+func WithInvisibleText(policy InvisibleTextPolicy) option {
+	return func(c *Command) {
+		switch policy {
+		case InvisibleTextTransparent:
+			c.opts.NoInvisibleText, c.opts.AllInvisibleText = false, false
+		case InvisibleTextSkip:
+			c.opts.NoInvisibleText, c.opts.AllInvisibleText = true, false
+		case InvisibleTextAll:
+			c.opts.NoInvisibleText, c.opts.AllInvisibleText = false, true
+		default:
+			c.errs = append(c.errs, fmt.Errorf("pdftohtml: unknown invisible text policy %d", policy))
+		}
 	}
 }
 
@@ -191,14 +635,14 @@ func WithAllInvisibleText() option {
 // (e.g., lines or boxes) in the field areas.
 func WithEmbedFormFields() option {
 	return func(c *Command) {
-		c.args = append(c.args, "-formfields")
+		c.opts.FormFields = true
 	}
 }
 
 // Include PDF document metadata as ’meta’ elements in the HTML header.
 func WithEmbedMetaTags() option {
 	return func(c *Command) {
-		c.args = append(c.args, "-meta")
+		c.opts.MetaTags = true
 	}
 }
 
@@ -209,7 +653,7 @@ func WithEmbedMetaTags() option {
 // Note: This does not generate HTML tables; it just changes the way text is split up.
 func WithModeTable() option {
 	return func(c *Command) {
-		c.args = append(c.args, "-table")
+		c.opts.Table = true
 	}
 }
 
@@ -218,13 +662,24 @@ func WithModeTable() option {
 // Providing this will bypass all security restrictions.
 func WithOwnerPassword(password string) option {
 	return func(c *Command) {
-		c.args = append(c.args, "-opw", password)
+		c.opts.OwnerPassword = password
 	}
 }
 
 // Specify the user password for the PDF file.
 func WithUserPassword(password string) option {
 	return func(c *Command) {
-		c.args = append(c.args, "-upw", password)
+		c.opts.UserPassword = password
+	}
+}
+
+// Customize the process of the executable before it starts, e.g. its Env, Dir,
+// SysProcAttr or ExtraFiles. Customizers run in the order options were given.
+//
+// The customizer must not replace Stderr, which is used to classify failures,
+// or the path and the arguments of the process.
+func WithCmdCustomizer(customize func(*exec.Cmd)) option {
+	return func(c *Command) {
+		c.customizers = append(c.customizers, customize)
 	}
 }
@@ -0,0 +1,298 @@
+package pdftohtml
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- pipeline
+// ----------------------------------------------------------------------------
+
+// ErrDiskLimitExceeded is returned when the pipeline workspace grows beyond the
+// configured disk limit.
+var ErrDiskLimitExceeded = errors.New("pdftohtml: disk limit exceeded")
+
+// Pipeline converts a stream of PDF bytes into a stream of ZIP archive bytes
+// with the conversion output, managing the temporary workspace internally.
+type Pipeline struct {
+	runner    Runner
+	tmpdir    string
+	diskLimit int64
+	pool      *WorkspacePool
+}
+
+// NewPipeline creates new pipeline converting with the runner, e.g. `pdftohtml`
+// command including its post-processing stages.
+func NewPipeline(runner Runner, opts ...pipelineOption) *Pipeline {
+	p := &Pipeline{runner: runner}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Run converts the PDF read from r and returns the archive with the output.
+//
+// The workspace is removed once the archive is read to the end or closed, so
+// the caller must always close the returned reader.
+func (p *Pipeline) Run(ctx context.Context, r io.Reader) (io.ReadCloser, error) {
+	w, release, err := p.workspace()
+	if err != nil {
+		return nil, err
+	}
+
+	// released here also when the runner panics, unless handed to the archive
+	archived := false
+	defer func() {
+		if !archived {
+			release()
+		}
+	}()
+
+	outdir, err := p.convert(ctx, w, r)
+	if err != nil {
+		return nil, err
+	}
+
+	archived = true
+	pr, pw := io.Pipe()
+	go func() {
+		// released before the end of the archive, so it is gone once read
+		err := writeZip(pw, outdir)
+		release()
+		pw.CloseWithError(err)
+	}()
+
+	return pr, nil
+}
+
+// workspace returns the empty workspace, from the workspace pool if any, and
+// the function releasing it.
+func (p *Pipeline) workspace() (*Workspace, func(), error) {
+	if p.pool != nil {
+		w, err := p.pool.Get()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return w, func() { p.pool.Put(w) }, nil
+	}
+
+	workdir, err := os.MkdirTemp(p.tmpdir, "pdftohtml-*")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w := &Workspace{
+		Dir:    workdir,
+		Input:  filepath.Join(workdir, "input.pdf"),
+		Output: filepath.Join(workdir, "output"),
+	}
+
+	return w, func() { os.RemoveAll(workdir) }, nil
+}
+
+// convert spools the input into the workspace and converts it, returning the
+// outdir with the result.
+func (p *Pipeline) convert(ctx context.Context, w *Workspace, r io.Reader) (string, error) {
+	inpath, outdir := w.Input, w.Output
+
+	f, err := os.Create(inpath)
+	if err != nil {
+		return "", err
+	}
+
+	if p.diskLimit > 0 {
+		r = io.LimitReader(r, p.diskLimit+1)
+	}
+
+	n, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if p.diskLimit > 0 && n > p.diskLimit {
+		return "", ErrDiskLimitExceeded
+	}
+
+	limit := p.diskLimit
+	if w.Quota > 0 && (limit <= 0 || w.Quota < limit) {
+		limit = w.Quota
+	}
+
+	err = withinDiskLimit(ctx, w.Dir, limit, func(ctx context.Context) error {
+		return p.runner.Run(ctx, inpath, outdir)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := w.Check(); err != nil {
+		return "", err
+	}
+
+	if p.diskLimit > 0 {
+		size, err := dirSize(w.Dir)
+		if err != nil {
+			return "", err
+		}
+		if size > p.diskLimit {
+			return "", ErrDiskLimitExceeded
+		}
+	}
+
+	return outdir, nil
+}
+
+// diskPollInterval is how often disk usage of the running conversion is checked
+// against its limit.
+const diskPollInterval = 250 * time.Millisecond
+
+// withinDiskLimit runs fn with the context canceled once files in the directory
+// exceed the limit in bytes, and returns `ErrDiskLimitExceeded` then. Usage is
+// polled while fn runs, so it may exceed the limit briefly, by as much as is
+// written between polls. A limit of 0 or less disables polling.
+func withinDiskLimit(ctx context.Context, dir string, limit int64, fn func(context.Context) error) error {
+	if limit <= 0 {
+		return fn(ctx)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		ticker := time.NewTicker(diskPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// files removed while walking fail the poll, the next one counts them
+				if size, err := dirSize(dir); err == nil && size > limit {
+					cancel(ErrDiskLimitExceeded)
+					return
+				}
+			}
+		}
+	}()
+
+	err := fn(ctx)
+	if errors.Is(context.Cause(ctx), ErrDiskLimitExceeded) {
+		return ErrDiskLimitExceeded
+	}
+
+	return err
+}
+
+// dirSize returns the total size of regular files in the directory tree.
+func dirSize(dir string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+
+		return nil
+	})
+
+	return size, err
+}
+
+// writeZip writes the ZIP archive of the directory tree to w.
+func writeZip(w io.Writer, dir string) error {
+	zw := zip.NewWriter(w)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		zf, err := zw.Create(filepath.ToSlash(name))
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(zf, f)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// ----------------------------------------------------------------------------
+// -- pipeline options
+// ----------------------------------------------------------------------------
+
+type pipelineOption func(*Pipeline)
+
+// Set custom parent directory for pipeline workspaces.
+//
+// By default the workspaces are created in the default directory for temporary
+// files, see `os.TempDir`.
+func WithPipelineTempDir(dir string) pipelineOption {
+	return func(p *Pipeline) {
+		p.tmpdir = dir
+	}
+}
+
+// Limit disk usage of a single pipeline workspace, in bytes.
+//
+// The limit covers the spooled input and the conversion output. It is enforced
+// while the conversion runs, polling disk usage a few times per second: once
+// exceeded, the runner is canceled through its context and the run fails with
+// `ErrDiskLimitExceeded`. Usage may exceed the limit briefly, by what is written
+// between the polls, and runners ignoring the cancellation fail once finished.
+func WithPipelineDiskLimit(bytes int64) pipelineOption {
+	return func(p *Pipeline) {
+		p.diskLimit = bytes
+	}
+}
+
+// Take pipeline workspaces from the pool, instead of creating them in the
+// temporary directory for every run. The quota of the pool applies in addition
+// to the disk limit of the pipeline.
+func WithPipelineWorkspaces(pool *WorkspacePool) pipelineOption {
+	return func(p *Pipeline) {
+		p.pool = pool
+	}
+}
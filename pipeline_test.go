@@ -0,0 +1,151 @@
+package pdftohtml
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// convertFunc is the runner calling the function with the input and the outdir.
+type convertFunc func(ctx context.Context, inpath, outdir string) error
+
+func (f convertFunc) Run(ctx context.Context, inpath, outdir string) error {
+	return f(ctx, inpath, outdir)
+}
+
+// writeOutput returns the runner writing the file of the size into the outdir,
+// then waiting for the context until the timeout.
+func writeOutput(size int, wait time.Duration) Runner {
+	return convertFunc(func(ctx context.Context, _, outdir string) error {
+		if err := os.MkdirAll(outdir, 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(outdir, "page1.html"), make([]byte, size), 0o644); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+			return nil
+		}
+	})
+}
+
+func TestPipelineRun(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	runner := convertFunc(func(_ context.Context, inpath, outdir string) error {
+		data, err := os.ReadFile(inpath)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Join(outdir, "fonts"), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(outdir, "page1.html"), data, 0o644); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(outdir, "fonts", "f1.woff"), []byte("font"), 0o644)
+	})
+
+	p := NewPipeline(runner, WithPipelineTempDir(tmpdir))
+
+	rc, err := p.Run(context.Background(), strings.NewReader("%PDF-1.4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[f.Name] = string(content)
+	}
+	if len(got) != 2 || got["page1.html"] != "%PDF-1.4" || got["fonts/f1.woff"] != "font" {
+		t.Errorf("archived %q", got)
+	}
+
+	// workspace is removed once the archive is read
+	entries, err := os.ReadDir(tmpdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("workspaces left: %d", len(entries))
+	}
+}
+
+func TestPipelineDiskLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  int
+		runner Runner
+	}{
+		{"input", 2048, writeOutput(0, 0)},
+		{"output", 16, writeOutput(2048, 0)},
+		// fails only if the runner is canceled while it runs
+		{"running", 16, writeOutput(2048, time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpdir := t.TempDir()
+			p := NewPipeline(tt.runner, WithPipelineTempDir(tmpdir), WithPipelineDiskLimit(1024))
+
+			_, err := p.Run(context.Background(), bytes.NewReader(make([]byte, tt.input)))
+			if !errors.Is(err, ErrDiskLimitExceeded) {
+				t.Errorf("got %v, want ErrDiskLimitExceeded", err)
+			}
+
+			entries, err := os.ReadDir(tmpdir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("workspaces left: %d", len(entries))
+			}
+		})
+	}
+}
+
+func TestPipelineWorkspaceQuota(t *testing.T) {
+	pool, err := NewWorkspacePool(WithWorkspaceTempDir(t.TempDir()), WithWorkspacePoolSize(1), WithWorkspaceQuota(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	p := NewPipeline(writeOutput(2048, time.Minute), WithPipelineWorkspaces(pool))
+
+	_, err = p.Run(context.Background(), strings.NewReader("%PDF-1.4"))
+	if !errors.Is(err, ErrDiskLimitExceeded) {
+		t.Errorf("got %v, want ErrDiskLimitExceeded", err)
+	}
+}
@@ -0,0 +1,106 @@
+package pdftohtml
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// ----------------------------------------------------------------------------
+// -- output plan
+// ----------------------------------------------------------------------------
+
+// Plan describes the output the conversion is expected to produce.
+type Plan struct {
+	Outdir string     // absolute path of the outdir
+	Pages  []int      // numbers of pages to convert
+	Files  []PlanFile // predicted files, index first
+	Fonts  bool       // whether extracted font files, not listed in Files, are produced too
+}
+
+// PlanFile describes a single predicted file in the outdir.
+type PlanFile struct {
+	Name string
+	Size int64 // estimated size in bytes
+}
+
+// Size returns the estimated total size of the predicted files.
+func (p *Plan) Size() int64 {
+	var size int64
+	for _, f := range p.Files {
+		size += f.Size
+	}
+
+	return size
+}
+
+// Rough sizes used for the estimates, based on typical text documents.
+const (
+	planPageHTMLSize  = 8 << 10 // markup and text of a single page
+	planIndexHTMLSize = 512     // index without the page links
+	planIndexLinkSize = 40      // single page link in the index
+	planPixelSize     = 0.15    // compressed size of a background image pixel
+	planDefaultDPI    = 150     // background image resolution of `pdftohtml`
+)
+
+// Plan predicts the files the conversion of the PDF file into the outdir will
+// produce, using the page count and the size of the first page reported by
+// `Info`, and the options of the command.
+//
+// Files created by post-processing stages are not predicted, and sizes are only
+// rough estimates.
+func (c *Command) Plan(ctx context.Context, inpath, outdir string) (*Plan, error) {
+	info, err := c.Info(ctx, inpath)
+	if err != nil {
+		return nil, err
+	}
+
+	outdir, err = filepath.Abs(outdir)
+	if err != nil {
+		return nil, err
+	}
+
+	first, last := max(c.opts.PageFrom, 1), uint64(info.Pages)
+	if c.opts.PageTo > 0 {
+		last = min(c.opts.PageTo, last)
+	}
+
+	plan := &Plan{Outdir: outdir, Fonts: !c.opts.NoFonts && !c.opts.EmbedFonts}
+
+	dpi := float64(planDefaultDPI)
+	if c.opts.Resolution > 0 {
+		dpi = float64(c.opts.Resolution)
+	}
+	stretch := max(c.opts.VerticalStretch, 1)
+	image := int64(info.Width / 72 * dpi * info.Height / 72 * dpi * stretch * planPixelSize)
+
+	var pages []PlanFile
+	for n := first; n <= last; n++ {
+		if !c.pages.contains(n) {
+			continue
+		}
+		plan.Pages = append(plan.Pages, int(n))
+
+		html := PlanFile{Name: fmt.Sprintf("page%d.html", n), Size: planPageHTMLSize}
+		if c.opts.EmbedBackground {
+			// base64 encoding grows the data by a third
+			html.Size += image * 4 / 3
+			pages = append(pages, html)
+			continue
+		}
+		pages = append(pages, html, PlanFile{Name: fmt.Sprintf("page%d.png", n), Size: image})
+	}
+
+	index := PlanFile{Name: "index.html", Size: planIndexHTMLSize + planIndexLinkSize*int64(len(plan.Pages))}
+	plan.Files = append([]PlanFile{index}, pages...)
+
+	if c.manifest || c.blank == BlankPagesMark {
+		size := int64(128)
+		for _, f := range plan.Files {
+			size += int64(len(f.Name)) + 64
+		}
+		plan.Files = append(plan.Files, PlanFile{Name: ManifestName, Size: size})
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,317 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"context"
+	"html"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ----------------------------------------------------------------------------
+// -- post-processing
+// ----------------------------------------------------------------------------
+
+// stage is a post-processing step applied to the output directory after
+// `pdftohtml` finished successfully.
+type stage func(ctx context.Context, inpath, outdir string) error
+
+// optionsKey is the context key of the options the stages run with.
+type optionsKey struct{}
+
+// runOptions returns the options the PDF file was opened with, including the
+// password found by `WithPasswordCandidates`, or the options of the command
+// outside of the run.
+func runOptions(ctx context.Context, c *Command) OptionSet {
+	if opts, ok := ctx.Value(optionsKey{}).(OptionSet); ok {
+		return opts
+	}
+
+	return c.opts
+}
+
+// rewriteHTML applies fn to the content of every HTML file in the outdir.
+func rewriteHTML(outdir string, fn func(name string, data []byte) ([]byte, error)) error {
+	paths, err := filepath.Glob(filepath.Join(outdir, "*.html"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		data, err = fn(filepath.Base(path), data)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(path, data, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rePageName matches names of the generated page files.
+var rePageName = regexp.MustCompile(`^page(\d+)\.html$`)
+
+// pageNumber returns the page number of the generated page file, or 0 if the
+// name does not belong to a page file.
+func pageNumber(name string) int {
+	match := rePageName.FindStringSubmatch(name)
+	if match == nil {
+		return 0
+	}
+
+	n, _ := strconv.Atoi(match[1])
+
+	return n
+}
+
+// listHTML returns names of all HTML files in the outdir, with the index first
+// and pages ordered by their number.
+func listHTML(outdir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(outdir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(paths))
+	for i, path := range paths {
+		names[i] = filepath.Base(path)
+	}
+
+	rank := func(name string) int {
+		switch n := pageNumber(name); {
+		case name == "index.html":
+			return -1
+		case n > 0:
+			return n
+		default:
+			return math.MaxInt
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		return rank(names[i]) < rank(names[j])
+	})
+
+	return names, nil
+}
+
+// replaceRunes creates a stage replacing every rune from the table with its
+// replacement in the text of all generated HTML files. Markup is left intact,
+// and replacements are escaped where needed.
+func replaceRunes(table map[rune]string) stage {
+	pairs := make([]string, 0, 2*len(table))
+	for r, s := range table {
+		pairs = append(pairs, string(r), s)
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	return func(_ context.Context, _, outdir string) error {
+		return rewriteHTML(outdir, func(_ string, data []byte) ([]byte, error) {
+			doc, xhtml := parseDocument(data)
+			doc.walk(func(n *htmlNode) bool {
+				if n.kind == textNode && !n.raw {
+					n.text = replacer.Replace(n.text)
+				}
+				return true
+			})
+
+			return renderDocument(doc, xhtml), nil
+		})
+	}
+}
+
+// ligatures maps typographic ligatures to their decomposed letters.
+var ligatures = map[rune]string{
+	'Ĳ': "IJ",
+	'ĳ': "ij",
+	'ﬀ': "ff",
+	'ﬁ': "fi",
+	'ﬂ': "fl",
+	'ﬃ': "ffi",
+	'ﬄ': "ffl",
+	'ﬅ': "st",
+	'ﬆ': "st",
+}
+
+// reTag matches HTML markup, including the content of style and script elements.
+var reTag = regexp.MustCompile(`(?is)<style.*?</style>|<script.*?</script>|<[^>]*>`)
+
+// htmlText returns the text content of the HTML document, without markup.
+func htmlText(data []byte) []byte {
+	return reTag.ReplaceAll(data, []byte(" "))
+}
+
+// rtlScripts maps right-to-left scripts to the language they are assumed to be
+// written in.
+var rtlScripts = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Syriac, "syr"},
+	{unicode.Thaana, "dv"},
+}
+
+// detectDirection reports whether most letters of the text are written in a
+// right-to-left script, and the language of the dominant script.
+func detectDirection(text []byte) (rtl bool, lang string) {
+	counts := make([]int, len(rtlScripts))
+	letters := 0
+
+	for _, r := range string(text) {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+
+		for i, script := range rtlScripts {
+			if unicode.Is(script.table, r) {
+				counts[i]++
+				break
+			}
+		}
+	}
+
+	total, best := 0, 0
+	for i, count := range counts {
+		total += count
+		if count > counts[best] {
+			best = i
+		}
+	}
+
+	if total == 0 || 2*total < letters {
+		return false, ""
+	}
+
+	return true, rtlScripts[best].lang
+}
+
+// reHTMLTag matches the opening tag of the root element.
+var reHTMLTag = regexp.MustCompile(`(?i)<html\b[^>]*>`)
+
+// setRootAttrs adds the attributes to the root element, unless it already has
+// an attribute with the same name.
+func setRootAttrs(data []byte, attrs ...string) []byte {
+	loc := reHTMLTag.FindIndex(data)
+	if loc == nil {
+		return data
+	}
+
+	tag := data[loc[0]:loc[1]]
+
+	var add []byte
+	for i := 0; i+1 < len(attrs); i += 2 {
+		re := regexp.MustCompile(`(?i)\s` + regexp.QuoteMeta(attrs[i]) + `\s*=`)
+		if re.Match(tag) {
+			continue
+		}
+		add = append(add, " "+attrs[i]+`="`+attrs[i+1]+`"`...)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(data[:loc[1]-1])
+	buf.Write(add)
+	buf.Write(data[loc[1]-1:])
+
+	return buf.Bytes()
+}
+
+// reHeadTag matches the opening tag of the head element.
+var reHeadTag = regexp.MustCompile(`(?i)<head\b[^>]*>`)
+
+// insertHead inserts the markup at the beginning of the head element, or right
+// after the root element if there is no head.
+func insertHead(data, markup []byte) []byte {
+	loc := reHeadTag.FindIndex(data)
+	if loc == nil {
+		loc = reHTMLTag.FindIndex(data)
+	}
+	if loc == nil {
+		return append(markup, data...)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(data[:loc[1]])
+	buf.Write(markup)
+	buf.Write(data[loc[1]:])
+
+	return buf.Bytes()
+}
+
+// reTitle matches the title element.
+var reTitle = regexp.MustCompile(`(?is)<title\b[^>]*>(.*?)</title>`)
+
+// htmlTitle returns the unescaped content of the title element.
+func htmlTitle(data []byte) string {
+	match := reTitle.FindSubmatch(data)
+	if match == nil {
+		return ""
+	}
+
+	return strings.TrimSpace(html.UnescapeString(string(match[1])))
+}
+
+// ----------------------------------------------------------------------------
+// -- post-processing options
+// ----------------------------------------------------------------------------
+
+// Expand typographic ligatures (e.g. ﬁ → fi) in the generated HTML files.
+//
+// Ligature glyphs break searching and copying of the converted text.
+func WithLigatureExpansion() option {
+	return func(c *Command) {
+		c.stages = append(c.stages, replaceRunes(ligatures))
+	}
+}
+
+// Replace glyph codes in the generated HTML files using the given table.
+//
+// This is mostly useful for fonts mapping their glyphs into the Unicode Private
+// Use Area, which leaves the extracted text unreadable. Only the text is
+// replaced, so replacements such as "<" are escaped and can't inject markup.
+func WithGlyphMap(table map[rune]string) option {
+	return func(c *Command) {
+		c.stages = append(c.stages, replaceRunes(table))
+	}
+}
+
+// Detect documents written in right-to-left scripts (e.g. Arabic, Hebrew).
+//
+// Every generated HTML file whose text is mostly right-to-left gets the `dir="rtl"`
+// and `lang` attributes on its root element, so browsers apply the bidirectional
+// algorithm to the extracted text runs in the right base direction.
+func WithDirectionDetection() option {
+	return func(c *Command) {
+		c.stages = append(c.stages, func(_ context.Context, _, outdir string) error {
+			return rewriteHTML(outdir, func(_ string, data []byte) ([]byte, error) {
+				rtl, lang := detectDirection(htmlText(data))
+				if !rtl {
+					return data, nil
+				}
+
+				return setRootAttrs(data, "dir", "rtl", "lang", lang), nil
+			})
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package pdftohtml
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplaceRunes(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"text", "<p>ﬁle</p>", "<p>file</p>"},
+		{"escaped", "<p>a\ue000</p>", "<p>a&lt;b&gt;</p>"},
+		{"attributes kept", "<p class=\"x\ue000\">\ue000</p>", "<p class=\"x\ue000\">&lt;b&gt;</p>"},
+		{"scripts kept", "<script>var s = \"\ue000\";</script>", "<script>var s = \"\ue000\";</script>"},
+	}
+
+	stage := replaceRunes(map[rune]string{'ﬁ': "fi", '\ue000': "<b>"})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outdir := t.TempDir()
+			path := filepath.Join(outdir, "page1.html")
+			if err := os.WriteFile(path, []byte(tt.in), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := stage(context.Background(), "", outdir); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package pdftohtml
+
+import "fmt"
+
+// ----------------------------------------------------------------------------
+// -- presets
+// ----------------------------------------------------------------------------
+
+// Preset is a named combination of options suited for a common use case.
+type Preset string
+
+const (
+	// PresetFast favors conversion speed and output size: low resolution
+	// backgrounds and no font extraction.
+	PresetFast Preset = "fast"
+
+	// PresetHighFidelity favors visual accuracy: larger initial zoom and high
+	// resolution backgrounds.
+	PresetHighFidelity Preset = "high-fidelity"
+
+	// PresetArchival produces self-contained pages: backgrounds and fonts are
+	// embedded into HTML files, together with document metadata.
+	PresetArchival Preset = "archival"
+
+	// PresetTextOnly is meant for consuming the text layer only: backgrounds
+	// are barely readable, fonts are not extracted, and text is split up in
+	// table mode, so columns of tabular content are kept apart.
+	PresetTextOnly Preset = "text-only"
+)
+
+// Every preset sets all of its options, including the invisible text policy
+// and the table mode, so the preset alone determines them. Invisible text, the
+// OCR text layer of scanned files, is kept as transparent HTML text by all of
+// them, so scanned pages stay searchable.
+var presets = map[Preset][]option{
+	PresetFast: {
+		WithResolution(72),
+		WithNoFonts(),
+		WithInvisibleText(InvisibleTextTransparent),
+		withModeTable(false),
+	},
+	PresetHighFidelity: {
+		WithInitialZoom(1.5),
+		WithResolution(300),
+		WithInvisibleText(InvisibleTextTransparent),
+		withModeTable(false),
+	},
+	PresetArchival: {
+		WithResolution(150),
+		WithEmbedBackground(),
+		WithEmbedFonts(),
+		WithEmbedMetaTags(),
+		WithInvisibleText(InvisibleTextTransparent),
+		withModeTable(false),
+	},
+	PresetTextOnly: {
+		WithResolution(36),
+		WithNoFonts(),
+		WithInvisibleText(InvisibleTextTransparent),
+		withModeTable(true),
+	},
+}
+
+// withModeTable turns the table mode on or off, see `WithModeTable`.
+func withModeTable(enabled bool) option {
+	return func(c *Command) {
+		c.opts.Table = enabled
+	}
+}
+
+// Apply options of the preset.
+//
+// Options given after the preset take precedence over the preset ones, e.g. using
+// `WithResolution` after `WithPreset(PresetFast)` overrides the resolution.
+func WithPreset(preset Preset) option {
+	return func(c *Command) {
+		opts, ok := presets[preset]
+		if !ok {
+			c.errs = append(c.errs, fmt.Errorf("pdftohtml: unknown preset %q", preset))
+			return
+		}
+
+		for _, opt := range opts {
+			opt(c)
+		}
+	}
+}
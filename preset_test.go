@@ -0,0 +1,47 @@
+package pdftohtml
+
+import "testing"
+
+func TestWithPreset(t *testing.T) {
+	tests := []struct {
+		preset Preset
+		want   OptionSet
+	}{
+		{PresetFast, OptionSet{Resolution: 72, NoFonts: true}},
+		{PresetHighFidelity, OptionSet{Zoom: 1.5, Resolution: 300}},
+		{PresetArchival, OptionSet{Resolution: 150, EmbedBackground: true, EmbedFonts: true, MetaTags: true}},
+		{PresetTextOnly, OptionSet{Resolution: 36, NoFonts: true, Table: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.preset), func(t *testing.T) {
+			// preset determines invisible text and table mode given before it
+			c := &Command{}
+			for _, opt := range []option{WithNoInvisibleText(), WithAllInvisibleText(), WithModeTable(), WithPreset(tt.preset)} {
+				opt(c)
+			}
+			if len(c.errs) > 0 {
+				t.Fatal(c.errs)
+			}
+			if c.opts != tt.want {
+				t.Errorf("got %+v, want %+v", c.opts, tt.want)
+			}
+
+			// options after the preset take precedence
+			WithInvisibleText(InvisibleTextSkip)(c)
+			WithResolution(96)(c)
+			if !c.opts.NoInvisibleText || c.opts.Resolution != 96 {
+				t.Errorf("got %+v, want options after the preset applied", c.opts)
+			}
+		})
+	}
+}
+
+func TestWithPresetUnknown(t *testing.T) {
+	c := &Command{}
+	WithPreset("slow")(c)
+
+	if len(c.errs) != 1 {
+		t.Errorf("got errors %v, want one", c.errs)
+	}
+}
@@ -0,0 +1,109 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- social preview
+// ----------------------------------------------------------------------------
+
+// PreviewImageName is the name of the social preview image in the outdir.
+const PreviewImageName = "preview.png"
+
+// renderPreview renders the first page of the PDF file into the outdir using
+// Xpdf `pdftopng` executable, opening the file like the run did.
+func (c *Command) renderPreview(ctx context.Context, inpath, outdir string) error {
+	path, err := c.toolPath("pdftopng")
+	if err != nil {
+		return err
+	}
+
+	args := []string{"-f", "1", "-l", "1", "-r", "150"}
+	opts := runOptions(ctx, c)
+	if opts.OwnerPassword != "" {
+		args = append(args, "-opw", opts.OwnerPassword)
+	}
+	if opts.UserPassword != "" {
+		args = append(args, "-upw", opts.UserPassword)
+	}
+
+	root := filepath.Join(outdir, "preview")
+
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, path, append(args, "--", inpath, root)...)
+	cmd.Env = c.environ()
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pdftohtml: pdftopng: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	// `pdftopng` appends zero-padded page number to the root name
+	return os.Rename(root+"-000001.png", filepath.Join(outdir, PreviewImageName))
+}
+
+// previewMeta creates Open Graph and Twitter Card meta tags for the page.
+func previewMeta(baseURL, name, title string) []byte {
+	image := baseURL + "/" + PreviewImageName
+	props := [][2]string{
+		{"og:type", "website"},
+		{"og:title", title},
+		{"og:url", baseURL + "/" + name},
+		{"og:image", image},
+	}
+	names := [][2]string{
+		{"twitter:card", "summary_large_image"},
+		{"twitter:title", title},
+		{"twitter:image", image},
+	}
+
+	var b strings.Builder
+	b.WriteString("\n")
+	for _, p := range props {
+		fmt.Fprintf(&b, "<meta property=\"%s\" content=\"%s\">\n", p[0], html.EscapeString(p[1]))
+	}
+	for _, n := range names {
+		fmt.Fprintf(&b, "<meta name=\"%s\" content=\"%s\">\n", n[0], html.EscapeString(n[1]))
+	}
+
+	return []byte(b.String())
+}
+
+// Render a social preview image of the first page and reference it from Open Graph
+// and Twitter Card meta tags injected into every generated HTML file.
+//
+// The baseURL is the public location the outdir is served from; crawlers require
+// absolute URLs. The image is rendered with Xpdf `pdftopng`, looked up next to
+// the `pdftohtml` executable first, then in the PATH, with the password that
+// opened the PDF file and the environment of the command.
+func WithSocialPreview(baseURL string) option {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	return func(c *Command) {
+		c.stages = append(c.stages, func(ctx context.Context, inpath, outdir string) error {
+			if err := c.renderPreview(ctx, inpath, outdir); err != nil {
+				return err
+			}
+
+			fallback := strings.TrimSuffix(filepath.Base(inpath), filepath.Ext(inpath))
+
+			return rewriteHTML(outdir, func(name string, data []byte) ([]byte, error) {
+				title := htmlTitle(data)
+				if title == "" {
+					title = fallback
+				}
+
+				return insertHead(data, previewMeta(baseURL, name, title)), nil
+			})
+		})
+	}
+}
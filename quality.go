@@ -0,0 +1,219 @@
+package pdftohtml
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------------------
+// -- conversion quality
+// ----------------------------------------------------------------------------
+
+// ErrLowQuality is returned by the command with the quality gate when the
+// conversion is flagged, so the fallback chain moves to the next runner.
+var ErrLowQuality = errors.New("pdftohtml: low conversion quality")
+
+// QualityName is the name of the quality report in the outdir.
+const QualityName = "quality.json"
+
+// QualityThresholds tell when the conversion is flagged. Zero fields are not
+// checked.
+type QualityThresholds struct {
+	MinTextRatio        float64 // fraction of pages with text
+	MaxReplacementRatio float64 // fraction of replacement characters in the text
+	MaxFontErrors       int     // font errors reported by `pdftohtml`
+	MaxWarningDensity   float64 // warnings and errors reported per page
+}
+
+// DefaultQualityThresholds flag documents that are mostly scanned, have broken
+// text encoding, or made `pdftohtml` complain a lot.
+var DefaultQualityThresholds = QualityThresholds{
+	MinTextRatio:        0.5,
+	MaxReplacementRatio: 0.01,
+	MaxFontErrors:       5,
+	MaxWarningDensity:   10,
+}
+
+// QualityReport describes heuristic quality of the conversion.
+type QualityReport struct {
+	Pages            int      `json:"pages"`
+	TextPages        int      `json:"text-pages"`
+	EmptyPages       []int    `json:"empty-pages,omitempty"` // pages without any text
+	Characters       int      `json:"characters"`
+	ReplacementChars int      `json:"replacement-chars"`
+	FontErrors       int      `json:"font-errors"`
+	Warnings         int      `json:"warnings"`
+	Flags            []string `json:"flags,omitempty"` // reasons for review
+}
+
+// TextRatio returns the fraction of pages with text.
+func (r *QualityReport) TextRatio() float64 {
+	if r.Pages == 0 {
+		return 0
+	}
+
+	return float64(r.TextPages) / float64(r.Pages)
+}
+
+// NeedsReview reports whether the conversion was flagged.
+func (r *QualityReport) NeedsReview() bool {
+	return len(r.Flags) > 0
+}
+
+// reToolMessage matches warnings and errors printed by `pdftohtml`.
+var reToolMessage = regexp.MustCompile(`^(?:Syntax )?(?:Warning|Error)\b`)
+
+// AssessQuality scores the conversion in the outdir, using the diagnostics the
+// tool printed to the standard error, if available, and flags it against the
+// thresholds.
+func AssessQuality(outdir string, stderr []byte, t QualityThresholds) (*QualityReport, error) {
+	names, err := listHTML(outdir)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &QualityReport{}
+	for _, name := range names {
+		n := pageNumber(name)
+		if n == 0 {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(outdir, name))
+		if err != nil {
+			return nil, err
+		}
+		r.Pages++
+
+		text := html.UnescapeString(string(htmlText(data)))
+
+		chars := 0
+		for _, c := range text {
+			switch {
+			case c == utf8.RuneError:
+				r.ReplacementChars++
+				chars++
+			case !unicode.IsSpace(c):
+				chars++
+			}
+		}
+		r.Characters += chars
+
+		if chars > 0 {
+			r.TextPages++
+		} else {
+			r.EmptyPages = append(r.EmptyPages, n)
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(stderr))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !reToolMessage.MatchString(line) {
+			continue
+		}
+
+		r.Warnings++
+		if strings.Contains(strings.ToLower(line), "font") {
+			r.FontErrors++
+		}
+	}
+
+	if t.MinTextRatio > 0 && r.TextRatio() < t.MinTextRatio {
+		r.Flags = append(r.Flags, fmt.Sprintf("only %d of %d pages have text", r.TextPages, r.Pages))
+	}
+	if t.MaxReplacementRatio > 0 && r.Characters > 0 && float64(r.ReplacementChars)/float64(r.Characters) > t.MaxReplacementRatio {
+		r.Flags = append(r.Flags, fmt.Sprintf("%d of %d characters are replacement characters", r.ReplacementChars, r.Characters))
+	}
+	if t.MaxFontErrors > 0 && r.FontErrors > t.MaxFontErrors {
+		r.Flags = append(r.Flags, fmt.Sprintf("%d font errors", r.FontErrors))
+	}
+	if t.MaxWarningDensity > 0 && r.Pages > 0 && float64(r.Warnings)/float64(r.Pages) > t.MaxWarningDensity {
+		r.Flags = append(r.Flags, fmt.Sprintf("%d warnings on %d pages", r.Warnings, r.Pages))
+	}
+
+	return r, nil
+}
+
+// stderrKey is the context key of the buffer collecting the standard error of
+// the tool during the run.
+type stderrKey struct{}
+
+// withStderr returns the context collecting the standard error of the tool.
+func withStderr(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(stderrKey{}).(*bytes.Buffer); ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, stderrKey{}, &bytes.Buffer{})
+}
+
+// stderrFrom returns the standard error collected in the context, or nil.
+func stderrFrom(ctx context.Context) *bytes.Buffer {
+	buf, _ := ctx.Value(stderrKey{}).(*bytes.Buffer)
+
+	return buf
+}
+
+// assessStage creates the stage assessing the quality of the conversion.
+func assessStage(t QualityThresholds, fn func(outdir string, r *QualityReport) error) stage {
+	return func(ctx context.Context, _, outdir string) error {
+		var stderr []byte
+		if buf := stderrFrom(ctx); buf != nil {
+			stderr = buf.Bytes()
+		}
+
+		r, err := AssessQuality(outdir, stderr, t)
+		if err != nil {
+			return err
+		}
+
+		return fn(outdir, r)
+	}
+}
+
+// ----------------------------------------------------------------------------
+// -- conversion quality options
+// ----------------------------------------------------------------------------
+
+// Write the `quality.json` with the quality report, flagged against the
+// thresholds, into the outdir, e.g. to queue flagged documents for review.
+func WithQualityReport(t QualityThresholds) option {
+	return func(c *Command) {
+		c.stderr = true
+		c.stages = append(c.stages, assessStage(t, func(outdir string, r *QualityReport) error {
+			data, err := json.MarshalIndent(r, "", "  ")
+			if err != nil {
+				return err
+			}
+
+			return os.WriteFile(filepath.Join(outdir, QualityName), data, 0o644)
+		}))
+	}
+}
+
+// Fail the conversion with `ErrLowQuality` when it is flagged against the
+// thresholds, e.g. so the `Chain` falls back to the OCR runner.
+func WithQualityGate(t QualityThresholds) option {
+	return func(c *Command) {
+		c.stderr = true
+		c.stages = append(c.stages, assessStage(t, func(_ string, r *QualityReport) error {
+			if r.NeedsReview() {
+				return fmt.Errorf("%w: %s", ErrLowQuality, strings.Join(r.Flags, ", "))
+			}
+
+			return nil
+		}))
+	}
+}
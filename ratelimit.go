@@ -0,0 +1,160 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- rate limiter
+// ----------------------------------------------------------------------------
+
+// ErrRateLimited is returned by the rate limiter when the caller ran out of
+// conversions, see `RateLimitError`.
+var ErrRateLimited = errors.New("pdftohtml: rate limited")
+
+// RateLimitError is returned by the rate limiter when the caller ran out of
+// conversions. It matches `ErrRateLimited`.
+type RateLimitError struct {
+	Key        string
+	RetryAfter time.Duration // time until the next conversion is allowed
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s: retry after %s", ErrRateLimited, e.RetryAfter.Round(time.Millisecond))
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+type rateLimitKey struct{}
+
+// WithRateLimitKey returns the context whose conversions are limited by the
+// key, e.g. API key or tenant ID. Conversions without the key share one limit.
+func WithRateLimitKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, rateLimitKey{}, key)
+}
+
+// RateLimiter limits the rate of conversions of the runner per caller, telling
+// callers by the key of the context, see `WithRateLimitKey`.
+//
+// Every caller has a token bucket: it holds up to the burst of conversions, and
+// refills at the limit.
+type RateLimiter struct {
+	runner Runner
+	rate   float64 // tokens per second
+	burst  int
+	key    func(context.Context) string
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates new rate limiter around the runner, allowing every
+// caller n conversions per interval.
+func NewRateLimiter(runner Runner, n int, per time.Duration, opts ...rateLimiterOption) *RateLimiter {
+	l := &RateLimiter{
+		runner:  runner,
+		rate:    float64(n) / per.Seconds(),
+		burst:   n,
+		key:     rateLimitKeyFrom,
+		buckets: make(map[string]*bucket),
+		swept:   time.Now(),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+func rateLimitKeyFrom(ctx context.Context) string {
+	key, _ := ctx.Value(rateLimitKey{}).(string)
+
+	return key
+}
+
+// Run executes the conversion, unless the caller ran out of conversions.
+func (l *RateLimiter) Run(ctx context.Context, inpath, outdir string) error {
+	if err := l.take(l.key(ctx), time.Now()); err != nil {
+		return err
+	}
+
+	return l.runner.Run(ctx, inpath, outdir)
+}
+
+// take takes the token from the bucket of the key.
+func (l *RateLimiter) take(key string, now time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*l.rate, float64(l.burst))
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return &RateLimitError{Key: key, RetryAfter: wait}
+	}
+	b.tokens--
+
+	return nil
+}
+
+// sweep removes buckets refilled since, which are no different from new ones,
+// so keys of past callers do not pile up.
+func (l *RateLimiter) sweep(now time.Time) {
+	full := time.Duration(float64(l.burst) / l.rate * float64(time.Second))
+	if now.Sub(l.swept) < full {
+		return
+	}
+	l.swept = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// ----------------------------------------------------------------------------
+// -- rate limiter options
+// ----------------------------------------------------------------------------
+
+type rateLimiterOption func(*RateLimiter)
+
+// Set the number of conversions the caller can run at once, after being idle.
+//
+// The default is the number of conversions per interval.
+func WithRateLimiterBurst(n int) rateLimiterOption {
+	return func(l *RateLimiter) {
+		l.burst = max(n, 1)
+	}
+}
+
+// Set the function telling callers by the context, e.g. by the authenticated
+// user stored in it by the middleware.
+//
+// The default is the key set by `WithRateLimitKey`.
+func WithRateLimiterKey(fn func(context.Context) string) rateLimiterOption {
+	return func(l *RateLimiter) {
+		l.key = fn
+	}
+}
@@ -0,0 +1,251 @@
+package pdftohtml
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- conversion report
+// ----------------------------------------------------------------------------
+
+// ReportName is the name of the conversion report in the outdir.
+const ReportName = "report.json"
+
+// Report aggregates what is known about the conversion, e.g. for dashboards of
+// conversion pipelines.
+type Report struct {
+	Pages    int           `json:"pages"`
+	Fonts    ReportFonts   `json:"fonts"`
+	Images   int           `json:"images"`
+	Bytes    int64         `json:"imageBytes"`
+	Warnings []string      `json:"warnings,omitempty"`
+	Timings  ReportTimings `json:"timings"`
+	Options  string        `json:"options"`           // SHA-256 of the arguments, like in the manifest
+	Version  string        `json:"version,omitempty"` // version of `pdftohtml`, if detected
+}
+
+// ReportFonts compares the fonts of the PDF file with the font files extracted
+// by `pdftohtml`. Fonts of encrypted files are not read, only the extracted ones
+// are listed.
+type ReportFonts struct {
+	Used      []string `json:"used,omitempty"`      // base names of the fonts of the pages
+	Embedded  []string `json:"embedded,omitempty"`  // used fonts embedded in the PDF file
+	Extracted []string `json:"extracted,omitempty"` // font files in the outdir
+}
+
+// ReportTimings are the durations of the phases of the conversion, in
+// nanoseconds in JSON.
+type ReportTimings struct {
+	Exec        time.Duration `json:"exec"`        // `pdftohtml` itself, including password candidates
+	PostProcess time.Duration `json:"postProcess"` // blank pages, renditions and the stages
+	Packaging   time.Duration `json:"packaging"`   // manifest
+}
+
+// reImageFile matches names of the image files in the outdir.
+var reImageFile = regexp.MustCompile(`(?i)\.(?:png|jpe?g|gif|webp|avif|svg)$`)
+
+// collectReport collects the report of the conversion in the outdir.
+func (c *Command) collectReport(ctx context.Context, inpath, outdir string, record *runRecord, warnings []string) (*Report, error) {
+	r := &Report{
+		Timings:  record.timings,
+		Options:  c.optionsHash(),
+		Warnings: slices.Clone(c.Warnings()),
+	}
+	r.Warnings = append(r.Warnings, warnings...)
+
+	if version, err := DetectVersion(ctx, c.path); err == nil {
+		r.Version = version.String()
+	}
+
+	err := filepath.WalkDir(outdir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		name := d.Name()
+		switch {
+		case pageNumber(name) > 0 && filepath.Dir(path) == outdir:
+			r.Pages++
+		case reFontFile.MatchString(name):
+			r.Fonts.Extracted = append(r.Fonts.Extracted, name)
+		case reImageFile.MatchString(name):
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			r.Images++
+			r.Bytes += info.Size()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.Fonts.Used, r.Fonts.Embedded, err = pdfFonts(inpath)
+	if err != nil && !errors.Is(err, ErrUnsupportedPDF) {
+		return nil, err
+	}
+
+	if buf := stderrFrom(ctx); buf != nil {
+		scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+		for scanner.Scan() {
+			if line := scanner.Text(); reToolMessage.MatchString(line) {
+				r.Warnings = append(r.Warnings, line)
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// pdfFonts returns the base names of the fonts used by the pages of the PDF file,
+// and of those embedded in it, without the subset prefixes.
+func pdfFonts(inpath string) ([]string, []string, error) {
+	r, err := openPDFFile(inpath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	used := make(map[string]bool)
+	seen := make(map[pdfRef]bool)
+
+	var resources func(obj any, depth int)
+	resources = func(obj any, depth int) {
+		res := r.dict(obj)
+		if res == nil || depth > pdfMaxDepth {
+			return
+		}
+
+		for _, font := range r.dict(res["Font"]) {
+			if ref, ok := font.(pdfRef); ok {
+				if seen[ref] {
+					continue
+				}
+				seen[ref] = true
+			}
+
+			font := r.dict(font)
+			name, _ := r.resolve(font["BaseFont"]).(pdfName)
+			if name == "" {
+				continue
+			}
+			_, base, ok := strings.Cut(string(name), "+")
+			if !ok || len(string(name))-len(base) != 7 {
+				base = string(name)
+			}
+			used[base] = used[base] || fontEmbedded(r, font)
+		}
+
+		// forms drawn on the pages have their own resources
+		for _, xobj := range r.dict(res["XObject"]) {
+			if ref, ok := xobj.(pdfRef); ok {
+				if seen[ref] {
+					continue
+				}
+				seen[ref] = true
+			}
+			if stream, ok := r.resolve(xobj).(*pdfStream); ok && r.resolve(stream.dict["Subtype"]) == pdfName("Form") {
+				resources(stream.dict["Resources"], depth+1)
+			}
+		}
+	}
+
+	for _, page := range r.pages() {
+		// resources are inherited from the page tree
+		node := page.dict
+		for depth := 0; node != nil && node["Resources"] == nil && depth < pdfMaxDepth; depth++ {
+			node = r.dict(node["Parent"])
+		}
+		if node != nil {
+			resources(node["Resources"], 0)
+		}
+	}
+
+	var names, embedded []string
+	for name, emb := range used {
+		names = append(names, name)
+		if emb {
+			embedded = append(embedded, name)
+		}
+	}
+	slices.Sort(names)
+	slices.Sort(embedded)
+
+	return names, embedded, nil
+}
+
+// fontEmbedded reports whether the program of the font is embedded. Type 3 fonts
+// are drawn by the content of the file, so always embedded.
+func fontEmbedded(r *pdfReader, font pdfDict) bool {
+	switch r.resolve(font["Subtype"]) {
+	case pdfName("Type3"):
+		return true
+	case pdfName("Type0"):
+		if descendants := r.array(font["DescendantFonts"]); len(descendants) > 0 {
+			font = r.dict(descendants[0])
+		}
+	}
+
+	desc := r.dict(font["FontDescriptor"])
+	for _, key := range []pdfName{"FontFile", "FontFile2", "FontFile3"} {
+		if desc[key] != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeReport writes the report into the outdir.
+func writeReport(outdir string, r *Report) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(outdir, ReportName), data, 0o644)
+}
+
+// ReadReport reads the conversion report from the outdir.
+func ReadReport(outdir string) (*Report, error) {
+	data, err := os.ReadFile(filepath.Join(outdir, ReportName))
+	if err != nil {
+		return nil, err
+	}
+
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// ----------------------------------------------------------------------------
+// -- conversion report options
+// ----------------------------------------------------------------------------
+
+// Write the `report.json` with the conversion report into the outdir, see
+// `Report`. The report is written last, after the manifest, so it is not listed
+// in the manifest.
+//
+// Fonts of the PDF file are read by the package itself, fonts of encrypted files
+// are not compared.
+func WithReport() option {
+	return func(c *Command) {
+		c.stderr = true
+		c.report = true
+	}
+}
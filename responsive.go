@@ -0,0 +1,129 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- responsive backgrounds
+// ----------------------------------------------------------------------------
+
+// BackgroundVariant is an extra rendition of the page backgrounds, stored as
+// `page<N>-<name>.png`.
+type BackgroundVariant struct {
+	Name       string
+	Resolution uint64 // in DPI
+}
+
+// DefaultBackgroundVariants are small, medium and large backgrounds, for phones
+// on slow connections up to 4K monitors.
+var DefaultBackgroundVariants = []BackgroundVariant{
+	{Name: "small", Resolution: 50},
+	{Name: "medium", Resolution: 100},
+	{Name: "large", Resolution: 200},
+}
+
+// reVariantName matches valid names of the background variants.
+var reVariantName = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+// renderVariants renders the background variants and lists them, together with
+// the original background, in the `srcset` attribute of the background images
+// with their widths, so browsers pick the one fitting the viewport.
+func (c *Command) renderVariants(ctx context.Context, opts OptionSet, inpath, outdir string) error {
+	rendered := make(map[int][]BackgroundVariant)
+	for _, variant := range c.variants {
+		pages, err := c.renderBackgrounds(ctx, opts, inpath, outdir, variant.Resolution, "-"+variant.Name)
+		if err != nil {
+			return err
+		}
+
+		for _, n := range pages {
+			rendered[n] = append(rendered[n], variant)
+		}
+	}
+
+	zoom, _ := c.pixelScale()
+
+	for n, variants := range rendered {
+		path := filepath.Join(outdir, fmt.Sprintf("page%d.html", n))
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		tag := reBackground.Find(data)
+		value, _ := attrValue(tag, "width")
+		width, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue // size of the page is unknown
+		}
+
+		// pixel width of the image rendered at the resolution
+		pixels := func(dpi uint64) int {
+			return int(math.Round(width / zoom / 72 * float64(dpi)))
+		}
+
+		var srcset []string
+		if src, ok := attrValue(tag, "src"); ok && !strings.HasPrefix(src, "data:") {
+			srcset = append(srcset, fmt.Sprintf("%s %dw", src, pixels(c.baseResolution())))
+		}
+		for _, variant := range variants {
+			srcset = append(srcset, fmt.Sprintf("page%d-%s.png %dw", n, variant.Name, pixels(variant.Resolution)))
+		}
+
+		css := formatPixels(width)
+		data = setBackgroundAttr(data, "srcset", strings.Join(srcset, ", "))
+		data = setBackgroundAttr(data, "sizes", fmt.Sprintf("(max-width: %spx) 100vw, %spx", css, css))
+
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+// -- responsive options
+// ----------------------------------------------------------------------------
+
+// Render page backgrounds also in the variants, `DefaultBackgroundVariants` if
+// none are given, and list them with their widths in the `srcset` and `sizes`
+// attributes of the background images, so browsers download the smallest one
+// fitting the viewport and the pixel density.
+//
+// Every variant is rendered with an extra run of `pdftohtml`. Variants can't be
+// combined with `WithHiDPIBackgrounds`.
+func WithResponsiveBackgrounds(variants ...BackgroundVariant) option {
+	return func(c *Command) {
+		if len(variants) == 0 {
+			variants = DefaultBackgroundVariants
+		}
+
+		if len(c.densities) > 0 {
+			c.errs = append(c.errs, errors.New("pdftohtml: responsive backgrounds can't be combined with hi-DPI backgrounds"))
+			return
+		}
+
+		for _, variant := range variants {
+			if !reVariantName.MatchString(variant.Name) {
+				c.errs = append(c.errs, fmt.Errorf("pdftohtml: invalid background variant name %q", variant.Name))
+				return
+			}
+			if !c.checkRange(RangeError{Option: "resolution", Value: float64(variant.Resolution), Min: 8, Max: 2400}) {
+				return
+			}
+		}
+
+		c.variants = variants
+	}
+}
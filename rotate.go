@@ -0,0 +1,333 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- upright pages
+// ----------------------------------------------------------------------------
+
+// uprightMinText is the length of the text, in characters, the orientation of
+// the page is detected from.
+const uprightMinText = 20
+
+// reRotate matches the rotation of the positioned element.
+var reRotate = regexp.MustCompile(`(?i)\brotate\(\s*(-?[0-9.]+)deg\s*\)`)
+
+// styleProp returns the value of the property of the inline style.
+func styleProp(style, prop string) (string, bool) {
+	for _, decl := range strings.Split(style, ";") {
+		key, val, ok := strings.Cut(decl, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), prop) {
+			return strings.TrimSpace(val), true
+		}
+	}
+
+	return "", false
+}
+
+// setStyleProp sets the property of the inline style, or removes it if the value
+// is empty.
+func setStyleProp(style, prop, val string) string {
+	var decls []string
+	found := false
+	for _, decl := range strings.Split(style, ";") {
+		key, _, _ := strings.Cut(decl, ":")
+		switch {
+		case strings.TrimSpace(decl) == "":
+			continue
+		case strings.EqualFold(strings.TrimSpace(key), prop):
+			if val != "" && !found {
+				decls = append(decls, prop+":"+val)
+			}
+			found = true
+		default:
+			decls = append(decls, strings.TrimSpace(decl))
+		}
+	}
+	if val != "" && !found {
+		decls = append(decls, prop+":"+val)
+	}
+
+	if len(decls) == 0 {
+		return ""
+	}
+
+	return strings.Join(decls, "; ") + ";"
+}
+
+// pixelProp returns the value of the property of the inline style in pixels.
+func pixelProp(style, prop string) (float64, bool) {
+	val, ok := styleProp(style, prop)
+	if !ok {
+		return 0, false
+	}
+
+	v, err := strconv.ParseFloat(strings.TrimSuffix(val, "px"), 64)
+
+	return v, err == nil
+}
+
+// textRotation returns the clockwise rotation of the text element, in degrees.
+func textRotation(style string) int {
+	transform, _ := styleProp(style, "transform")
+
+	match := reRotate.FindStringSubmatch(transform)
+	if match == nil {
+		return 0
+	}
+
+	deg, _ := strconv.ParseFloat(match[1], 64)
+
+	return ((int(math.Round(deg/90))*90)%360 + 360) % 360
+}
+
+// detectRotation returns the clockwise rotation making the text of the page
+// upright: the opposite of the rotation of the most of the text, if sideways.
+func detectRotation(doc *htmlNode) int {
+	weights := make(map[int]int)
+	total := 0
+
+	doc.walk(func(n *htmlNode) bool {
+		class, _ := n.attr("class")
+		if n.kind != elementNode || n.tag != "div" || class != "txt" {
+			return true
+		}
+
+		style, _ := n.attr("style")
+		length := len([]rune(strings.TrimSpace(n.textContent())))
+		weights[textRotation(style)] += length
+		total += length
+
+		return false
+	})
+
+	if total < uprightMinText {
+		return 0
+	}
+	for rot, weight := range weights {
+		if rot != 0 && 2*weight > total {
+			return (360 - rot) % 360
+		}
+	}
+
+	return 0
+}
+
+// rotatePoint rotates the point of the page of the width and height clockwise.
+func rotatePoint(x, y, w, h float64, rot int) (float64, float64) {
+	switch rot {
+	case 90:
+		return h - y, x
+	case 180:
+		return w - x, h - y
+	case 270:
+		return y, w - x
+	}
+
+	return x, y
+}
+
+// rotateImage rotates the PNG image clockwise, and returns it with its width.
+func rotateImage(data []byte, rot int) ([]byte, int, error) {
+	src, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	bounds := image.Rect(0, 0, h, w)
+	if rot == 180 {
+		bounds = image.Rect(0, 0, w, h)
+	}
+
+	// palette and grayscale of the image are kept
+	var dst draw.Image
+	var set func(dx, dy, x, y int)
+	switch s := src.(type) {
+	case *image.Paletted:
+		d := image.NewPaletted(bounds, s.Palette)
+		dst, set = d, func(dx, dy, x, y int) { d.SetColorIndex(dx, dy, s.ColorIndexAt(x, y)) }
+	case *image.Gray:
+		d := image.NewGray(bounds)
+		dst, set = d, func(dx, dy, x, y int) { d.SetGray(dx, dy, s.GrayAt(x, y)) }
+	default:
+		d := image.NewNRGBA(bounds)
+		dst, set = d, func(dx, dy, x, y int) { d.Set(dx, dy, src.At(x, y)) }
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch rot {
+			case 90:
+				dx, dy = h-1-y, x
+			case 180:
+				dx, dy = w-1-x, h-1-y
+			default:
+				dx, dy = y, w-1-x
+			}
+			set(dx, dy, b.Min.X+x, b.Min.Y+y)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, 0, err
+	}
+
+	return buf.Bytes(), bounds.Dx(), nil
+}
+
+// rotatePage rotates the page clockwise: the background and its renditions, and
+// the positioned elements. Boxes, e.g. form fields, keep their orientation, text
+// is rotated with the page.
+func rotatePage(outdir string, n int, doc *htmlNode, rot int) error {
+	background := findBackground(doc)
+	if background == nil {
+		return nil
+	}
+
+	attr, _ := background.attr("width")
+	width, err := strconv.ParseFloat(attr, 64)
+	if err != nil {
+		return fmt.Errorf("pdftohtml: page %d: background without width", n)
+	}
+	attr, _ = background.attr("height")
+	height, err := strconv.ParseFloat(attr, 64)
+	if err != nil {
+		return fmt.Errorf("pdftohtml: page %d: background without height", n)
+	}
+
+	newWidth, newHeight := height, width
+	if rot == 180 {
+		newWidth, newHeight = width, height
+	}
+
+	err = transformBackground(outdir, n, background, width, newWidth, newHeight, func(data []byte) ([]byte, int, error) {
+		return rotateImage(data, rot)
+	})
+	if err != nil {
+		return err
+	}
+
+	round := func(v float64) string {
+		return formatPixels(math.Round(v*100)/100) + "px"
+	}
+
+	doc.walk(func(node *htmlNode) bool {
+		style, ok := node.attr("style")
+		if node.kind != elementNode || node == background || !ok {
+			return true
+		}
+
+		left, okLeft := pixelProp(style, "left")
+		top, okTop := pixelProp(style, "top")
+		if !okLeft || !okTop {
+			return true
+		}
+
+		w, okWidth := pixelProp(style, "width")
+		h, okHeight := pixelProp(style, "height")
+
+		if okWidth && okHeight && node.tag != "div" {
+			// the box is moved, with the width and height swapped sideways
+			x0, y0 := rotatePoint(left, top, width, height, rot)
+			x1, y1 := rotatePoint(left+w, top+h, width, height, rot)
+			style = setStyleProp(style, "left", round(min(x0, x1)))
+			style = setStyleProp(style, "top", round(min(y0, y1)))
+			if rot != 180 {
+				style = setStyleProp(style, "width", round(h))
+				style = setStyleProp(style, "height", round(w))
+			}
+		} else {
+			// the text is turned around its top-left corner
+			x, y := rotatePoint(left, top, width, height, rot)
+			style = setStyleProp(style, "left", round(x))
+			style = setStyleProp(style, "top", round(y))
+
+			turn := (textRotation(style) + rot) % 360
+			if turn == 0 {
+				style = setStyleProp(style, "transform", "")
+				style = setStyleProp(style, "transform-origin", "")
+			} else {
+				style = setStyleProp(style, "transform", fmt.Sprintf("rotate(%ddeg)", turn))
+				style = setStyleProp(style, "transform-origin", "0 0")
+			}
+		}
+		node.setAttr("style", style)
+
+		// descendants are positioned relative to the element
+		return false
+	})
+
+	return nil
+}
+
+// uprightPages is the stage rotating the pages with sideways text upright, and
+// recording the rotations in the record of the run.
+func (c *Command) uprightPages(ctx context.Context, inpath, outdir string) error {
+	// pdftohtml applies the rotation of the PDF page already
+	applied := make(map[int]int)
+	if r, err := openPDFFile(inpath); err == nil {
+		for i, page := range r.pages() {
+			applied[i+1] = page.rotate
+		}
+	} else if !errors.Is(err, ErrUnsupportedPDF) {
+		return err
+	}
+
+	record := recordFrom(ctx)
+
+	return rewriteHTML(outdir, func(name string, data []byte) ([]byte, error) {
+		n := pageNumber(name)
+		if n == 0 {
+			return data, nil
+		}
+
+		doc, xhtml := parseDocument(data)
+
+		rot := detectRotation(doc)
+		if total := (applied[n] + rot) % 360; total != 0 && record != nil {
+			record.rotations[n] = total
+		}
+		if rot == 0 {
+			return data, nil
+		}
+
+		if err := rotatePage(outdir, n, doc, rot); err != nil {
+			return nil, err
+		}
+
+		return renderDocument(doc, xhtml), nil
+	})
+}
+
+// ----------------------------------------------------------------------------
+// -- upright pages options
+// ----------------------------------------------------------------------------
+
+// Rotate pages upright. `pdftohtml` applies the rotation of the PDF pages, but
+// pages whose content is sideways, e.g. landscape scans with recognized text,
+// are still turned. Such pages are detected from the orientation of the most of
+// their text, and their backgrounds and positioned elements are rotated.
+//
+// The rotation applied to every page, including the rotation of the PDF page,
+// is recorded in the manifest.
+func WithUprightPages() option {
+	return func(c *Command) {
+		c.stages = append(c.stages, c.uprightPages)
+	}
+}
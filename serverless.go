@@ -0,0 +1,88 @@
+package pdftohtml
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- serverless
+// ----------------------------------------------------------------------------
+
+// ServerlessLayerDirs are directories searched for the executable before the
+// PATH, where AWS Lambda layers and most serverless images install binaries.
+var ServerlessLayerDirs = []string{"/opt/bin", "/opt"}
+
+// verifyTimeout limits the detection of the version of the executable.
+const verifyTimeout = 30 * time.Second
+
+// verified caches successful verification of executables, so warm invocations
+// of the same process skip it.
+var verified sync.Map // path -> verification
+
+type verification struct {
+	mu      sync.Mutex
+	done    bool
+	version Version
+}
+
+// verifyBinary detects the version of the executable until it succeeds once per
+// process. Failures are not cached, so an executable installed later or a probe
+// interrupted by a slow cold start is verified again by the next call.
+//
+// The probe is not canceled with the context, so concurrent callers waiting for
+// it do not fail together with the request that started it.
+func verifyBinary(ctx context.Context, path string) (Version, error) {
+	v, _ := verified.LoadOrStore(path, &verification{})
+	entry := v.(*verification)
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.done {
+		return entry.version, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), verifyTimeout)
+	defer cancel()
+
+	version, err := DetectVersion(ctx, path)
+	if err != nil {
+		return Version{}, err
+	}
+	entry.version, entry.done = version, true
+
+	return version, nil
+}
+
+// NewServerlessPipeline creates new pipeline for serverless environments, such as
+// AWS Lambda or Cloud Run, with the `pdftohtml` command built from the options.
+//
+// The executable is looked up in `ServerlessLayerDirs` first and verified once
+// per process. Workspaces are created in the default directory for temporary
+// files, the only writable one in such environments, and limited to the budget
+// in bytes. The output is streamed from the returned pipeline as the archive.
+func NewServerlessPipeline(ctx context.Context, budget int64, opts ...option) (*Pipeline, error) {
+	path := "pdftohtml"
+	for _, dir := range ServerlessLayerDirs {
+		if found, err := exec.LookPath(filepath.Join(dir, "pdftohtml")); err == nil {
+			path = found
+			break
+		}
+	}
+
+	cmd, err := NewCommand(append([]option{WithCustomPath(path)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := verifyBinary(ctx, cmd.Path()); err != nil {
+		return nil, err
+	}
+
+	return NewPipeline(cmd, WithPipelineTempDir(os.TempDir()), WithPipelineDiskLimit(budget)), nil
+}
@@ -0,0 +1,100 @@
+package pdftohtml
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeScript writes the shell script as the executable.
+func writeScript(t *testing.T, path, script string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not executable on Windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "pdftohtml")
+
+	// missing executable is not cached
+	if _, err := verifyBinary(context.Background(), path); err == nil {
+		t.Fatal("missing executable verified")
+	}
+
+	// probe is not canceled with the request
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	writeScript(t, path, "echo 'pdftohtml version 24.02.0'\n")
+	version, err := verifyBinary(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Version{Major: 24, Minor: 2}); version != want {
+		t.Errorf("got %v, want %v", version, want)
+	}
+
+	// success is cached
+	writeScript(t, path, "exit 1\n")
+	if _, err := verifyBinary(context.Background(), path); err != nil {
+		t.Errorf("verification not cached: %v", err)
+	}
+}
+
+func TestNewServerlessPipelineEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not executable on Windows")
+	}
+
+	// layer with the executable in bin and its libraries in lib
+	layer := t.TempDir()
+	bin := filepath.Join(layer, "bin")
+	if err := os.Mkdir(bin, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	envpath := filepath.Join(layer, "env")
+	writeScript(t, filepath.Join(bin, "pdftohtml"), "env > '"+envpath+"'\necho 'pdftohtml version 4.04'\n")
+
+	dirs := ServerlessLayerDirs
+	ServerlessLayerDirs = []string{bin}
+	t.Cleanup(func() { ServerlessLayerDirs = dirs })
+
+	libs := filepath.Join(layer, "lib")
+	t.Setenv("LD_LIBRARY_PATH", libs)
+	t.Setenv("PDFTOHTML_TEST_SECRET", "no")
+
+	p, err := NewServerlessPipeline(context.Background(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := p.runner.(*Command)
+	if want := filepath.Join(bin, "pdftohtml"); cmd.Path() != want {
+		t.Errorf("path %q, want %q", cmd.Path(), want)
+	}
+
+	data, err := os.ReadFile(envpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if !slices.Contains(env, "LD_LIBRARY_PATH="+libs) {
+		t.Errorf("probe environment %q, want LD_LIBRARY_PATH of the layer", env)
+	}
+	if slices.Contains(env, "PDFTOHTML_TEST_SECRET=no") {
+		t.Errorf("probe environment %q inherits the process environment", env)
+	}
+	if !slices.Contains(cmd.environ(), "LD_LIBRARY_PATH="+libs) {
+		t.Errorf("environment %q, want LD_LIBRARY_PATH of the layer", cmd.environ())
+	}
+}
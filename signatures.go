@@ -0,0 +1,305 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- digital signatures
+// ----------------------------------------------------------------------------
+
+// Signatures are the digital signatures of the PDF file, which the generated
+// HTML loses.
+type Signatures struct {
+	Fields []Signature `json:"fields,omitempty"`
+	// DSS reports the document security store, i.e. the material for long-term
+	// validation of the signatures, e.g. certificates and revocation data.
+	DSS bool `json:"dss,omitempty"`
+}
+
+// Signed reports whether any of the signature fields is signed.
+func (s Signatures) Signed() bool {
+	for _, field := range s.Fields {
+		if field.Signed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Signature is the signature field of the PDF file. The signature itself is not
+// verified, only the information recorded with it is read.
+type Signature struct {
+	Field     string     `json:"field"`
+	Page      int        `json:"page,omitempty"` // 0 if the page of the widget is unknown
+	Signed    bool       `json:"signed"`         // false for fields waiting to be signed
+	Signer    string     `json:"signer,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+	Location  string     `json:"location,omitempty"`
+	Time      *time.Time `json:"time,omitempty"`
+	SubFilter string     `json:"subFilter,omitempty"` // e.g. "adbe.pkcs7.detached" or "ETSI.CAdES.detached"
+	// Certified reports the certification signature, which restricts changes of
+	// the document.
+	Certified bool `json:"certified,omitempty"`
+	// Whole reports whether the signed byte range covers the whole file, i.e. no
+	// changes were appended to the file after it was signed.
+	Whole bool `json:"whole,omitempty"`
+}
+
+// DetectSignatures reads the signature fields and the document security store
+// of the PDF file.
+//
+// The file is read by the package itself, which does not read encrypted files,
+// see `ErrUnsupportedPDF`.
+func DetectSignatures(inpath string) (Signatures, error) {
+	r, err := openPDFFile(inpath)
+	if err != nil {
+		return Signatures{}, err
+	}
+
+	catalog := r.catalog()
+	sigs := Signatures{DSS: r.dict(catalog["DSS"]) != nil}
+
+	pageOf := make(map[pdfRef]int)
+	annotPage := make(map[pdfRef]int)
+	for i, page := range r.pages() {
+		pageOf[page.ref] = i + 1
+		for _, annot := range r.array(page.dict["Annots"]) {
+			if ref, ok := annot.(pdfRef); ok {
+				annotPage[ref] = i + 1
+			}
+		}
+	}
+
+	// certification signature is referred to by the permissions of the document
+	certification, _ := r.dict(catalog["Perms"])["DocMDP"].(pdfRef)
+
+	seen := make(map[pdfRef]bool)
+
+	var walk func(obj any, name, ft string, value any, depth int)
+	walk = func(obj any, name, ft string, value any, depth int) {
+		if ref, ok := obj.(pdfRef); ok {
+			if seen[ref] {
+				return
+			}
+			seen[ref] = true
+		}
+
+		node := r.dict(obj)
+		if node == nil || depth > pdfMaxDepth {
+			return
+		}
+
+		if t := r.text(node["T"]); t != "" {
+			if name != "" {
+				name += "."
+			}
+			name += t
+		}
+		if v, ok := r.resolve(node["FT"]).(pdfName); ok {
+			ft = string(v)
+		}
+		if v, ok := node["V"]; ok {
+			value = v
+		}
+
+		// kids without names are the widgets of the field, not fields
+		var widgets []any
+		terminal := true
+		for _, kid := range r.array(node["Kids"]) {
+			if r.dict(kid)["T"] != nil {
+				walk(kid, name, ft, value, depth+1)
+				terminal = false
+			} else {
+				widgets = append(widgets, kid)
+			}
+		}
+		if !terminal || ft != "Sig" || name == "" {
+			return
+		}
+		if len(widgets) == 0 {
+			widgets = append(widgets, obj) // widget merged with the field
+		}
+
+		sig := Signature{Field: name}
+		for _, widget := range widgets {
+			if ref, ok := r.dict(widget)["P"].(pdfRef); ok && sig.Page == 0 {
+				sig.Page = pageOf[ref]
+			}
+			if ref, ok := widget.(pdfRef); ok && sig.Page == 0 {
+				sig.Page = annotPage[ref]
+			}
+		}
+
+		if v := r.dict(value); v != nil {
+			sig.Signed = true
+			sig.Signer = r.text(v["Name"])
+			sig.Reason = r.text(v["Reason"])
+			sig.Location = r.text(v["Location"])
+			if t, ok := parsePDFDate(r.text(v["M"])); ok {
+				sig.Time = &t
+			}
+			if sub, ok := r.resolve(v["SubFilter"]).(pdfName); ok {
+				sig.SubFilter = string(sub)
+			}
+
+			if ref, ok := value.(pdfRef); ok && ref == certification {
+				sig.Certified = true
+			}
+			for _, ref := range r.array(v["Reference"]) {
+				if r.resolve(r.dict(ref)["TransformMethod"]) == pdfName("DocMDP") {
+					sig.Certified = true
+				}
+			}
+
+			// byte range is the file except the hexadecimal signature itself
+			if rng := r.array(v["ByteRange"]); len(rng) == 4 {
+				var off [4]float64
+				for i := range off {
+					off[i], _ = r.number(rng[i])
+				}
+				sig.Whole = off[0] == 0 && int(off[2]+off[3]) == len(r.data)
+			}
+		}
+
+		sigs.Fields = append(sigs.Fields, sig)
+	}
+
+	for _, field := range r.array(r.dict(catalog["AcroForm"])["Fields"]) {
+		walk(field, "", "", nil, 0)
+	}
+
+	return sigs, nil
+}
+
+// parsePDFDate parses the date string of the PDF file, e.g. "D:20240131120000+01'00'".
+// Missing fields default to their least value, and missing offset to UTC.
+func parsePDFDate(s string) (time.Time, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "D:")
+	if len(s) < 4 {
+		return time.Time{}, false
+	}
+
+	// year, month, day, hour, minute and second
+	fields := [6]int{0, 1, 1, 0, 0, 0}
+	for i, width := range []int{4, 2, 2, 2, 2, 2} {
+		if len(s) < width || s[0] < '0' || s[0] > '9' {
+			break
+		}
+		v, err := strconv.Atoi(s[:width])
+		if err != nil {
+			return time.Time{}, false
+		}
+		fields[i], s = v, s[width:]
+	}
+
+	loc := time.UTC
+	if len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+		var hh, mm int
+		digits := strings.NewReplacer("'", "").Replace(s[1:])
+		if len(digits) >= 2 {
+			hh, _ = strconv.Atoi(digits[:2])
+		}
+		if len(digits) >= 4 {
+			mm, _ = strconv.Atoi(digits[2:4])
+		}
+		offset := hh*3600 + mm*60
+		if s[0] == '-' {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	}
+
+	t := time.Date(fields[0], time.Month(fields[1]), fields[2], fields[3], fields[4], fields[5], 0, loc)
+
+	return t, true
+}
+
+// signatureBadge returns the markup of the badge noting the document was signed.
+func signatureBadge(sigs Signatures) []byte {
+	var signed []Signature
+	for _, field := range sigs.Fields {
+		if field.Signed {
+			signed = append(signed, field)
+		}
+	}
+
+	label := "Digitally signed"
+	first := signed[0]
+	if first.Certified {
+		label = "Certified"
+	}
+	if first.Signer != "" {
+		label += " by " + first.Signer
+	}
+	if first.Time != nil {
+		label += " on " + first.Time.Format("2006-01-02")
+	}
+	if len(signed) > 1 {
+		label += fmt.Sprintf(" (%d signatures)", len(signed))
+	}
+
+	return []byte(fmt.Sprintf("<div class=\"pdftohtml-signed\" style=\"position:fixed; right:8px; bottom:8px; "+
+		"padding:4px 8px; font:12px sans-serif; background:#eef7ee; border:1px solid #5a5; z-index:1000;\" "+
+		"title=\"The signatures of the original PDF file are not verified and do not apply to this copy.\">%s</div>\n",
+		html.EscapeString(label)))
+}
+
+// recordSignatures is the stage recording the signatures of the PDF file in the
+// record of the run, and adding the badge to the index and the pages if signed.
+func recordSignatures(badge bool) stage {
+	return func(ctx context.Context, inpath, outdir string) error {
+		sigs, err := DetectSignatures(inpath)
+		if errors.Is(err, ErrUnsupportedPDF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(sigs.Fields) == 0 && !sigs.DSS {
+			return nil
+		}
+		if record := recordFrom(ctx); record != nil {
+			record.signatures = &sigs
+		}
+
+		if !badge || !sigs.Signed() {
+			return nil
+		}
+
+		markup := signatureBadge(sigs)
+
+		return rewriteHTML(outdir, func(name string, data []byte) ([]byte, error) {
+			if name != "index.html" && pageNumber(name) == 0 {
+				return data, nil
+			}
+
+			return insertBodyEnd(data, markup), nil
+		})
+	}
+}
+
+// ----------------------------------------------------------------------------
+// -- digital signatures options
+// ----------------------------------------------------------------------------
+
+// Detect the digital signatures of the PDF file, and record them in the
+// manifest, see `Signatures`. With the badge, the index and the pages of signed
+// files note the document was signed, since the generated HTML carries none of
+// the signatures.
+//
+// Signatures are read by the package itself, not verified. Signatures of
+// encrypted files are not detected.
+func WithSignatures(badge bool) option {
+	return func(c *Command) {
+		c.stages = append(c.stages, recordSignatures(badge))
+	}
+}
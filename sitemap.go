@@ -0,0 +1,97 @@
+package pdftohtml
+
+import (
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- sitemap
+// ----------------------------------------------------------------------------
+
+const (
+	// SitemapName is the name of the sitemap file in the outdir.
+	SitemapName = "sitemap.xml"
+
+	// RobotsName is the name of the robots rules file in the outdir.
+	RobotsName = "robots.txt"
+)
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// writeSitemap writes the sitemap of all HTML files in the outdir.
+func writeSitemap(baseURL, outdir string) error {
+	names, err := listHTML(outdir)
+	if err != nil {
+		return err
+	}
+
+	set := sitemapURLSet{URLs: make([]sitemapURL, 0, len(names))}
+	for _, name := range names {
+		info, err := os.Stat(filepath.Join(outdir, name))
+		if err != nil {
+			return err
+		}
+
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:     baseURL + "/" + name,
+			LastMod: info.ModTime().UTC().Format(time.RFC3339),
+		})
+	}
+
+	data, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(outdir, SitemapName), append([]byte(xml.Header), data...), 0o644)
+}
+
+// writeRobots writes the robots rules for all user agents, pointing crawlers
+// to the sitemap.
+func writeRobots(baseURL, outdir string, rules []string) error {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	for _, rule := range rules {
+		b.WriteString(rule + "\n")
+	}
+	b.WriteString("\nSitemap: " + baseURL + "/" + SitemapName + "\n")
+
+	return os.WriteFile(filepath.Join(outdir, RobotsName), []byte(b.String()), 0o644)
+}
+
+// Write a `sitemap.xml` covering all generated HTML files, for outdirs published
+// as static sites.
+//
+// The baseURL is the public location the outdir is served from. When robots rules
+// are given (e.g. "Disallow: /private/"), a `robots.txt` referencing the sitemap is
+// written as well.
+func WithSitemap(baseURL string, robots ...string) option {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	return func(c *Command) {
+		c.stages = append(c.stages, func(_ context.Context, _, outdir string) error {
+			if err := writeSitemap(baseURL, outdir); err != nil {
+				return err
+			}
+
+			if len(robots) == 0 {
+				return nil
+			}
+
+			return writeRobots(baseURL, outdir, robots)
+		})
+	}
+}
@@ -0,0 +1,338 @@
+package pdftohtml
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// -- content-addressable store
+// ----------------------------------------------------------------------------
+
+// ErrInvalidStoreKey is returned when the key is not a key of the store, i.e.
+// the hex-encoded SHA-256 hash of the input.
+var ErrInvalidStoreKey = errors.New("pdftohtml: invalid store key")
+
+// storeGCGrace is the age of file contents below which they are not removed by
+// the garbage collection, so documents being stored by other processes keep
+// their contents.
+const storeGCGrace = time.Hour
+
+// Store files conversion outputs under the content hash of the input PDF.
+//
+// Contents of the output files are stored once, no matter how many documents
+// share them, e.g. identical fonts or background images.
+type Store struct {
+	root string
+
+	// mu is held for reading while storing documents and for writing while
+	// collecting garbage
+	mu sync.RWMutex
+}
+
+// StoreFile describes a single output file of the stored document.
+type StoreFile struct {
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+}
+
+// StoreDocument describes the stored conversion output of a single input.
+type StoreDocument struct {
+	Key   string      `json:"key"`
+	Files []StoreFile `json:"files"`
+}
+
+// NewStore creates new store in the root directory.
+func NewStore(root string) (*Store, error) {
+	s := &Store{root: root}
+	for _, dir := range []string{s.blobsDir(), s.docsDir()} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *Store) blobsDir() string { return filepath.Join(s.root, "blobs") }
+func (s *Store) docsDir() string  { return filepath.Join(s.root, "docs") }
+
+func (s *Store) blobPath(hash string) string { return filepath.Join(s.blobsDir(), hash) }
+func (s *Store) docPath(key string) string   { return filepath.Join(s.docsDir(), key+".json") }
+
+// isStoreKey reports whether the key is a hex-encoded SHA-256 hash, the form of
+// both the keys of the documents and the names of the file contents.
+func isStoreKey(key string) bool {
+	if len(key) != 2*sha256.Size {
+		return false
+	}
+
+	for i := 0; i < len(key); i++ {
+		if !('0' <= key[i] && key[i] <= '9' || 'a' <= key[i] && key[i] <= 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Key returns the key the conversion output of the input is stored under.
+func (s *Store) Key(inpath string) (string, error) {
+	return hashFile(inpath)
+}
+
+// Put stores the conversion output of the input and returns its key.
+func (s *Store) Put(inpath, outdir string) (string, error) {
+	key, err := s.Key(inpath)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc := StoreDocument{Key: key}
+
+	err = filepath.WalkDir(outdir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+
+		name, err := filepath.Rel(outdir, path)
+		if err != nil {
+			return err
+		}
+
+		file, err := s.putBlob(path)
+		if err != nil {
+			return err
+		}
+		file.Name = filepath.ToSlash(name)
+		doc.Files = append(doc.Files, file)
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+
+	return key, writeFileAtomic(s.docPath(key), data)
+}
+
+// putBlob stores the content of the file, unless already stored.
+func (s *Store) putBlob(path string) (StoreFile, error) {
+	hash, err := hashFile(path)
+	if err != nil {
+		return StoreFile{}, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return StoreFile{}, err
+	}
+
+	file := StoreFile{Hash: hash, Size: info.Size()}
+
+	// reused contents are renewed, so they are not collected by other processes
+	// before the document is stored
+	now := time.Now()
+	if err := os.Chtimes(s.blobPath(hash), now, now); err == nil {
+		return file, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return StoreFile{}, err
+	}
+
+	return file, writeFileAtomic(s.blobPath(hash), data)
+}
+
+// Lookup returns the stored document. The error satisfies `errors.Is(err, fs.ErrNotExist)`
+// if nothing is stored under the key.
+func (s *Store) Lookup(key string) (*StoreDocument, error) {
+	if !isStoreKey(key) {
+		return nil, ErrInvalidStoreKey
+	}
+
+	data, err := os.ReadFile(s.docPath(key))
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &StoreDocument{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// Restore writes files of the stored document into the outdir.
+func (s *Store) Restore(key, outdir string) error {
+	doc, err := s.Lookup(key)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range doc.Files {
+		name := filepath.FromSlash(file.Name)
+		if !filepath.IsLocal(name) || !isStoreKey(file.Hash) {
+			return fmt.Errorf("pdftohtml: invalid stored file %q of %s", file.Name, key)
+		}
+
+		path := filepath.Join(outdir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+
+		if err := copyFile(s.blobPath(file.Hash), path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Remove forgets the stored document. Its file contents are removed from the
+// store by the next garbage collection, unless shared with other documents.
+func (s *Store) Remove(key string) error {
+	if !isStoreKey(key) {
+		return ErrInvalidStoreKey
+	}
+
+	return os.Remove(s.docPath(key))
+}
+
+// GC removes file contents not referenced by any stored document and returns
+// the number of bytes freed.
+//
+// Documents being stored by the store are waited for. Contents written or reused
+// in the last hour are kept, as they may belong to documents being stored by
+// other processes sharing the root.
+func (s *Store) GC() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	docs, err := os.ReadDir(s.docsDir())
+	if err != nil {
+		return 0, err
+	}
+
+	used := make(map[string]bool)
+	for _, entry := range docs {
+		key, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || !isStoreKey(key) {
+			continue
+		}
+
+		doc, err := s.Lookup(key)
+		if err != nil {
+			return 0, err
+		}
+		for _, file := range doc.Files {
+			used[file.Hash] = true
+		}
+	}
+
+	blobs, err := os.ReadDir(s.blobsDir())
+	if err != nil {
+		return 0, err
+	}
+
+	var freed int64
+	for _, entry := range blobs {
+		// temporary files of contents being written are not collected
+		if used[entry.Name()] || !isStoreKey(entry.Name()) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return freed, err
+		}
+		if time.Since(info.ModTime()) < storeGCGrace {
+			continue
+		}
+
+		if err := os.Remove(s.blobPath(entry.Name())); err != nil {
+			return freed, err
+		}
+		freed += info.Size()
+	}
+
+	return freed, nil
+}
+
+// hashFile returns the hex-encoded SHA-256 hash of the file content.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeFileAtomic writes the data to a temporary file renamed to the path, so
+// readers never see a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// copyFile copies content of the src file into the dst file.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
@@ -0,0 +1,164 @@
+package pdftohtml
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	s, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmp := t.TempDir()
+	inpath := filepath.Join(tmp, "in.pdf")
+	if err := os.WriteFile(inpath, []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	outdir := filepath.Join(tmp, "out")
+	files := map[string]string{"page1.html": "<p>1</p>", "page2.html": "<p>2</p>", "fonts/f1.woff": "font", "f2.woff": "font"}
+	for name, content := range files {
+		path := filepath.Join(outdir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	key, err := s.Put(inpath, outdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blobs, err := os.ReadDir(s.blobsDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blobs) != 3 {
+		t.Errorf("stored %d contents, want 3", len(blobs))
+	}
+
+	restored := filepath.Join(tmp, "restored")
+	if err := s.Restore(key, restored); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		data, err := os.ReadFile(filepath.Join(restored, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("restored %s = %q, want %q", name, data, content)
+		}
+	}
+}
+
+func TestStoreInvalidKey(t *testing.T) {
+	s, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outside := filepath.Join(s.root, "x.json")
+	if err := os.WriteFile(outside, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"", "../x", "../../x", "ABC", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b85g"} {
+		if _, err := s.Lookup(key); !errors.Is(err, ErrInvalidStoreKey) {
+			t.Errorf("Lookup(%q) = %v, want ErrInvalidStoreKey", key, err)
+		}
+		if err := s.Restore(key, t.TempDir()); !errors.Is(err, ErrInvalidStoreKey) {
+			t.Errorf("Restore(%q) = %v, want ErrInvalidStoreKey", key, err)
+		}
+		if err := s.Remove(key); !errors.Is(err, ErrInvalidStoreKey) {
+			t.Errorf("Remove(%q) = %v, want ErrInvalidStoreKey", key, err)
+		}
+	}
+
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("file outside of the store removed: %v", err)
+	}
+}
+
+func TestStoreGC(t *testing.T) {
+	s, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmp := t.TempDir()
+	inpath := filepath.Join(tmp, "in.pdf")
+	outdir := filepath.Join(tmp, "out")
+	if err := os.WriteFile(inpath, []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(outdir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outdir, "page1.html"), []byte("used"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Put(inpath, outdir); err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-2 * storeGCGrace)
+	blob := func(content string, modified time.Time) string {
+		path := filepath.Join(s.blobsDir(), sha256Hex(content))
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	unused := blob("unused", old)
+	recent := blob("recent", time.Now())
+	temp := filepath.Join(s.blobsDir(), ".tmp-1")
+	if err := os.WriteFile(temp, []byte("writing"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(temp, old, old); err != nil {
+		t.Fatal(err)
+	}
+	// contents of stored documents are kept however old
+	used := filepath.Join(s.blobsDir(), sha256Hex("used"))
+	if err := os.Chtimes(used, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	freed, err := s.GC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if freed != int64(len("unused")) {
+		t.Errorf("freed %d bytes, want %d", freed, len("unused"))
+	}
+
+	if _, err := os.Stat(unused); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("unused content kept: %v", err)
+	}
+	for _, path := range []string{recent, temp, used} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s removed: %v", filepath.Base(path), err)
+		}
+	}
+}
+
+// sha256Hex returns the hex-encoded SHA-256 hash of the content.
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,224 @@
+package pdftohtml
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- shared stylesheet
+// ----------------------------------------------------------------------------
+
+// StylesheetName is the name of the stylesheet shared by the pages in the outdir.
+const StylesheetName = "pdftohtml.css"
+
+// ExtractStylesheet moves CSS rules repeated in the inline styles of at least
+// two pages, e.g. font faces and positioning classes, into the stylesheet shared
+// by all pages. Rules are compared by their text.
+//
+// The stylesheet is linked in place of the first inline style of the page, so
+// the shared rules keep preceding the page rules.
+func ExtractStylesheet(outdir string) error {
+	names, err := listHTML(outdir)
+	if err != nil {
+		return err
+	}
+
+	type page struct {
+		name   string
+		doc    *htmlNode
+		xhtml  bool
+		styles []*htmlNode
+	}
+
+	var pages []*page
+	var order []string        // rules in order of their first occurrence
+	count := map[string]int{} // rule -> number of pages
+	for _, name := range names {
+		if pageNumber(name) == 0 {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(outdir, name))
+		if err != nil {
+			return err
+		}
+
+		p := &page{name: name}
+		p.doc, p.xhtml = parseDocument(data)
+
+		seen := map[string]bool{}
+		if head := p.doc.find("head"); head != nil {
+			for _, child := range head.children {
+				if !isPlainStyle(child) {
+					continue
+				}
+				p.styles = append(p.styles, child)
+
+				for _, rule := range splitCSSRules(styleText(child)) {
+					if seen[rule] {
+						continue
+					}
+					seen[rule] = true
+
+					if count[rule]++; count[rule] == 1 {
+						order = append(order, rule)
+					}
+				}
+			}
+		}
+		pages = append(pages, p)
+	}
+
+	var shared []string
+	for _, rule := range order {
+		if count[rule] > 1 {
+			shared = append(shared, rule)
+		}
+	}
+	if len(shared) == 0 {
+		return nil
+	}
+
+	css := strings.Join(shared, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(outdir, StylesheetName), []byte(css), 0o644); err != nil {
+		return err
+	}
+
+	for _, p := range pages {
+		if len(p.styles) == 0 {
+			continue
+		}
+
+		link := &htmlNode{kind: elementNode, tag: "link", attrs: []htmlAttr{
+			{Key: "rel", Val: "stylesheet"},
+			{Key: "type", Val: "text/css"},
+			{Key: "href", Val: StylesheetName},
+		}}
+
+		head := p.styles[0].parent
+		children := make([]*htmlNode, 0, len(head.children)+1)
+		for _, child := range head.children {
+			if child == p.styles[0] {
+				link.parent = head
+				children = append(children, link)
+			}
+
+			if !isPlainStyle(child) {
+				children = append(children, child)
+				continue
+			}
+
+			var kept []string
+			for _, rule := range splitCSSRules(styleText(child)) {
+				if count[rule] < 2 {
+					kept = append(kept, rule)
+				}
+			}
+			if len(kept) > 0 {
+				child.children = nil
+				child.appendChild(&htmlNode{kind: textNode, text: "\n" + strings.Join(kept, "\n") + "\n", raw: true})
+				children = append(children, child)
+			}
+		}
+		head.children = children
+
+		path := filepath.Join(outdir, p.name)
+		if err := os.WriteFile(path, renderDocument(p.doc, p.xhtml), 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isPlainStyle reports whether the node is the style element applying to all
+// media.
+func isPlainStyle(n *htmlNode) bool {
+	if n.kind != elementNode || n.tag != "style" {
+		return false
+	}
+
+	media, _ := n.attr("media")
+
+	return media == "" || media == "all"
+}
+
+// styleText returns the CSS of the style element.
+func styleText(n *htmlNode) string {
+	var b strings.Builder
+	for _, child := range n.children {
+		b.WriteString(child.text)
+	}
+
+	return b.String()
+}
+
+// splitCSSRules splits the style sheet into trimmed top-level rules, including
+// at-rules with their blocks. Comments are dropped.
+func splitCSSRules(css string) []string {
+	var rules []string
+	var rule strings.Builder
+
+	depth := 0
+	var quote byte
+	for i := 0; i < len(css); i++ {
+		b := css[i]
+
+		switch {
+		case quote != 0:
+			if b == '\\' && i+1 < len(css) {
+				rule.WriteByte(b)
+				i++
+				b = css[i]
+			} else if b == quote {
+				quote = 0
+			}
+		case b == '/' && strings.HasPrefix(css[i:], "/*"):
+			end := strings.Index(css[i+2:], "*/")
+			if end < 0 {
+				i = len(css)
+			} else {
+				i += end + 3
+			}
+			continue
+		case b == '"' || b == '\'':
+			quote = b
+		case b == '{':
+			depth++
+		case b == '}' && depth > 0:
+			depth--
+		}
+		rule.WriteByte(b)
+
+		// rules end with their block, statements with the semicolon
+		if quote == 0 && depth == 0 && (b == '}' || b == ';') {
+			if text := strings.TrimSpace(rule.String()); text != "" && text != ";" {
+				rules = append(rules, text)
+			}
+			rule.Reset()
+		}
+	}
+
+	if text := strings.TrimSpace(rule.String()); text != "" {
+		rules = append(rules, text)
+	}
+
+	return rules
+}
+
+// ----------------------------------------------------------------------------
+// -- shared stylesheet options
+// ----------------------------------------------------------------------------
+
+// Move CSS rules repeated across pages into the shared stylesheet linked by all
+// pages. See `ExtractStylesheet`.
+func WithSharedStylesheet() option {
+	return func(c *Command) {
+		c.stages = append(c.stages, func(_ context.Context, _, outdir string) error {
+			return ExtractStylesheet(outdir)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ----------------------------------------------------------------------------
+// -- tenant
+// ----------------------------------------------------------------------------
+
+// ErrInvalidTenantID is returned when the tenant ID cannot be used as a name of
+// the tenant directory.
+var ErrInvalidTenantID = errors.New("pdftohtml: invalid tenant ID")
+
+// Tenant isolates conversions of a single customer sharing the wrapper with
+// others: it has own workspace directory, own limit of concurrent conversions,
+// and own disk limit of a single conversion.
+//
+// Every conversion slot reserves the disk limit for the workspace using it, so
+// the tenant never uses more than the limit times the concurrency.
+type Tenant struct {
+	id          string
+	tmpdir      string
+	concurrency int
+	diskLimit   int64
+
+	pipeline *Pipeline
+	slots    chan struct{}
+	queued   atomic.Int64
+}
+
+// TenantStats describes the conversions of the tenant.
+type TenantStats struct {
+	Active int // conversions holding the slot
+	Queued int // conversions waiting for the slot
+}
+
+// NewTenant creates new tenant converting with the runner.
+func NewTenant(id string, runner Runner, opts ...tenantOption) (*Tenant, error) {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return nil, ErrInvalidTenantID
+	}
+
+	t := &Tenant{id: id, tmpdir: os.TempDir(), concurrency: 1}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	// namespace workspaces of the tenant
+	t.tmpdir = filepath.Join(t.tmpdir, "pdftohtml-tenants", id)
+	if err := os.MkdirAll(t.tmpdir, 0o700); err != nil {
+		return nil, err
+	}
+
+	t.pipeline = NewPipeline(runner, WithPipelineTempDir(t.tmpdir), WithPipelineDiskLimit(t.diskLimit))
+	t.slots = make(chan struct{}, max(t.concurrency, 1))
+
+	return t, nil
+}
+
+// ID returns the tenant ID.
+func (t *Tenant) ID() string {
+	return t.id
+}
+
+// Stats returns the current conversions of the tenant.
+func (t *Tenant) Stats() TenantStats {
+	return TenantStats{Active: len(t.slots), Queued: int(t.queued.Load())}
+}
+
+// Run converts the PDF read from r like `Pipeline.Run`, waiting for a free
+// conversion slot of the tenant first.
+//
+// The slot is held until the returned reader is closed.
+func (t *Tenant) Run(ctx context.Context, r io.Reader) (io.ReadCloser, error) {
+	EmitEvent(ctx, Event{Type: EventQueued})
+
+	t.queued.Add(1)
+	select {
+	case t.slots <- struct{}{}:
+		t.queued.Add(-1)
+	case <-ctx.Done():
+		t.queued.Add(-1)
+		return nil, ctx.Err()
+	}
+
+	release := sync.OnceFunc(func() { <-t.slots })
+
+	rc, err := t.pipeline.Run(ctx, r)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	return &tenantReader{ReadCloser: rc, release: release}, nil
+}
+
+// tenantReader releases the tenant conversion slot on close.
+type tenantReader struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r *tenantReader) Close() error {
+	defer r.release()
+
+	return r.ReadCloser.Close()
+}
+
+// ----------------------------------------------------------------------------
+// -- tenant options
+// ----------------------------------------------------------------------------
+
+type tenantOption func(*Tenant)
+
+// Set custom parent directory for tenant workspaces.
+//
+// By default the workspaces are created in the default directory for temporary
+// files, see `os.TempDir`.
+func WithTenantTempDir(dir string) tenantOption {
+	return func(t *Tenant) {
+		t.tmpdir = dir
+	}
+}
+
+// Limit the number of concurrent conversions of the tenant.
+//
+// By default the tenant runs one conversion at a time.
+func WithTenantConcurrency(n int) tenantOption {
+	return func(t *Tenant) {
+		t.concurrency = n
+	}
+}
+
+// Limit disk usage of a single tenant conversion, in bytes, enforced while the
+// conversion runs like `WithPipelineDiskLimit`.
+//
+// Workspaces are kept until the archive is closed, while the conversion still
+// holds its slot, so the limit times the concurrency bounds the total disk
+// usage of the tenant.
+func WithTenantDiskLimit(bytes int64) tenantOption {
+	return func(t *Tenant) {
+		t.diskLimit = bytes
+	}
+}
@@ -0,0 +1,96 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewTenantInvalidID(t *testing.T) {
+	for _, id := range []string{"", ".", "..", "a/b", `a\b`, "../x"} {
+		if _, err := NewTenant(id, writeOutput(0, 0), WithTenantTempDir(t.TempDir())); !errors.Is(err, ErrInvalidTenantID) {
+			t.Errorf("NewTenant(%q) = %v, want ErrInvalidTenantID", id, err)
+		}
+	}
+}
+
+func TestTenantConcurrency(t *testing.T) {
+	tmpdir := t.TempDir()
+	tenant, err := NewTenant("acme", writeOutput(16, 0), WithTenantTempDir(tmpdir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := tenant.Run(context.Background(), strings.NewReader("%PDF-1.4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// workspaces are namespaced by the tenant
+	entries, err := os.ReadDir(filepath.Join(tmpdir, "pdftohtml-tenants", "acme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("got %d workspaces of the tenant, want 1", len(entries))
+	}
+
+	// conversion waiting for the slot gives up with the context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tenant.Run(ctx, strings.NewReader("%PDF-1.4")); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		rc, err := tenant.Run(context.Background(), strings.NewReader("%PDF-1.4"))
+		if err == nil {
+			_, err = io.Copy(io.Discard, rc)
+			rc.Close()
+		}
+		done <- err
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for tenant.Stats() != (TenantStats{Active: 1, Queued: 1}) {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %+v, want one active and one queued", tenant.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// slot is held until the archive is closed
+	if _, err := io.Copy(io.Discard, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := tenant.Stats(); got != (TenantStats{}) {
+		t.Errorf("got %+v, want no conversions", got)
+	}
+}
+
+func TestTenantDiskLimit(t *testing.T) {
+	tenant, err := NewTenant("acme", writeOutput(2048, time.Minute), WithTenantTempDir(t.TempDir()), WithTenantDiskLimit(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tenant.Run(context.Background(), strings.NewReader("%PDF-1.4")); !errors.Is(err, ErrDiskLimitExceeded) {
+		t.Errorf("got %v, want ErrDiskLimitExceeded", err)
+	}
+	if got := tenant.Stats(); got != (TenantStats{}) {
+		t.Errorf("got %+v, want the slot released", got)
+	}
+}
@@ -0,0 +1,243 @@
+package pdftohtml
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ----------------------------------------------------------------------------
+// -- text only
+// ----------------------------------------------------------------------------
+
+// textLine is the line of text of the page, joined from the text elements at
+// the same height.
+type textLine struct {
+	top, left, size float64
+	text            string
+}
+
+// Relative sizes of the headings to the size of the body text.
+const (
+	textHeading1 = 1.5
+	textHeading2 = 1.2
+)
+
+// reListItem matches the bullet or the number starting the list item.
+var reListItem = regexp.MustCompile(`^(?:([•●▪◦‣∙·\-–*])|(\d{1,3}[.)]|[a-z][.)]|\(\d{1,3}\)))\s+`)
+
+// textLines returns the lines of text of the page, in the reading order.
+func textLines(doc *htmlNode) []textLine {
+	var parts []textLine
+	doc.walk(func(n *htmlNode) bool {
+		class, _ := n.attr("class")
+		if n.kind != elementNode || n.tag != "div" || !strings.Contains(" "+class+" ", " txt ") {
+			return true
+		}
+
+		style, _ := n.attr("style")
+		part := textLine{text: strings.TrimSpace(n.textContent())}
+		part.left, _ = pixelProp(style, "left")
+		part.top, _ = pixelProp(style, "top")
+		n.walk(func(m *htmlNode) bool {
+			style, _ := m.attr("style")
+			if size, ok := pixelProp(style, "font-size"); ok {
+				part.size = max(part.size, size)
+			}
+			return true
+		})
+		if part.text != "" {
+			parts = append(parts, part)
+		}
+
+		return false
+	})
+
+	sort.SliceStable(parts, func(i, j int) bool {
+		if parts[i].top != parts[j].top {
+			return parts[i].top < parts[j].top
+		}
+		return parts[i].left < parts[j].left
+	})
+
+	// parts of one line differ in height by the baseline of the fonts at most
+	var lines []textLine
+	for _, part := range parts {
+		if n := len(lines); n > 0 && part.top-lines[n-1].top < max(lines[n-1].size, part.size)*0.3 {
+			line := &lines[n-1]
+			line.text += " " + part.text
+			line.size = max(line.size, part.size)
+			continue
+		}
+		lines = append(lines, part)
+	}
+
+	return lines
+}
+
+// bodySize returns the font size of most of the text of the lines.
+func bodySize(lines []textLine) float64 {
+	chars := make(map[float64]int)
+	for _, line := range lines {
+		chars[line.size] += len(line.text)
+	}
+
+	size, most := 0.0, 0
+	for s, n := range chars {
+		if n > most || n == most && s < size {
+			size, most = s, n
+		}
+	}
+
+	return size
+}
+
+// semanticBody returns the paragraphs, headings and lists of the lines.
+func semanticBody(lines []textLine, body float64) []*htmlNode {
+	var nodes []*htmlNode
+	var block *htmlNode // paragraph or list item lines are appended to
+	var list *htmlNode
+	var prev textLine
+
+	element := func(tag, text string) *htmlNode {
+		n := &htmlNode{kind: elementNode, tag: tag}
+		if text != "" {
+			n.appendChild(&htmlNode{kind: textNode, text: text})
+		}
+		return n
+	}
+	appendText := func(n *htmlNode, text string) {
+		last := n.children[len(n.children)-1]
+		// words broken across the lines are joined again
+		if r := []rune(text); strings.HasSuffix(last.text, "-") && len(r) > 0 && unicode.IsLower(r[0]) {
+			last.text = strings.TrimSuffix(last.text, "-") + text
+			return
+		}
+		last.text += " " + text
+	}
+
+	for i, line := range lines {
+		gap := line.top - prev.top - prev.size
+		continued := i > 0 && block != nil && gap < prev.size*0.8 && line.size == prev.size
+		prev = line
+
+		switch {
+		case body > 0 && line.size >= body*textHeading1:
+			block, list = nil, nil
+			nodes = append(nodes, element("h1", line.text))
+			continue
+		case body > 0 && line.size >= body*textHeading2:
+			block, list = nil, nil
+			nodes = append(nodes, element("h2", line.text))
+			continue
+		}
+
+		if match := reListItem.FindStringSubmatch(line.text); match != nil {
+			tag := "ol"
+			if match[1] != "" {
+				tag = "ul"
+			}
+			if list == nil || list.tag != tag {
+				list = element(tag, "")
+				nodes = append(nodes, list)
+
+				// markers are drawn by the list, so it starts where the PDF does
+				marker := strings.Trim(match[2], "().")
+				if n, err := strconv.Atoi(marker); err == nil && n != 1 {
+					list.setAttr("start", strconv.Itoa(n))
+				} else if len(marker) == 1 && unicode.IsLower(rune(marker[0])) {
+					list.setAttr("type", "a")
+					if marker != "a" {
+						list.setAttr("start", strconv.Itoa(int(marker[0]-'a'+1)))
+					}
+				}
+			}
+			block = element("li", line.text[len(match[0]):])
+			list.appendChild(block)
+			continue
+		}
+
+		if continued {
+			appendText(block, line.text)
+			continue
+		}
+
+		list = nil
+		block = element("p", line.text)
+		nodes = append(nodes, block)
+	}
+
+	return nodes
+}
+
+// textOnlyPages replaces the positioned text and the backgrounds of the pages
+// with the semantic markup of their text, and removes the background files.
+func textOnlyPages(_ context.Context, _, outdir string) error {
+	err := rewriteHTML(outdir, func(name string, data []byte) ([]byte, error) {
+		if pageNumber(name) == 0 {
+			return data, nil
+		}
+
+		doc, xhtml := parseDocument(data)
+		body := doc.find("body")
+		if body == nil {
+			return data, nil
+		}
+
+		lines := textLines(doc)
+		body.children = nil
+		main := &htmlNode{kind: elementNode, tag: "main"}
+		main.setAttr("class", "pdftohtml-text")
+		for _, n := range semanticBody(lines, bodySize(lines)) {
+			main.appendChild(n)
+		}
+		body.appendChild(main)
+
+		return renderDocument(doc, xhtml), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(outdir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && reBackgroundFile.MatchString(entry.Name()) {
+			if err := os.Remove(filepath.Join(outdir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+// -- text only options
+// ----------------------------------------------------------------------------
+
+// Convert the text layer only, into the semantic markup of the pages: headings,
+// paragraphs and lists, without the positioned text and the backgrounds, e.g.
+// for search previews, reader views or language models.
+//
+// Headings are told by their font size, larger than the body text, paragraphs
+// by the gaps between the lines, and lists by the bullets and numbers starting
+// the lines. `pdftohtml` always renders the backgrounds, so they are rendered at
+// the lowest resolution and removed with their renditions.
+//
+// The option should be given after the options changing the pages, as the
+// stages given after it see the text only.
+func WithTextOnly() option {
+	return func(c *Command) {
+		c.opts.Resolution = 36
+		c.opts.EmbedBackground = false
+		c.stages = append(c.stages, textOnlyPages)
+	}
+}
@@ -0,0 +1,173 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// ----------------------------------------------------------------------------
+// -- multipart upload
+// ----------------------------------------------------------------------------
+
+// ErrUploadTooLarge is returned when the uploaded PDF file exceeds the size
+// limit.
+var ErrUploadTooLarge = errors.New("pdftohtml: upload too large")
+
+// uploadFormOverhead is the size of the multipart form allowed on top of the
+// upload limit, for the boundaries, the part headers and other small fields.
+const uploadFormOverhead = 1 << 20
+
+// Upload is the PDF file uploaded in the multipart form, spooled to the
+// temporary file. Close removes the file.
+type Upload struct {
+	Path     string // absolute path of the spooled PDF file, ready to convert
+	Filename string // as sent by the client, not to be used as a path
+	Size     int64
+	dir      string
+}
+
+// Close removes the spooled PDF file.
+func (u *Upload) Close() error {
+	return os.RemoveAll(u.dir)
+}
+
+// uploadPolicy describes how PDF files are read by `ReadUpload`.
+type uploadPolicy struct {
+	limit  int64
+	tmpdir string
+}
+
+// ReadUpload streams the PDF file uploaded in the field of the
+// `multipart/form-data` request into the temporary file, without buffering the
+// form in memory. The caller converts the file at `Upload.Path`, and must always
+// close the upload.
+//
+// Parts before the field are skipped, parts after it are not read. The request
+// body is limited to the upload limit and a small overhead, so large requests are
+// cut short with `ErrUploadTooLarge`. The content type sent by the client is not
+// trusted, the content must start like the PDF file, see `ErrInvalidInput`.
+// Missing field is reported with `http.ErrMissingFile`, non-multipart request
+// with `http.ErrNotMultipart`.
+func ReadUpload(w http.ResponseWriter, r *http.Request, field string, opts ...uploadOption) (*Upload, error) {
+	policy := uploadPolicy{limit: 256 << 20}
+	for _, opt := range opts {
+		opt(&policy)
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, policy.limit+uploadFormOverhead)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, http.ErrMissingFile
+		}
+		if err != nil {
+			return nil, uploadError(err)
+		}
+
+		if part.FormName() != field || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+
+		upload, err := spoolUpload(part, part.FileName(), policy)
+		part.Close()
+
+		return upload, err
+	}
+}
+
+// spoolUpload writes the PDF file read from r into the file in the new temporary
+// directory.
+func spoolUpload(r io.Reader, filename string, policy uploadPolicy) (*Upload, error) {
+	head := make([]byte, 1024)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, uploadError(err)
+	}
+	if !bytes.Contains(head[:n], pdfMagic) {
+		return nil, fmt.Errorf("%w: %q is not a PDF file", ErrInvalidInput, filename)
+	}
+
+	dir, err := os.MkdirTemp(policy.tmpdir, "pdftohtml-upload-*")
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &Upload{Filename: filename, dir: dir}
+	if upload.Path, err = filepath.Abs(filepath.Join(dir, inputName(filename))); err != nil {
+		upload.Close()
+		return nil, err
+	}
+
+	f, err := os.OpenFile(upload.Path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		upload.Close()
+		return nil, err
+	}
+
+	r = io.MultiReader(bytes.NewReader(head[:n]), r)
+	upload.Size, err = io.Copy(f, io.LimitReader(r, policy.limit+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && upload.Size > policy.limit {
+		err = fmt.Errorf("%w: over %d bytes", ErrUploadTooLarge, policy.limit)
+	}
+	if err != nil {
+		upload.Close()
+		return nil, uploadError(err)
+	}
+
+	return upload, nil
+}
+
+// uploadError reports the request body cut short by its limit as
+// `ErrUploadTooLarge`.
+func uploadError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return fmt.Errorf("%w: %w", ErrUploadTooLarge, err)
+	}
+
+	return err
+}
+
+// ----------------------------------------------------------------------------
+// -- multipart upload options
+// ----------------------------------------------------------------------------
+
+type uploadOption func(*uploadPolicy)
+
+// Specifies the maximum size, in bytes, of PDF files read by `ReadUpload`.
+// Larger uploads fail with `ErrUploadTooLarge`.
+//
+// The default is 256 MiB. Sizes not greater than 0 are ignored.
+func WithUploadMaxSize(bytes int64) uploadOption {
+	return func(p *uploadPolicy) {
+		if bytes > 0 && bytes < math.MaxInt64-uploadFormOverhead {
+			p.limit = bytes
+		}
+	}
+}
+
+// Set custom parent directory for uploaded PDF files.
+//
+// By default the files are spooled in the default directory for temporary
+// files, see `os.TempDir`.
+func WithUploadTempDir(dir string) uploadOption {
+	return func(p *uploadPolicy) {
+		p.tmpdir = dir
+	}
+}
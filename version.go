@@ -0,0 +1,228 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ----------------------------------------------------------------------------
+// -- versions
+// ----------------------------------------------------------------------------
+
+// ErrNoMatchingVersion is returned when no registered binary satisfies the
+// version constraint.
+var ErrNoMatchingVersion = errors.New("pdftohtml: no binary matching version constraint")
+
+// Version of the `pdftohtml` executable, e.g. 4.04.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// ParseVersion parses version in the "major[.minor[.patch]]" format.
+func ParseVersion(s string) (Version, error) {
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	if len(parts) > 3 {
+		return Version{}, fmt.Errorf("pdftohtml: invalid version %q", s)
+	}
+
+	var nums [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("pdftohtml: invalid version %q", s)
+		}
+		nums[i] = n
+	}
+
+	return Version{Major: nums[0], Minor: nums[1], Patch: nums[2]}, nil
+}
+
+// Compare returns -1, 0 or +1 depending on whether v is lower, equal or higher
+// than o.
+func (v Version) Compare(o Version) int {
+	for _, d := range [3]int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		switch {
+		case d < 0:
+			return -1
+		case d > 0:
+			return +1
+		}
+	}
+
+	return 0
+}
+
+// String returns the version in the format used by Xpdf, e.g. 4.04.
+func (v Version) String() string {
+	if v.Patch != 0 {
+		return fmt.Sprintf("%d.%02d.%d", v.Major, v.Minor, v.Patch)
+	}
+
+	return fmt.Sprintf("%d.%02d", v.Major, v.Minor)
+}
+
+// reVersion matches the version printed by `pdftohtml -v`.
+var reVersion = regexp.MustCompile(`version (\d+(?:\.\d+){0,2})`)
+
+// DetectVersion runs the executable with `-v` option and parses the version it
+// reports.
+func DetectVersion(ctx context.Context, path string) (Version, error) {
+	// exit code is ignored, some versions exit with an error after printing it
+	cmd := exec.CommandContext(ctx, path, "-v")
+	cmd.Env = minimalEnviron(os.TempDir(), inheritedEnv, nil)
+	out, _ := cmd.CombinedOutput()
+
+	match := reVersion.FindSubmatch(out)
+	if match == nil {
+		return Version{}, fmt.Errorf("pdftohtml: cannot detect version of %q", path)
+	}
+
+	return ParseVersion(string(match[1]))
+}
+
+// ----------------------------------------------------------------------------
+// -- version constraints
+// ----------------------------------------------------------------------------
+
+type versionCondition struct {
+	op      string
+	version Version
+}
+
+// reCondition matches a single condition of the version constraint.
+var reCondition = regexp.MustCompile(`^(>=|<=|==|!=|>|<|=)?\s*(\S+)$`)
+
+// parseConstraint parses comma separated conditions, e.g. ">=4.00, <4.05".
+func parseConstraint(s string) ([]versionCondition, error) {
+	var conds []versionCondition
+	for _, part := range strings.Split(s, ",") {
+		match := reCondition.FindStringSubmatch(strings.TrimSpace(part))
+		if match == nil {
+			return nil, fmt.Errorf("pdftohtml: invalid version constraint %q", s)
+		}
+
+		version, err := ParseVersion(match[2])
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, versionCondition{op: match[1], version: version})
+	}
+
+	return conds, nil
+}
+
+// satisfies reports whether the version meets all conditions.
+func satisfies(v Version, conds []versionCondition) bool {
+	for _, cond := range conds {
+		cmp := v.Compare(cond.version)
+
+		var ok bool
+		switch cond.op {
+		case ">=":
+			ok = cmp >= 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case "<":
+			ok = cmp < 0
+		case "!=":
+			ok = cmp != 0
+		default:
+			ok = cmp == 0
+		}
+
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ----------------------------------------------------------------------------
+// -- binaries registry
+// ----------------------------------------------------------------------------
+
+// Binary is an installed `pdftohtml` executable with known version.
+type Binary struct {
+	Path    string
+	Version Version
+}
+
+var binaries struct {
+	mu   sync.Mutex
+	list []Binary
+}
+
+// RegisterBinary detects version of the executable and makes it available for
+// selection with `WithVersionConstraint` option.
+func RegisterBinary(ctx context.Context, path string) (Binary, error) {
+	path, err := exec.LookPath(path)
+	if err != nil {
+		return Binary{}, err
+	}
+
+	version, err := DetectVersion(ctx, path)
+	if err != nil {
+		return Binary{}, err
+	}
+
+	binaries.mu.Lock()
+	defer binaries.mu.Unlock()
+
+	bin := Binary{Path: path, Version: version}
+	for i, b := range binaries.list {
+		if b.Path == path {
+			binaries.list[i] = bin
+			return bin, nil
+		}
+	}
+	binaries.list = append(binaries.list, bin)
+
+	return bin, nil
+}
+
+// Binaries returns registered executables, ordered from the highest version.
+func Binaries() []Binary {
+	binaries.mu.Lock()
+	defer binaries.mu.Unlock()
+
+	list := append([]Binary(nil), binaries.list...)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Version.Compare(list[j].Version) > 0
+	})
+
+	return list
+}
+
+// Use the registered executable with the highest version satisfying the constraint,
+// e.g. ">=4.04" or ">=4.00, <4.05".
+//
+// Executables have to be registered with `RegisterBinary` first.
+func WithVersionConstraint(constraint string) option {
+	return func(c *Command) {
+		conds, err := parseConstraint(constraint)
+		if err != nil {
+			c.errs = append(c.errs, err)
+			return
+		}
+
+		for _, bin := range Binaries() {
+			if satisfies(bin.Version, conds) {
+				c.path = bin.Path
+				return
+			}
+		}
+
+		c.errs = append(c.errs, fmt.Errorf("%w %q", ErrNoMatchingVersion, constraint))
+	}
+}
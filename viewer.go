@@ -0,0 +1,270 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// -- viewer shell
+// ----------------------------------------------------------------------------
+
+const (
+	// ViewerName is the name of the viewer shell page in the outdir.
+	ViewerName = "viewer.html"
+
+	// ViewerStylesheetName is the name of the viewer shell stylesheet in the outdir.
+	ViewerStylesheetName = "viewer.css"
+
+	// ViewerScriptName is the name of the viewer shell script in the outdir.
+	ViewerScriptName = "viewer.js"
+)
+
+// writeViewer writes the viewer shell of the pages converted in the outdir, with
+// the sidebar built from the outline, or listing the pages by their labels
+// without it.
+func writeViewer(outdir string, outline []OutlineItem, labels []string) error {
+	names, err := listHTML(outdir)
+	if err != nil {
+		return err
+	}
+
+	var pages []string
+	available := make(map[int]bool)
+	for _, name := range names {
+		if n := pageNumber(name); n > 0 && name == fmt.Sprintf("page%d.html", n) {
+			pages = append(pages, name)
+			available[n] = true
+		}
+	}
+	if len(pages) == 0 {
+		return nil
+	}
+
+	var title string
+	if data, err := os.ReadFile(filepath.Join(outdir, "index.html")); err == nil {
+		title = htmlTitle(data)
+	}
+
+	if len(outline) == 0 {
+		for _, name := range pages {
+			n := pageNumber(name)
+			outline = append(outline, OutlineItem{Title: "Page " + pageLabel(labels, n), Page: n})
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"UTF-8\">\n")
+	b.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n", html.EscapeString(title))
+	fmt.Fprintf(&b, "<link rel=\"stylesheet\" href=\"%s\">\n</head>\n", ViewerStylesheetName)
+	fmt.Fprintf(&b, "<body data-pages=\"%s\">\n", strings.Join(pages, " "))
+	b.WriteString("<nav id=\"pdftohtml-sidebar\" aria-label=\"Outline\">\n")
+	writeViewerItems(&b, outline, available, 0)
+	b.WriteString("</nav>\n<main>\n")
+	b.WriteString("<button id=\"pdftohtml-toggle\" type=\"button\" aria-controls=\"pdftohtml-sidebar\" aria-expanded=\"true\" title=\"Toggle sidebar (s)\">&#9776;</button>\n")
+	fmt.Fprintf(&b, "<iframe id=\"pdftohtml-content\" name=\"pdftohtml-content\" src=\"%s\" title=\"Page\"></iframe>\n", pages[0])
+	fmt.Fprintf(&b, "</main>\n<script src=\"%s\"></script>\n</body>\n</html>\n", ViewerScriptName)
+
+	files := map[string]string{
+		ViewerName:           b.String(),
+		ViewerStylesheetName: viewerCSS,
+		ViewerScriptName:     viewerJS,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(outdir, name), []byte(content), 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeViewerItems writes the nested list of the outline items. Items with
+// children are collapsible, only the top level is expanded.
+func writeViewerItems(b *strings.Builder, items []OutlineItem, available map[int]bool, depth int) {
+	b.WriteString("<ul>\n")
+	for _, item := range items {
+		label := html.EscapeString(item.Title)
+		if available[item.Page] {
+			label = fmt.Sprintf("<a href=\"page%d.html\" target=\"pdftohtml-content\" data-top=\"%s\">%s</a>",
+				item.Page, formatPixels(item.Top), label)
+		} else {
+			label = "<span>" + label + "</span>"
+		}
+
+		if len(item.Children) == 0 {
+			b.WriteString("<li>" + label + "</li>\n")
+			continue
+		}
+
+		open := ""
+		if depth == 0 {
+			open = " open"
+		}
+		fmt.Fprintf(b, "<li><details%s><summary>%s</summary>\n", open, label)
+		writeViewerItems(b, item.Children, available, depth+1)
+		b.WriteString("</details></li>\n")
+	}
+	b.WriteString("</ul>\n")
+}
+
+const viewerCSS = `html, body { height: 100%; margin: 0; }
+body { display: flex; font: 14px/1.4 sans-serif; }
+#pdftohtml-sidebar { flex: 0 0 18em; overflow: auto; padding: 0.5em 0; background: #f4f4f4; border-right: 1px solid #ccc; }
+#pdftohtml-sidebar ul { list-style: none; margin: 0; padding-left: 1em; }
+#pdftohtml-sidebar li { margin: 0.2em 0; }
+#pdftohtml-sidebar summary { cursor: pointer; }
+#pdftohtml-sidebar a { color: inherit; text-decoration: none; }
+#pdftohtml-sidebar a:hover, #pdftohtml-sidebar a:focus { text-decoration: underline; }
+#pdftohtml-sidebar a[aria-current] { font-weight: bold; }
+#pdftohtml-sidebar span { color: #777; }
+main { flex: 1 1 auto; position: relative; }
+#pdftohtml-content { display: block; width: 100%; height: 100%; border: 0; }
+#pdftohtml-toggle { position: absolute; top: 0.5em; left: 0.5em; z-index: 1; }
+body.pdftohtml-collapsed #pdftohtml-sidebar { display: none; }
+@media (max-width: 40em) { #pdftohtml-sidebar { flex-basis: 12em; } }
+@media print { #pdftohtml-sidebar, #pdftohtml-toggle { display: none; } }
+`
+
+const viewerJS = `(function () {
+	"use strict";
+
+	var frame = document.getElementById("pdftohtml-content");
+	var toggle = document.getElementById("pdftohtml-toggle");
+	var links = document.querySelectorAll("#pdftohtml-sidebar a[href]");
+	var pages = document.body.getAttribute("data-pages").split(" ");
+	var current = pages[0];
+	var pendingTop = 0;
+
+	function scrollTo(top) {
+		try {
+			frame.contentWindow.scrollTo(0, top);
+		} catch (e) {
+			// pages opened from the file system may be cross-origin
+		}
+	}
+
+	function open(page, top) {
+		top = top || 0;
+		if (page === current) {
+			scrollTo(top);
+			return;
+		}
+		current = page;
+		pendingTop = top;
+		frame.src = page;
+		mark();
+	}
+
+	function step(delta) {
+		var i = pages.indexOf(current) + delta;
+		if (i >= 0 && i < pages.length) {
+			open(pages[i], 0);
+		}
+	}
+
+	function mark() {
+		var marked = false;
+		for (var i = 0; i < links.length; i++) {
+			if (!marked && links[i].getAttribute("href") === current) {
+				links[i].setAttribute("aria-current", "page");
+				marked = true;
+			} else {
+				links[i].removeAttribute("aria-current");
+			}
+		}
+	}
+
+	function toggleSidebar() {
+		var collapsed = document.body.classList.toggle("pdftohtml-collapsed");
+		toggle.setAttribute("aria-expanded", collapsed ? "false" : "true");
+	}
+
+	function onKey(e) {
+		var target = e.target;
+		if (e.altKey || e.ctrlKey || e.metaKey || target.isContentEditable ||
+			/^(INPUT|TEXTAREA|SELECT)$/.test(target.tagName)) {
+			return;
+		}
+		switch (e.key) {
+		case "ArrowRight":
+		case "j":
+		case "n":
+			step(1);
+			break;
+		case "ArrowLeft":
+		case "k":
+		case "p":
+			step(-1);
+			break;
+		case "s":
+			toggleSidebar();
+			break;
+		default:
+			return;
+		}
+		e.preventDefault();
+	}
+
+	for (var i = 0; i < links.length; i++) {
+		links[i].addEventListener("click", function (e) {
+			e.preventDefault();
+			open(this.getAttribute("href"), parseFloat(this.getAttribute("data-top")));
+		});
+	}
+
+	frame.addEventListener("load", function () {
+		try {
+			var name = frame.contentWindow.location.pathname.split("/").pop();
+			if (pages.indexOf(name) >= 0) {
+				current = name;
+			}
+			frame.contentDocument.addEventListener("keydown", onKey);
+		} catch (e) {
+			// keyboard navigation works from the sidebar only
+		}
+		scrollTo(pendingTop);
+		pendingTop = 0;
+		mark();
+	});
+
+	toggle.addEventListener("click", toggleSidebar);
+	document.addEventListener("keydown", onKey);
+	mark();
+})();
+`
+
+// ----------------------------------------------------------------------------
+// -- viewer shell options
+// ----------------------------------------------------------------------------
+
+// Write the viewer shell `viewer.html`, with `viewer.css` and `viewer.js`, next
+// to the converted pages: a collapsible sidebar built from the outline of the
+// PDF file, and the pane showing the pages. Pages are switched with arrow keys,
+// "j"/"k" or "n"/"p", and the sidebar is toggled with "s".
+//
+// Without the outline, or for encrypted files, the sidebar lists the pages, by
+// their labels with `WithPageLabels`.
+func WithViewer() option {
+	return func(c *Command) {
+		c.stages = append(c.stages, func(_ context.Context, inpath, outdir string) error {
+			outline, err := c.Outline(inpath)
+			if err != nil && !errors.Is(err, ErrUnsupportedPDF) {
+				return err
+			}
+
+			var labels []string
+			if c.pageLabels && len(outline) == 0 {
+				labels, _ = c.PageLabels(inpath)
+			}
+
+			return writeViewer(outdir, outline, labels)
+		})
+	}
+}
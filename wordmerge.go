@@ -0,0 +1,121 @@
+package pdftohtml
+
+import (
+	"context"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------------------
+// -- word merging
+// ----------------------------------------------------------------------------
+
+// mergeLetterRuns joins runs of at least `run` single letters or digits
+// separated by single spaces into words, e.g. "o n e  w o r d" into "one  word".
+// Wider gaps, of two or more spaces, separate the words of the run and are kept.
+func mergeLetterRuns(text string, run int) string {
+	var buf strings.Builder
+	var letters []string // pending run of single letters
+	flush := func() {
+		if len(letters) >= run {
+			buf.WriteString(strings.Join(letters, ""))
+		} else {
+			buf.WriteString(strings.Join(letters, " "))
+		}
+		letters = letters[:0]
+	}
+
+	for i := 0; i < len(text); {
+		// gap of spaces before the next token
+		j := i
+		for j < len(text) && text[j] == ' ' {
+			j++
+		}
+		k := j
+		for k < len(text) && text[k] != ' ' {
+			k++
+		}
+		gap, token := text[i:j], text[j:k]
+		i = k
+
+		if isSingleLetter(token) && (len(letters) == 0 || gap == " ") {
+			if len(letters) == 0 {
+				buf.WriteString(gap)
+			}
+			letters = append(letters, token)
+			continue
+		}
+
+		if len(letters) > 0 {
+			flush()
+		}
+
+		if isSingleLetter(token) {
+			buf.WriteString(gap)
+			letters = append(letters, token)
+			continue
+		}
+		buf.WriteString(gap)
+		buf.WriteString(token)
+	}
+	flush()
+
+	return buf.String()
+}
+
+// isSingleLetter reports whether the token is the single letter or digit.
+func isSingleLetter(token string) bool {
+	r, size := utf8.DecodeRuneInString(token)
+
+	return size > 0 && size == len(token) && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// mergeWords creates the stage joining runs of single letters in the text of
+// the pages.
+func mergeWords(run int) stage {
+	return func(_ context.Context, _, outdir string) error {
+		return rewriteHTML(outdir, func(name string, data []byte) ([]byte, error) {
+			if pageNumber(name) == 0 {
+				return data, nil
+			}
+
+			doc, xhtml := parseDocument(data)
+			doc.walk(func(n *htmlNode) bool {
+				if n.kind == textNode && !n.raw {
+					n.text = mergeLetterRuns(n.text, run)
+				}
+				return true
+			})
+
+			return renderDocument(doc, xhtml), nil
+		})
+	}
+}
+
+// ----------------------------------------------------------------------------
+// -- word merging options
+// ----------------------------------------------------------------------------
+
+// Join runs of single letters separated by spaces into words, in the text of
+// the pages, e.g. "o n e l e t t e r" into "oneletter". Documents with wide
+// letter spacing or unusual kerning are extracted like that, as `pdftohtml`
+// breaks the word at every wide gap between the glyphs.
+//
+// The run is the number of single letters in a row merged into the word, at
+// least 2. Lower runs merge more aggressively, and may join genuine one-letter
+// words, e.g. "a b c" of the list, higher ones keep them but miss short words.
+// Gaps of two or more spaces are kept as word breaks.
+//
+// Xpdf `pdftohtml` has no word-break threshold, unlike Poppler `-wbt`, so the
+// text is merged by the package.
+func WithWordMerging(run int) option {
+	return func(c *Command) {
+		if !c.checkRange(RangeError{Option: "word merging run", Value: float64(run), Min: 2, Max: math.Inf(+1)}) {
+			return
+		}
+
+		c.stages = append(c.stages, mergeWords(run))
+	}
+}
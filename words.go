@@ -0,0 +1,179 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// ----------------------------------------------------------------------------
+// -- word geometry
+// ----------------------------------------------------------------------------
+
+// WordsName is the name of the word geometry file in the outdir.
+const WordsName = "words.json"
+
+// PageWords describes words of a single page, in pixels of the generated HTML.
+type PageWords struct {
+	Number int     `json:"number"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+	Words  []Word  `json:"words"`
+}
+
+// Word is a single word with its bounding box.
+type Word struct {
+	Text string  `json:"text"`
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+	W    float64 `json:"w"`
+	H    float64 `json:"h"`
+}
+
+// Words extracts bounding boxes of words of the PDF file, scaled into the pixel
+// coordinates of the HTML generated with the zoom and the vertical stretch of
+// the command.
+//
+// Xpdf `pdftotext` does not report word geometry, the path has to point to the
+// Poppler `pdftotext`, supporting the `-bbox` option. If the path is empty, the
+// executable is looked up in the PATH.
+func (c *Command) Words(ctx context.Context, pdftotext, inpath string) ([]PageWords, error) {
+	if pdftotext == "" {
+		pdftotext = "pdftotext"
+	}
+
+	args := []string{"-bbox", "-enc", "UTF-8"}
+	if c.opts.PageFrom > 0 {
+		args = append(args, "-f", strconv.FormatUint(c.opts.PageFrom, 10))
+	}
+	if c.opts.PageTo > 0 {
+		args = append(args, "-l", strconv.FormatUint(c.opts.PageTo, 10))
+	}
+	if c.opts.OwnerPassword != "" {
+		args = append(args, "-opw", c.opts.OwnerPassword)
+	}
+	if c.opts.UserPassword != "" {
+		args = append(args, "-upw", c.opts.UserPassword)
+	}
+
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, pdftotext, append(args, "--", inpath, "-")...)
+	cmd.Env = c.environ()
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("pdftohtml: pdftotext -bbox: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	sx, sy := c.pixelScale()
+
+	return parseWords(out, max(c.opts.PageFrom, 1), sx, sy)
+}
+
+// pixelScale returns the horizontal and vertical scale of PDF points to pixels
+// of the generated HTML.
+func (c *Command) pixelScale() (float64, float64) {
+	zoom := 1.0
+	if c.opts.Zoom > 0 {
+		zoom = c.opts.Zoom
+	}
+
+	return zoom, zoom * max(c.opts.VerticalStretch, 1)
+}
+
+// parseWords parses the `-bbox` output of Poppler `pdftotext`, scaling the
+// coordinates in points into pixels.
+func parseWords(data []byte, first uint64, sx, sy float64) ([]PageWords, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+
+	attr := func(el xml.StartElement, name string) float64 {
+		for _, a := range el.Attr {
+			if a.Name.Local == name {
+				v, _ := strconv.ParseFloat(a.Value, 64)
+				return v
+			}
+		}
+
+		return 0
+	}
+
+	var pages []PageWords
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch el.Name.Local {
+		case "page":
+			pages = append(pages, PageWords{
+				Number: int(first) + len(pages),
+				Width:  attr(el, "width") * sx,
+				Height: attr(el, "height") * sy,
+				Words:  []Word{},
+			})
+
+		case "word":
+			if len(pages) == 0 {
+				continue
+			}
+
+			var text string
+			if err := dec.DecodeElement(&text, &el); err != nil {
+				return nil, err
+			}
+
+			x0, y0 := attr(el, "xMin"), attr(el, "yMin")
+			x1, y1 := attr(el, "xMax"), attr(el, "yMax")
+
+			page := &pages[len(pages)-1]
+			page.Words = append(page.Words, Word{Text: text, X: x0 * sx, Y: y0 * sy, W: (x1 - x0) * sx, H: (y1 - y0) * sy})
+		}
+	}
+
+	return pages, nil
+}
+
+// ----------------------------------------------------------------------------
+// -- word geometry options
+// ----------------------------------------------------------------------------
+
+// Write the `words.json` with bounding boxes of all words, in pixels of the
+// generated HTML, into the outdir. See `Command.Words`.
+func WithWordGeometry(pdftotext string) option {
+	return func(c *Command) {
+		c.stages = append(c.stages, func(ctx context.Context, inpath, outdir string) error {
+			pages, err := c.Words(ctx, pdftotext, inpath)
+			if err != nil {
+				return err
+			}
+
+			data, err := json.Marshal(pages)
+			if err != nil {
+				return err
+			}
+
+			return os.WriteFile(filepath.Join(outdir, WordsName), data, 0o644)
+		})
+	}
+}
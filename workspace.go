@@ -0,0 +1,237 @@
+package pdftohtml
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// ----------------------------------------------------------------------------
+// -- workspace pool
+// ----------------------------------------------------------------------------
+
+// Workspace is the private temporary directory of a single conversion, with the
+// conventional locations of the input and the output.
+type Workspace struct {
+	Dir    string // workspace directory, accessible to the owner only
+	Input  string // path of the input PDF file, not created
+	Output string // outdir of the conversion, not created
+	Quota  int64  // disk quota of the workspace in bytes, 0 if unlimited
+}
+
+// Check returns `ErrDiskLimitExceeded` if files in the workspace exceed its
+// quota. Workspaces taken with `WorkspacePool.Get` are not watched while in use,
+// the quota is enforced only by checking them once the conversion finishes.
+func (w *Workspace) Check() error {
+	if w.Quota <= 0 {
+		return nil
+	}
+
+	size, err := dirSize(w.Dir)
+	if err != nil {
+		return err
+	}
+	if size > w.Quota {
+		return ErrDiskLimitExceeded
+	}
+
+	return nil
+}
+
+// reset removes everything in the workspace directory, keeping the directory.
+func (w *Workspace) reset() error {
+	entries, err := os.ReadDir(w.Dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(w.Dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WorkspacePool pre-creates workspaces and recycles them between conversions,
+// so conversions skip setting up and tearing down their directories. It is safe
+// for concurrent use.
+type WorkspacePool struct {
+	root  string
+	size  int
+	quota int64
+
+	mu     sync.Mutex
+	free   []*Workspace
+	closed bool
+}
+
+// NewWorkspacePool creates new pool of workspaces in the new directory in the
+// default directory for temporary files, and pre-creates its workspaces.
+func NewWorkspacePool(opts ...workspacePoolOption) (*WorkspacePool, error) {
+	p := &WorkspacePool{size: runtime.NumCPU()}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	root, err := os.MkdirTemp(p.root, "pdftohtml-workspaces-*")
+	if err != nil {
+		return nil, err
+	}
+	p.root = root
+
+	for range p.size {
+		w, err := p.create()
+		if err != nil {
+			os.RemoveAll(root)
+			return nil, err
+		}
+		p.free = append(p.free, w)
+	}
+
+	return p, nil
+}
+
+// create creates new workspace in the pool directory.
+func (p *WorkspacePool) create() (*Workspace, error) {
+	dir, err := os.MkdirTemp(p.root, "ws-*")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Workspace{
+		Dir:    dir,
+		Input:  filepath.Join(dir, "input.pdf"),
+		Output: filepath.Join(dir, "output"),
+		Quota:  p.quota,
+	}, nil
+}
+
+// Get returns the empty workspace, recycled or created if all are in use. The
+// caller must return it with `Put`.
+func (p *WorkspacePool) Get() (*Workspace, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, os.ErrClosed
+	}
+
+	if n := len(p.free); n > 0 {
+		w := p.free[n-1]
+		p.free = p.free[:n-1]
+		return w, nil
+	}
+
+	return p.create()
+}
+
+// Put empties the workspace and returns it to the pool, or removes it if the
+// pool is full, closed or the workspace cannot be emptied.
+func (p *WorkspacePool) Put(w *Workspace) error {
+	if err := w.reset(); err != nil {
+		os.RemoveAll(w.Dir)
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed || len(p.free) >= p.size {
+		return os.RemoveAll(w.Dir)
+	}
+	p.free = append(p.free, w)
+
+	return nil
+}
+
+// Do runs fn in the workspace and returns the workspace to the pool afterwards,
+// also when fn panics, in which case the panic is propagated once emptied.
+//
+// The quota of the workspace is enforced while fn runs: the context of fn is
+// canceled once the workspace exceeds it, and `ErrDiskLimitExceeded` returned.
+// It is checked once more after fn succeeds.
+func (p *WorkspacePool) Do(ctx context.Context, fn func(context.Context, *Workspace) error) error {
+	w, err := p.Get()
+	if err != nil {
+		return err
+	}
+	defer p.Put(w)
+
+	err = withinDiskLimit(ctx, w.Dir, w.Quota, func(ctx context.Context) error {
+		return fn(ctx, w)
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.Check()
+}
+
+// Run converts the PDF file with the runner in the workspace, and calls fn with
+// the outdir, e.g. to store the output, before the workspace is recycled.
+func (p *WorkspacePool) Run(ctx context.Context, runner Runner, inpath string, fn func(outdir string) error) error {
+	return p.Do(ctx, func(ctx context.Context, w *Workspace) error {
+		if err := runner.Run(ctx, inpath, w.Output); err != nil {
+			return err
+		}
+		if err := w.Check(); err != nil {
+			return err
+		}
+
+		return fn(w.Output)
+	})
+}
+
+// Close removes the pool directory with all workspaces. Workspaces in use are
+// removed as well, and removed again when put back.
+func (p *WorkspacePool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	p.free = nil
+
+	return os.RemoveAll(p.root)
+}
+
+// ----------------------------------------------------------------------------
+// -- workspace pool options
+// ----------------------------------------------------------------------------
+
+type workspacePoolOption func(*WorkspacePool)
+
+// Set custom parent directory for the pool directory.
+//
+// By default the pool directory is created in the default directory for
+// temporary files, see `os.TempDir`.
+func WithWorkspaceTempDir(dir string) workspacePoolOption {
+	return func(p *WorkspacePool) {
+		p.root = dir
+	}
+}
+
+// Specifies the number of workspaces pre-created and kept for recycling.
+// Workspaces beyond it are created on demand and removed once put back.
+//
+// The default is the number of CPUs.
+func WithWorkspacePoolSize(n int) workspacePoolOption {
+	return func(p *WorkspacePool) {
+		p.size = max(n, 0)
+	}
+}
+
+// Limit disk usage of a single workspace, in bytes.
+//
+// The quota is enforced while the workspace is used by `WorkspacePool.Do` and
+// `WorkspacePool.Run`, which cancel the conversion once it is exceeded, polling
+// disk usage a few times per second. Runners ignoring the cancellation are
+// failed only once they finish. See also `Workspace.Check`.
+func WithWorkspaceQuota(bytes int64) workspacePoolOption {
+	return func(p *WorkspacePool) {
+		p.quota = bytes
+	}
+}
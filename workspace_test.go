@@ -0,0 +1,160 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWorkspacePool(t *testing.T) {
+	pool, err := NewWorkspacePool(WithWorkspaceTempDir(t.TempDir()), WithWorkspacePoolSize(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	first, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// created on demand when all are in use
+	second, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Dir == second.Dir {
+		t.Fatal("workspace handed out twice")
+	}
+
+	if err := os.WriteFile(first.Input, []byte("%PDF-1.4"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.Put(first); err != nil {
+		t.Fatal(err)
+	}
+	// removed, the pool is full
+	if err := pool.Put(second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(second.Dir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("workspace over the pool size kept: %v", err)
+	}
+
+	recycled, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recycled.Dir != first.Dir {
+		t.Errorf("got %q, want recycled %q", recycled.Dir, first.Dir)
+	}
+	entries, err := os.ReadDir(recycled.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("recycled workspace not emptied: %d entries", len(entries))
+	}
+
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Get(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("got %v from the closed pool, want os.ErrClosed", err)
+	}
+}
+
+func TestWorkspacePoolDoPanics(t *testing.T) {
+	pool, err := NewWorkspacePool(WithWorkspaceTempDir(t.TempDir()), WithWorkspacePoolSize(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	var dir string
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic not propagated")
+			}
+		}()
+
+		pool.Do(context.Background(), func(_ context.Context, w *Workspace) error {
+			dir = w.Dir
+			if err := os.WriteFile(w.Input, nil, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			panic("conversion")
+		})
+	}()
+
+	w, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Dir != dir {
+		t.Errorf("workspace not returned to the pool")
+	}
+	if _, err := os.Stat(w.Input); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("workspace not emptied: %v", err)
+	}
+}
+
+func TestWorkspaceQuota(t *testing.T) {
+	tests := []struct {
+		name string
+		wait time.Duration
+	}{
+		{"after", 0},
+		// fails only if the runner is canceled while it runs
+		{"running", time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, err := NewWorkspacePool(WithWorkspaceTempDir(t.TempDir()), WithWorkspacePoolSize(1), WithWorkspaceQuota(1024))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			called := false
+			err = pool.Run(context.Background(), writeOutput(2048, tt.wait), "", func(string) error {
+				called = true
+				return nil
+			})
+			if !errors.Is(err, ErrDiskLimitExceeded) {
+				t.Errorf("got %v, want ErrDiskLimitExceeded", err)
+			}
+			if called {
+				t.Error("output of the conversion over the quota used")
+			}
+		})
+	}
+}
+
+func TestWorkspacePoolRun(t *testing.T) {
+	pool, err := NewWorkspacePool(WithWorkspaceTempDir(t.TempDir()), WithWorkspacePoolSize(1), WithWorkspaceQuota(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	var size int64
+	err = pool.Run(context.Background(), writeOutput(512, 0), "", func(outdir string) error {
+		info, err := os.Stat(filepath.Join(outdir, "page1.html"))
+		if err != nil {
+			return err
+		}
+		size = info.Size()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 512 {
+		t.Errorf("got output of %d bytes, want 512", size)
+	}
+}
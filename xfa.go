@@ -0,0 +1,125 @@
+package pdftohtml
+
+import (
+	"context"
+	"errors"
+)
+
+// ----------------------------------------------------------------------------
+// -- xfa forms
+// ----------------------------------------------------------------------------
+
+// ErrXFAForm is matched by `XFAError`, returned for PDF files with XFA forms.
+var ErrXFAForm = errors.New("pdftohtml: XFA form")
+
+// XFAForm is the kind of the XFA form of the PDF file.
+type XFAForm int
+
+const (
+	// XFANone is the PDF file without the XFA form.
+	XFANone XFAForm = iota
+	// XFAStatic is the XFA form with the AcroForm fields, and the appearances of
+	// the fields on the pages.
+	XFAStatic
+	// XFADynamic is the XFA form laid out by the viewer, whose pages only ask to
+	// open the file in the viewer supporting XFA.
+	XFADynamic
+)
+
+// XFAError is the XFA form of the PDF file, which `pdftohtml` renders poorly or
+// blank.
+type XFAError struct {
+	Form XFAForm
+}
+
+func (e *XFAError) Error() string {
+	if e.Form == XFADynamic {
+		return "pdftohtml: dynamic XFA form, pages are converted blank"
+	}
+
+	return "pdftohtml: static XFA form, form fields may be converted incompletely"
+}
+
+func (e *XFAError) Is(target error) bool {
+	return target == ErrXFAForm
+}
+
+// XFAPolicy determines how PDF files with XFA forms are converted.
+type XFAPolicy int
+
+const (
+	// XFAWarn converts the file as usual, and records the warning in the manifest.
+	XFAWarn XFAPolicy = iota + 1
+	// XFAReject fails the conversion with `XFAError`.
+	XFAReject
+	// XFAFallback converts the file with the static appearances of the fields
+	// drawn in the backgrounds, instead of input elements, and records the warning
+	// in the manifest.
+	XFAFallback
+)
+
+// DetectXFA reports the kind of the XFA form of the PDF file.
+//
+// The file is read by the package itself, which does not read encrypted files,
+// see `ErrUnsupportedPDF`.
+func DetectXFA(inpath string) (XFAForm, error) {
+	r, err := openPDFFile(inpath)
+	if err != nil {
+		return XFANone, err
+	}
+
+	catalog := r.catalog()
+	form := r.dict(catalog["AcroForm"])
+	if form["XFA"] == nil {
+		return XFANone, nil
+	}
+
+	// dynamic forms have no fields to render outside of the XFA viewer
+	if needs, _ := r.resolve(catalog["NeedsRendering"]).(bool); needs || len(r.array(form["Fields"])) == 0 {
+		return XFADynamic, nil
+	}
+
+	return XFAStatic, nil
+}
+
+// handleXFA applies the XFA policy to the options of the conversion of the PDF
+// file, and returns the warnings for the manifest.
+func (c *Command) handleXFA(_ context.Context, opts OptionSet, inpath string) (OptionSet, []string, error) {
+	if c.xfa == 0 {
+		return opts, nil, nil
+	}
+
+	form, err := DetectXFA(inpath)
+	if errors.Is(err, ErrUnsupportedPDF) || form == XFANone {
+		return opts, nil, nil
+	}
+	if err != nil {
+		return opts, nil, err
+	}
+
+	xerr := &XFAError{Form: form}
+	switch c.xfa {
+	case XFAReject:
+		return opts, nil, xerr
+	case XFAFallback:
+		// input elements erase the appearances of the fields from the backgrounds
+		opts.FormFields = false
+	}
+
+	return opts, []string{xerr.Error()}, nil
+}
+
+// ----------------------------------------------------------------------------
+// -- xfa forms options
+// ----------------------------------------------------------------------------
+
+// Detect XFA forms, which `pdftohtml` converts poorly or, in case of dynamic
+// forms, blank, and handle them by the policy, see `XFAPolicy`.
+//
+// The file is read by the package itself, XFA forms of encrypted files are not
+// detected.
+func WithXFAPolicy(policy XFAPolicy) option {
+	return func(c *Command) {
+		c.xfa = policy
+	}
+}
@@ -0,0 +1,75 @@
+package pdftohtml
+
+import (
+	"bytes"
+	"context"
+)
+
+// ----------------------------------------------------------------------------
+// -- xhtml
+// ----------------------------------------------------------------------------
+
+// xhtmlNamespace is the namespace of XHTML elements.
+const xhtmlNamespace = "http://www.w3.org/1999/xhtml"
+
+// toXHTML re-serializes the document as well-formed XHTML.
+func toXHTML(data []byte) []byte {
+	return renderXHTML(parseXHTML(data))
+}
+
+// parseXHTML parses the document normalized into the XHTML document tree.
+func parseXHTML(data []byte) *htmlNode {
+	doc := parseHTML(normalizeMarkup(data))
+	doc.removeDoctype()
+
+	if root := doc.find("html"); root != nil {
+		root.setAttr("xmlns", xhtmlNamespace)
+	}
+
+	return doc
+}
+
+// renderXHTML serializes the document tree with the XHTML prolog.
+func renderXHTML(doc *htmlNode) []byte {
+	prolog := []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE html>\n")
+
+	return append(prolog, renderHTML(doc, true)...)
+}
+
+// parseDocument parses the generated document, and reports whether it is XHTML
+// written by `WithXHTMLOutput`.
+func parseDocument(data []byte) (*htmlNode, bool) {
+	if bytes.HasPrefix(data, []byte("<?xml")) {
+		return parseXHTML(data), true
+	}
+
+	return parseHTML(data), false
+}
+
+// renderDocument serializes the document in the format it was parsed from.
+func renderDocument(doc *htmlNode, xhtml bool) []byte {
+	if xhtml {
+		return renderXHTML(doc)
+	}
+
+	return renderHTML(doc, false)
+}
+
+// ----------------------------------------------------------------------------
+// -- xhtml options
+// ----------------------------------------------------------------------------
+
+// Re-serialize the generated HTML files as well-formed XHTML: properly nested
+// elements, quoted attributes, self-closed void elements and the XHTML namespace,
+// for XML tooling such as XSLT or EPUB.
+//
+// File names are kept, so links between the files keep working.
+func WithXHTMLOutput() option {
+	return func(c *Command) {
+		c.stages = append(c.stages, func(_ context.Context, _, outdir string) error {
+			return rewriteHTML(outdir, func(_ string, data []byte) ([]byte, error) {
+				return toXHTML(data), nil
+			})
+		})
+	}
+}